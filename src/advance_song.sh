@@ -1,2 +0,0 @@
-#!/bin/sh
-python3 song_advance.py
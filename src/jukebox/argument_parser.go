@@ -63,6 +63,13 @@ func (ap *ArgumentParser) AddRequiredArgument(arg string, help string) {
 	ap.listCommands = append(ap.listCommands, arg)
 }
 
+// AddOptionalPositionalArgument adds a positional argument that may be
+// left out. it must be added after the required arguments.
+func (ap *ArgumentParser) AddOptionalPositionalArgument(arg string, help string) {
+	ap.dictCommands[arg] = help
+	ap.listCommands = append(ap.listCommands, arg)
+}
+
 func (ap *ArgumentParser) ParseArgs(args []string) *PropertySet {
 
 	ps := NewPropertySet()
@@ -99,6 +99,27 @@ func TestAddRequiredArgument(t *testing.T) {
 	}
 }
 
+func TestAddOptionalPositionalArgument(t *testing.T) {
+	th := NewTestHelper(t)
+	ap := NewArgumentParser(false)
+	ap.AddRequiredArgument("command", "command to execute")
+	ap.AddOptionalPositionalArgument("action", "action for command")
+
+	ps := ap.ParseArgs([]string{"ctl"})
+	th.Require(ps != nil, "ParseArgs must return non-nil")
+	if ps != nil {
+		th.Require(ps.Contains("command"), "required argument must exist")
+		th.RequireFalse(ps.Contains("action"), "optional argument not provided should not exist")
+	}
+
+	ps = ap.ParseArgs([]string{"ctl", "pause"})
+	th.Require(ps != nil, "ParseArgs must return non-nil")
+	if ps != nil {
+		th.RequireStringEquals("ctl", ps.GetStringValue("command"), "")
+		th.RequireStringEquals("pause", ps.GetStringValue("action"), "")
+	}
+}
+
 func TestParseArgs(t *testing.T) {
 	th := NewTestHelper(t)
 	ap := NewArgumentParser(false)
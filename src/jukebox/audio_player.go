@@ -0,0 +1,125 @@
+package jukebox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+)
+
+const (
+	audioPlayerMpv     = "mpv"
+	audioPlayerFfplay  = "ffplay"
+	audioPlayerMpg123  = "mpg123"
+	audioPlayerMplayer = "mplayer"
+	audioPlayerAfplay  = "afplay"
+	audioPlayerMpcHc   = "mpc-hc"
+	audioPlayerCommand = "command"
+	audioPlayerNull    = "null"
+)
+
+var ErrPlayerUnsupported = errors.New("not supported by audio player")
+var ErrPlaybackFailed = errors.New("audio player failed")
+
+// AudioPlayer plays songs. Play, PlayFrom and PlayStream block until the
+// song has been played or the player is stopped. Stop, Pause and Resume
+// are meant to be called from other goroutines while a song is playing.
+type AudioPlayer interface {
+	Play(filePath string) error
+	// PlayFrom starts playing offsetSeconds into the song. players that
+	// can't do that (SupportsStartOffset is false) play from the start.
+	PlayFrom(filePath string, offsetSeconds int) error
+	SupportsStartOffset() bool
+	// PlayStream plays the song read from input. only available when
+	// ReadsStdin is true.
+	PlayStream(input io.Reader) error
+	Stop() error
+	Pause() error
+	Resume() error
+	ReadsStdin() bool
+}
+
+// ControllableAudioPlayer is implemented by players that are controlled
+// while they play, so that Pause and Resume keep the player (and its
+// position) instead of stopping it.
+type ControllableAudioPlayer interface {
+	AudioPlayer
+	Seek(offsetSeconds int) error
+	SetVolume(percent int) error
+	// Position returns the seconds played and the length of the song
+	Position() (float64, float64, error)
+}
+
+// DurationLimitedAudioPlayer is implemented by players that can be told
+// to stop by themselves after playing for a number of seconds.
+type DurationLimitedAudioPlayer interface {
+	AudioPlayer
+	// SetPlayDuration limits the songs played after it's called to the
+	// number of seconds (0 for no limit). returns false if the player has
+	// no way to limit them.
+	SetPlayDuration(seconds int) bool
+}
+
+// AudioPlayerNames returns the names that can be given to NewAudioPlayer.
+func AudioPlayerNames() []string {
+	return []string{audioPlayerMpv,
+		audioPlayerFfplay,
+		audioPlayerMpg123,
+		audioPlayerMplayer,
+		audioPlayerAfplay,
+		audioPlayerMpcHc,
+		audioPlayerCommand,
+		audioPlayerNull}
+}
+
+// DefaultAudioPlayerName returns the player that is used when none has
+// been configured.
+func DefaultAudioPlayerName() string {
+	osId := strings.ToLower(runtime.GOOS)
+	if strings.HasPrefix(osId, "darwin") {
+		return audioPlayerAfplay
+	} else if strings.HasPrefix(osId, "windows") {
+		return audioPlayerMpcHc
+	} else {
+		return audioPlayerMplayer
+	}
+}
+
+// NewAudioPlayer creates the player with the given name. commandTemplate
+// is only used by the "command" player (e.g., "player --start={offset} {file}").
+// the null player plays each song by sleeping for playLengthSeconds.
+func NewAudioPlayer(playerName string, commandTemplate string, playLengthSeconds int) (AudioPlayer, error) {
+	switch playerName {
+	case audioPlayerMpv:
+		return NewMpvAudioPlayer("mpv", defaultMpvSocketPath()), nil
+	case audioPlayerFfplay:
+		player := NewCommandAudioPlayer("ffplay",
+			[]string{"-nodisp", "-autoexit", "-loglevel", "quiet", "-ss", "{offset}", "{file}"},
+			true)
+		player.SetDurationArgs([]string{"-t", "{duration}"})
+		return player, nil
+	case audioPlayerMpg123:
+		return NewCommandAudioPlayer("mpg123", []string{"-q", "{file}"}, true), nil
+	case audioPlayerMplayer:
+		player := NewCommandAudioPlayer("/usr/bin/mplayer",
+			[]string{"-novideo", "-nolirc", "-really-quiet", "-ss", "{offset}", "{file}"},
+			true)
+		player.SetDurationArgs([]string{"-endpos", "{duration}"})
+		return player, nil
+	case audioPlayerAfplay:
+		player := NewCommandAudioPlayer("afplay", []string{"{file}"}, false)
+		player.SetDurationArgs([]string{"-t", "{duration}"})
+		return player, nil
+	case audioPlayerMpcHc:
+		return NewCommandAudioPlayer("C:\\Program Files\\MPC-HC\\mpc-hc64.exe",
+			[]string{"/play", "/close", "/minimized", "{file}"},
+			false), nil
+	case audioPlayerCommand:
+		return NewCommandAudioPlayerFromTemplate(commandTemplate)
+	case audioPlayerNull:
+		return NewNullAudioPlayer(playLengthSeconds), nil
+	default:
+		return nil, fmt.Errorf("unknown audio player '%s'", playerName)
+	}
+}
@@ -0,0 +1,32 @@
+package jukebox
+
+import "testing"
+
+func TestNewAudioPlayer(t *testing.T) {
+	th := NewTestHelper(t)
+
+	for _, playerName := range []string{audioPlayerMpv, audioPlayerFfplay, audioPlayerMpg123, audioPlayerMplayer} {
+		player, err := NewAudioPlayer(playerName, "", 1)
+		th.Require(err == nil && player != nil, "player must be created: "+playerName)
+		th.Require(player.ReadsStdin(), "player must read stdin: "+playerName)
+	}
+	for _, playerName := range []string{audioPlayerAfplay, audioPlayerMpcHc, audioPlayerNull} {
+		player, err := NewAudioPlayer(playerName, "", 1)
+		th.Require(err == nil && player != nil, "player must be created: "+playerName)
+		th.RequireFalse(player.ReadsStdin(), "player must not read stdin: "+playerName)
+	}
+
+	player, err := NewAudioPlayer(audioPlayerCommand, "player --start={offset} {file}", 1)
+	th.Require(err == nil && player != nil, "command player must be created from template")
+	_, err = NewAudioPlayer(audioPlayerCommand, "", 1)
+	th.Require(err != nil, "command player must require a template")
+	_, err = NewAudioPlayer("winamp", "", 1)
+	th.Require(err != nil, "unknown player must be rejected")
+}
+
+func TestDefaultAudioPlayerName(t *testing.T) {
+	th := NewTestHelper(t)
+	playerName := DefaultAudioPlayerName()
+	_, err := NewAudioPlayer(playerName, "", 1)
+	th.Require(err == nil, "default audio player must be known")
+}
@@ -0,0 +1,65 @@
+package jukebox
+
+import (
+	"os"
+	"strings"
+)
+
+// AudioProperties describes the audio in a file. values that can't be
+// read from the file are 0.
+type AudioProperties struct {
+	DurationMs int64
+	Bitrate    int // kbit/s
+	SampleRate int // Hz
+	Channels   int
+}
+
+func NewAudioProperties() *AudioProperties {
+	var properties AudioProperties
+	properties.DurationMs = 0
+	properties.Bitrate = 0
+	properties.SampleRate = 0
+	properties.Channels = 0
+	return &properties
+}
+
+// ReadAudioProperties reads the audio properties from the headers of an
+// mp3 (Xing/VBRI header or the frames themselves), flac (STREAMINFO) or
+// m4a (mvhd and the sample description) file. returns nil if the file's
+// length can't be worked out.
+func ReadAudioProperties(filePath string) *AudioProperties {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	fileSize := fileInfo.Size()
+
+	var properties *AudioProperties
+	_, extension := PathSplitExt(filePath)
+	switch strings.ToLower(extension) {
+	case ".mp3":
+		properties = readMp3Properties(file, fileSize)
+	case ".flac":
+		properties = readFlacProperties(file, fileSize)
+	case ".m4a", ".mp4", ".m4b":
+		properties = readMp4Properties(file, fileSize)
+	}
+	if properties == nil || properties.DurationMs <= 0 {
+		return nil
+	}
+	return properties
+}
+
+// setBitrate works out the average bitrate from the number of bytes of
+// audio, once the duration is known.
+func (properties *AudioProperties) setBitrate(audioBytes int64) {
+	if properties.DurationMs > 0 && audioBytes > 0 {
+		// bits per millisecond is kbit/s
+		properties.Bitrate = int((audioBytes*8 + properties.DurationMs/2) / properties.DurationMs)
+	}
+}
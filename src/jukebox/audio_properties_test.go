@@ -0,0 +1,45 @@
+package jukebox
+
+import "testing"
+
+func TestNewAudioProperties(t *testing.T) {
+	th := NewTestHelper(t)
+	properties := NewAudioProperties()
+	th.Require(properties.DurationMs == 0 && properties.Bitrate == 0, "new properties must be empty")
+}
+
+func TestReadAudioProperties(t *testing.T) {
+	th := NewTestHelper(t)
+
+	properties := ReadAudioProperties(tagFixturePath("xing.mp3"))
+	th.Require(properties != nil, "mp3 must have properties")
+	if properties != nil {
+		th.Require(properties.DurationMs == 26122, "mp3 duration")
+	}
+
+	properties = ReadAudioProperties(tagFixturePath("vorbis.flac"))
+	th.Require(properties != nil, "flac must have properties")
+	if properties != nil {
+		th.Require(properties.DurationMs == 545500, "flac duration")
+	}
+
+	properties = ReadAudioProperties(tagFixturePath("atoms.m4a"))
+	th.Require(properties != nil, "m4a must have properties")
+	if properties != nil {
+		th.Require(properties.DurationMs == 354320, "m4a duration")
+	}
+
+	th.Require(ReadAudioProperties(tagFixturePath("untagged.mp3")) == nil, "file without frames")
+	th.Require(ReadAudioProperties(tagFixturePath("missing.mp3")) == nil, "missing file")
+}
+
+func TestAudioPropertiesSetBitrate(t *testing.T) {
+	th := NewTestHelper(t)
+	properties := NewAudioProperties()
+	properties.setBitrate(1000)
+	th.Require(properties.Bitrate == 0, "no bitrate without duration")
+
+	properties.DurationMs = 2612
+	properties.setBitrate(41700)
+	th.Require(properties.Bitrate == 128, "bitrate must be rounded to kbit/s")
+}
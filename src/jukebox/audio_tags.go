@@ -0,0 +1,195 @@
+package jukebox
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AudioTags is the metadata embedded in an audio file. numbers that
+// aren't in the tags are 0.
+type AudioTags struct {
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Title       string
+	TrackNumber int
+	DiscNumber  int
+	Year        int
+	Genre       string
+}
+
+func NewAudioTags() *AudioTags {
+	var tags AudioTags
+	tags.Artist = ""
+	tags.AlbumArtist = ""
+	tags.Album = ""
+	tags.Title = ""
+	tags.TrackNumber = 0
+	tags.DiscNumber = 0
+	tags.Year = 0
+	tags.Genre = ""
+	return &tags
+}
+
+// ReadAudioTags reads the tags of an mp3 (ID3v2 or ID3v1), flac (Vorbis
+// comments) or m4a (MP4 atoms) file. returns nil if the file has no tags
+// that can be read.
+func ReadAudioTags(filePath string) *AudioTags {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	fileSize := fileInfo.Size()
+
+	var tags *AudioTags
+	_, extension := PathSplitExt(filePath)
+	switch strings.ToLower(extension) {
+	case ".mp3":
+		tags = readId3Tags(file, fileSize)
+	case ".flac":
+		tags = readFlacTags(file)
+	case ".m4a", ".mp4", ".m4b":
+		tags = readMp4Tags(file, fileSize)
+	}
+	if tags == nil || tags.isEmpty() {
+		return nil
+	}
+	return tags
+}
+
+func (tags *AudioTags) isEmpty() bool {
+	return len(tags.Artist) == 0 && len(tags.AlbumArtist) == 0 && len(tags.Album) == 0 &&
+		len(tags.Title) == 0 && tags.TrackNumber == 0 && tags.DiscNumber == 0 &&
+		tags.Year == 0 && len(tags.Genre) == 0
+}
+
+// SongArtist returns the artist to file the song under, which is the
+// album artist when the song's own artist isn't known.
+func (tags *AudioTags) SongArtist() string {
+	if len(tags.Artist) > 0 {
+		return tags.Artist
+	}
+	return tags.AlbumArtist
+}
+
+// setField sets the field that a tag is for. name is the tag's name
+// converted to the names used here (e.g., "title"). fields that already
+// have a value keep it.
+func (tags *AudioTags) setField(name string, value string) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return
+	}
+	switch name {
+	case "artist":
+		setIfEmpty(&tags.Artist, value)
+	case "album-artist":
+		setIfEmpty(&tags.AlbumArtist, value)
+	case "album":
+		setIfEmpty(&tags.Album, value)
+	case "title":
+		setIfEmpty(&tags.Title, value)
+	case "track":
+		if tags.TrackNumber == 0 {
+			tags.TrackNumber = parseTagNumber(value)
+		}
+	case "disc":
+		if tags.DiscNumber == 0 {
+			tags.DiscNumber = parseTagNumber(value)
+		}
+	case "year":
+		if tags.Year == 0 {
+			tags.Year = parseTagYear(value)
+		}
+	case "genre":
+		setIfEmpty(&tags.Genre, value)
+	}
+}
+
+func setIfEmpty(field *string, value string) {
+	if len(*field) == 0 {
+		*field = value
+	}
+}
+
+// parseTagNumber parses numbers like "3" and "3/12" (track 3 of 12).
+// returns 0 if there's no number.
+func parseTagNumber(value string) int {
+	value = strings.TrimSpace(value)
+	if posSlash := strings.Index(value, "/"); posSlash >= 0 {
+		value = value[:posSlash]
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || number < 0 {
+		return 0
+	}
+	return number
+}
+
+// parseTagYear returns the year from a date like "1971" or "1971-08-14".
+func parseTagYear(value string) int {
+	value = strings.TrimSpace(value)
+	if len(value) < 4 {
+		return 0
+	}
+	year, err := strconv.Atoi(value[:4])
+	if err != nil {
+		return 0
+	}
+	return year
+}
+
+// latin1String converts ISO-8859-1 text (which is how ID3v1 and some
+// ID3v2 text is stored) to a string.
+func latin1String(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// trimTagText removes the padding that fixed-size and null-terminated
+// tag text has.
+func trimTagText(text string) string {
+	return strings.TrimRightFunc(strings.TrimLeft(text, " "), func(r rune) bool {
+		return r == 0 || unicode.IsSpace(r)
+	})
+}
+
+// id3Genres are the genres that ID3v1 (and ID3v2 genres like "(17)")
+// refer to by number, including the Winamp extensions.
+var id3Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop",
+	"Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap",
+	"Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska", "Death Metal", "Pranks",
+	"Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance",
+	"Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"Alternative Rock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock",
+	"Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle",
+	"Native American", "Cabaret", "New Wave", "Psychedelic", "Rave", "Showtunes", "Trailer", "Lo-Fi",
+	"Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebop", "Latin", "Revival",
+	"Celtic", "Bluegrass", "Avantgarde", "Gothic Rock", "Progressive Rock", "Psychedelic Rock", "Symphonic Rock", "Slow Rock",
+	"Big Band", "Chorus", "Easy Listening", "Acoustic", "Humour", "Speech", "Chanson", "Opera",
+	"Chamber Music", "Sonata", "Symphony", "Booty Bass", "Primus", "Porn Groove", "Satire", "Slow Jam",
+	"Club", "Tango", "Samba", "Folklore", "Ballad", "Power Ballad", "Rhythmic Soul", "Freestyle",
+	"Duet", "Punk Rock", "Drum Solo", "A Cappella", "Euro-House", "Dance Hall",
+}
+
+// id3GenreName returns the name of a numbered genre, or "" if there's no
+// such genre.
+func id3GenreName(genreNumber int) string {
+	if genreNumber < 0 || genreNumber >= len(id3Genres) {
+		return ""
+	}
+	return id3Genres[genreNumber]
+}
@@ -0,0 +1,91 @@
+package jukebox
+
+import "testing"
+
+// tagFixturePath returns the path of a file in testdata/tags, which has
+// small audio files with hand-built tags.
+func tagFixturePath(fileName string) string {
+	return PathJoin(PathJoin("testdata", "tags"), fileName)
+}
+
+func TestNewAudioTags(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := NewAudioTags()
+	th.Require(tags.isEmpty(), "new tags must be empty")
+}
+
+func TestReadAudioTags(t *testing.T) {
+	th := NewTestHelper(t)
+
+	tags := ReadAudioTags(tagFixturePath("id3v23.mp3"))
+	th.Require(tags != nil, "mp3 must have tags")
+	if tags != nil {
+		th.RequireStringEquals(tags.Title, "Behind Blue Eyes", "mp3 title")
+	}
+
+	tags = ReadAudioTags(tagFixturePath("vorbis.flac"))
+	th.Require(tags != nil, "flac must have tags")
+	if tags != nil {
+		th.RequireStringEquals(tags.Title, "So What", "flac title")
+	}
+
+	tags = ReadAudioTags(tagFixturePath("atoms.m4a"))
+	th.Require(tags != nil, "m4a must have tags")
+	if tags != nil {
+		th.RequireStringEquals(tags.Title, "Bohemian Rhapsody", "m4a title")
+	}
+
+	th.Require(ReadAudioTags(tagFixturePath("untagged.mp3")) == nil, "untagged file must not have tags")
+	th.Require(ReadAudioTags(tagFixturePath("missing.mp3")) == nil, "missing file must not have tags")
+}
+
+func TestAudioTagsSetField(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := NewAudioTags()
+	tags.setField("title", "  My Wife ")
+	tags.setField("title", "Bargain")
+	th.RequireStringEquals(tags.Title, "My Wife", "first title must be kept and trimmed")
+
+	tags.setField("track", "3/12")
+	tags.setField("disc", "2")
+	tags.setField("year", "1971-08-14")
+	tags.setField("genre", "")
+	th.Require(tags.TrackNumber == 3, "track number must be parsed")
+	th.Require(tags.DiscNumber == 2, "disc number must be parsed")
+	th.Require(tags.Year == 1971, "year must be parsed from date")
+	th.RequireStringEquals(tags.Genre, "", "empty values must be ignored")
+}
+
+func TestAudioTagsSongArtist(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := NewAudioTags()
+	tags.AlbumArtist = "Various Artists"
+	th.RequireStringEquals(tags.SongArtist(), "Various Artists", "album artist when there's no artist")
+	tags.Artist = "The Who"
+	th.RequireStringEquals(tags.SongArtist(), "The Who", "artist when there is one")
+}
+
+func TestParseTagNumber(t *testing.T) {
+	th := NewTestHelper(t)
+	th.Require(parseTagNumber("7") == 7, "plain number")
+	th.Require(parseTagNumber(" 3 / 12 ") == 3, "number with total")
+	th.Require(parseTagNumber("A1") == 0, "not a number")
+	th.Require(parseTagNumber("") == 0, "empty")
+}
+
+func TestParseTagYear(t *testing.T) {
+	th := NewTestHelper(t)
+	th.Require(parseTagYear("1971") == 1971, "year")
+	th.Require(parseTagYear("1975-11-21T08:00:00Z") == 1975, "timestamp")
+	th.Require(parseTagYear("71") == 0, "short year")
+	th.Require(parseTagYear("unknown") == 0, "not a year")
+}
+
+func TestId3GenreName(t *testing.T) {
+	th := NewTestHelper(t)
+	th.RequireStringEquals(id3GenreName(0), "Blues", "first genre")
+	th.RequireStringEquals(id3GenreName(17), "Rock", "rock")
+	th.RequireStringEquals(id3GenreName(125), "Dance Hall", "last genre")
+	th.RequireStringEquals(id3GenreName(255), "", "no genre")
+	th.RequireStringEquals(id3GenreName(-1), "", "negative genre")
+}
@@ -0,0 +1,235 @@
+package jukebox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	playerArgFile     = "{file}"
+	playerArgOffset   = "{offset}"
+	playerArgDuration = "{duration}"
+	playerArgStdin    = "-"
+)
+
+// CommandAudioPlayer plays each song by running an external program.
+// the arguments may contain {file} (the song to play) and {offset}
+// (seconds into the song to start at). if there is no {file} argument,
+// the song is added as the last argument.
+type CommandAudioPlayer struct {
+	exeFileName  string
+	args         []string
+	durationArgs []string
+	playDuration int
+	readsStdin   bool
+	mutex        sync.Mutex
+	cmd          *exec.Cmd
+	stopped      bool
+}
+
+func NewCommandAudioPlayer(exeFileName string, args []string, readsStdin bool) *CommandAudioPlayer {
+	var player CommandAudioPlayer
+	player.exeFileName = exeFileName
+	player.args = args
+	player.readsStdin = readsStdin
+	player.durationArgs = nil
+	player.playDuration = 0
+	return &player
+}
+
+// SetDurationArgs sets the arguments that limit how long the player
+// plays, where {duration} is the number of seconds. they're put ahead of
+// the other arguments when there's a play duration.
+func (player *CommandAudioPlayer) SetDurationArgs(durationArgs []string) {
+	player.durationArgs = durationArgs
+}
+
+func (player *CommandAudioPlayer) SetPlayDuration(seconds int) bool {
+	if len(player.durationArgs) == 0 {
+		return false
+	}
+	player.playDuration = seconds
+	return true
+}
+
+// NewCommandAudioPlayerFromTemplate creates a player from a command line
+// such as "player --start={offset} {file}". double quotes can be used
+// around arguments that contain spaces.
+func NewCommandAudioPlayerFromTemplate(commandTemplate string) (*CommandAudioPlayer, error) {
+	fields, err := splitCommandLine(commandTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("audio player command is missing")
+	}
+	return NewCommandAudioPlayer(fields[0], fields[1:], false), nil
+}
+
+// splitCommandLine splits on white space, keeping text in double quotes together.
+func splitCommandLine(commandLine string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	inQuotes := false
+
+	for _, ch := range commandLine {
+		if ch == '"' {
+			inQuotes = !inQuotes
+			inField = true
+		} else if !inQuotes && (ch == ' ' || ch == '\t') {
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		} else {
+			field.WriteRune(ch)
+			inField = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unbalanced quotes in '%s'", commandLine)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func (player *CommandAudioPlayer) ReadsStdin() bool {
+	return player.readsStdin
+}
+
+// commandArgs fills in the song and offset placeholders.
+func (player *CommandAudioPlayer) commandArgs(songInput string, offsetSeconds int) []string {
+	var args []string
+	haveFileArg := false
+	offset := strconv.Itoa(offsetSeconds)
+
+	if player.playDuration > 0 {
+		duration := strconv.Itoa(player.playDuration)
+		for _, arg := range player.durationArgs {
+			args = append(args, strings.ReplaceAll(arg, playerArgDuration, duration))
+		}
+	}
+	for _, arg := range player.args {
+		if strings.Contains(arg, playerArgFile) {
+			haveFileArg = true
+			arg = strings.ReplaceAll(arg, playerArgFile, songInput)
+		}
+		args = append(args, strings.ReplaceAll(arg, playerArgOffset, offset))
+	}
+	if !haveFileArg {
+		args = append(args, songInput)
+	}
+	return args
+}
+
+func (player *CommandAudioPlayer) run(songInput string, input io.Reader, offsetSeconds int) error {
+	cmd := exec.Command(player.exeFileName, player.commandArgs(songInput, offsetSeconds)...)
+	cmd.Stdin = input
+
+	player.mutex.Lock()
+	err := cmd.Start()
+	if err == nil {
+		player.cmd = cmd
+		player.stopped = false
+	}
+	player.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	errWait := cmd.Wait()
+
+	player.mutex.Lock()
+	player.cmd = nil
+	stopped := player.stopped
+	player.mutex.Unlock()
+
+	if errWait != nil {
+		var exitErr *exec.ExitError
+		if errors.As(errWait, &exitErr) {
+			// the player exits abnormally when it's stopped or killed
+			if stopped || exitedOnSignal(exitErr) {
+				return nil
+			}
+			return fmt.Errorf("%w: %v", ErrPlaybackFailed, errWait)
+		}
+	}
+	return errWait
+}
+
+func exitedOnSignal(exitErr *exec.ExitError) bool {
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled()
+}
+
+func (player *CommandAudioPlayer) Play(filePath string) error {
+	return player.run(filePath, nil, 0)
+}
+
+func (player *CommandAudioPlayer) PlayFrom(filePath string, offsetSeconds int) error {
+	return player.run(filePath, nil, offsetSeconds)
+}
+
+// SupportsStartOffset reports whether the player's arguments have a
+// place for the start offset.
+func (player *CommandAudioPlayer) SupportsStartOffset() bool {
+	for _, arg := range player.args {
+		if strings.Contains(arg, playerArgOffset) {
+			return true
+		}
+	}
+	return false
+}
+
+func (player *CommandAudioPlayer) PlayStream(input io.Reader) error {
+	if !player.readsStdin {
+		return fmt.Errorf("%w: playing from stdin", ErrPlayerUnsupported)
+	}
+	return player.run(playerArgStdin, input, 0)
+}
+
+func (player *CommandAudioPlayer) IsPlaying() bool {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.cmd != nil
+}
+
+func (player *CommandAudioPlayer) signal(sig syscall.Signal) error {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	if player.cmd == nil || player.cmd.Process == nil {
+		return nil
+	}
+	return player.cmd.Process.Signal(sig)
+}
+
+func (player *CommandAudioPlayer) Stop() error {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	if player.cmd == nil || player.cmd.Process == nil {
+		return nil
+	}
+	player.stopped = true
+	return player.cmd.Process.Kill()
+}
+
+func (player *CommandAudioPlayer) Pause() error {
+	return player.signal(syscall.SIGSTOP)
+}
+
+func (player *CommandAudioPlayer) Resume() error {
+	return player.signal(syscall.SIGCONT)
+}
@@ -0,0 +1,104 @@
+package jukebox
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestNewCommandAudioPlayerFromTemplate(t *testing.T) {
+	th := NewTestHelper(t)
+
+	player, err := NewCommandAudioPlayerFromTemplate(`"C:\Program Files\Player\player.exe" --start={offset} {file}`)
+	th.Require(err == nil, "template must be parsed")
+	th.RequireStringEquals(player.exeFileName, `C:\Program Files\Player\player.exe`,
+		"quoted executable must be kept together")
+	args := player.commandArgs("song.mp3", 42)
+	th.Require(len(args) == 2, "template arguments must be used")
+	th.RequireStringEquals(args[0], "--start=42", "offset must be filled in")
+	th.RequireStringEquals(args[1], "song.mp3", "file must be filled in")
+
+	_, err = NewCommandAudioPlayerFromTemplate(`"player --quiet`)
+	th.Require(err != nil, "unbalanced quotes must be rejected")
+}
+
+func TestCommandAudioPlayerArgs(t *testing.T) {
+	th := NewTestHelper(t)
+	player := NewCommandAudioPlayer("player", []string{"-q"}, false)
+	args := player.commandArgs("song.mp3", 0)
+	th.Require(len(args) == 2 && args[1] == "song.mp3", "file must be appended when not in template")
+	th.RequireFalse(player.SupportsStartOffset(), "player without offset argument can't start at offset")
+
+	player = NewCommandAudioPlayer("mpv", []string{"--start={offset}", "{file}"}, true)
+	th.Require(player.SupportsStartOffset(), "player with offset argument must start at offset")
+	args = player.commandArgs("song.mp3", 95)
+	th.RequireStringEquals(args[0], "--start=95", "player must be started at offset")
+}
+
+func TestCommandAudioPlayerDuration(t *testing.T) {
+	th := NewTestHelper(t)
+	player := NewCommandAudioPlayer("player", []string{"{file}"}, false)
+	th.RequireFalse(player.SetPlayDuration(10), "player without duration arguments can't limit play")
+
+	player = NewCommandAudioPlayer("ffplay", []string{"-ss", "{offset}", "{file}"}, true)
+	player.SetDurationArgs([]string{"-t", "{duration}"})
+	th.Require(player.SetPlayDuration(10), "player with duration arguments must limit play")
+	args := player.commandArgs("song.mp3", 30)
+	th.Require(len(args) == 5, "duration arguments must be added")
+	th.RequireStringEquals(args[0]+" "+args[1], "-t 10", "duration must be filled in")
+
+	player.SetPlayDuration(0)
+	th.Require(len(player.commandArgs("song.mp3", 30)) == 3, "duration arguments must be left out without limit")
+}
+
+func TestCommandAudioPlayerStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	th := NewTestHelper(t)
+	player := NewCommandAudioPlayer("/bin/sh", []string{"-c", "sleep 30", "{file}"}, false)
+
+	playDone := make(chan error)
+	go func() {
+		playDone <- player.Play("song.mp3")
+	}()
+
+	// wait for the player process to start
+	for i := 0; i < 500; i++ {
+		player.mutex.Lock()
+		started := player.cmd != nil
+		player.mutex.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	th.Require(player.Pause() == nil, "Pause must succeed")
+	th.Require(player.Resume() == nil, "Resume must succeed")
+	th.Require(player.Stop() == nil, "Stop must succeed")
+	select {
+	case err := <-playDone:
+		th.Require(err == nil, "stopped player must not report an error")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop must end Play")
+	}
+
+	missing := NewCommandAudioPlayer(PathJoin(t.TempDir(), "missing-player"), nil, false)
+	th.Require(missing.Play("song.mp3") != nil, "missing player must report an error")
+	th.Require(missing.PlayStream(nil) != nil, "player that doesn't read stdin must not stream")
+}
+
+func TestCommandAudioPlayerFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	th := NewTestHelper(t)
+	player := NewCommandAudioPlayer("/bin/sh", []string{"-c", "exit 2", "{file}"}, false)
+	err := player.Play("song.mp3")
+	th.Require(errors.Is(err, ErrPlaybackFailed), "player that exits with an error must report it")
+
+	player = NewCommandAudioPlayer("/bin/sh", []string{"-c", "kill -TERM $$", "{file}"}, false)
+	th.Require(player.Play("song.mp3") == nil, "player killed by a signal must not report an error")
+}
@@ -0,0 +1,71 @@
+package jukebox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+const controlRequestTimeout = 10 * time.Second
+
+var ErrJukeboxNotRunning = errors.New("no jukebox running")
+
+// ControlClient sends requests to a jukebox's control socket.
+type ControlClient struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	encoder *json.Encoder
+}
+
+// NewControlClient connects to the control socket of a running jukebox.
+// the pid file next to the socket is used to tell a jukebox that isn't
+// running (or a socket left behind by one) from one that can't be reached.
+func NewControlClient(socketPath string) (*ControlClient, error) {
+	conn, err := net.DialTimeout("unix", socketPath, controlDialTimeout)
+	if err != nil {
+		pid := RunningJukeboxPid(PathJoin(filepath.Dir(socketPath), jukeboxPidFileName))
+		if pid == 0 {
+			if FileExists(socketPath) {
+				return nil, fmt.Errorf("%w (stale control socket '%s')", ErrJukeboxNotRunning, socketPath)
+			}
+			return nil, ErrJukeboxNotRunning
+		}
+		return nil, fmt.Errorf("unable to connect to jukebox (pid %d) on '%s': %w", pid, socketPath, err)
+	}
+
+	var client ControlClient
+	client.conn = conn
+	client.reader = bufio.NewReader(conn)
+	client.encoder = json.NewEncoder(conn)
+	return &client, nil
+}
+
+func (client *ControlClient) Close() {
+	client.conn.Close()
+}
+
+// Send sends the request and waits for the response. a response that
+// reports failure is returned as an error.
+func (client *ControlClient) Send(request *ControlRequest) (*ControlResponse, error) {
+	client.conn.SetDeadline(time.Now().Add(controlRequestTimeout))
+	if err := client.encoder.Encode(request); err != nil {
+		return nil, err
+	}
+	line, err := client.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var response ControlResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		return nil, fmt.Errorf("invalid response from jukebox: %w", err)
+	}
+	if !response.Ok {
+		return &response, errors.New(response.Error)
+	}
+	return &response, nil
+}
@@ -0,0 +1,92 @@
+package jukebox
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestControlClient(t *testing.T) {
+	th := NewTestHelper(t)
+	_, controlServer := newTestControlServer(t)
+
+	client, err := NewControlClient(controlServer.socketPath)
+	th.Require(err == nil, "client must connect")
+	defer client.Close()
+
+	response, err := client.Send(&ControlRequest{Command: ControlStatus})
+	th.Require(err == nil && response.Status != nil, "status must succeed")
+
+	response, err = client.Send(&ControlRequest{Command: ControlEnqueue, Song: "missing.mp3"})
+	th.Require(err != nil, "failed request must return error")
+	th.RequireStringEquals(err.Error(), "unknown song 'missing.mp3'", "error must come from jukebox")
+	th.RequireFalse(response.Ok, "failed response must be returned")
+}
+
+func TestControlClientNotRunning(t *testing.T) {
+	th := NewTestHelper(t)
+	testDir := t.TempDir()
+	socketPath := PathJoin(testDir, defaultControlSocket)
+	pidFilePath := PathJoin(testDir, jukeboxPidFileName)
+
+	_, err := NewControlClient(socketPath)
+	th.Require(errors.Is(err, ErrJukeboxNotRunning), "missing socket must mean no jukebox")
+
+	listener, _ := net.Listen("unix", socketPath)
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	FileWriteAllText(pidFilePath, "2147483646\n")
+	_, err = NewControlClient(socketPath)
+	th.Require(errors.Is(err, ErrJukeboxNotRunning), "stale socket must mean no jukebox")
+	th.RequireFalse(FileExists(pidFilePath), "stale pid file must be removed")
+
+	WritePidFile(pidFilePath)
+	_, err = NewControlClient(socketPath)
+	th.Require(err != nil && !errors.Is(err, ErrJukeboxNotRunning),
+		"running jukebox that can't be reached must not look stopped")
+}
+
+func TestControlPlayingJukebox(t *testing.T) {
+	th := NewTestHelper(t)
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+	options.HttpAddress = ""
+	jukebox := newTestJukebox(t, options)
+	importTestSongs(t, jukebox, testApiSongs)
+	jukebox.songPlayLengthSeconds = 60
+
+	playDone := make(chan bool)
+	go func() {
+		jukebox.PlaySongs(false, "", "")
+		playDone <- true
+	}()
+
+	var client *ControlClient
+	for i := 0; i < 500 && client == nil; i++ {
+		client, _ = NewControlClient(defaultControlSocket)
+		time.Sleep(10 * time.Millisecond)
+	}
+	th.Require(client != nil, "client must connect to playing jukebox")
+	defer client.Close()
+
+	th.RequireFalse(NewControlServer(jukebox, defaultControlSocket).Start(),
+		"second control server must not take over socket")
+
+	_, err := client.Send(&ControlRequest{Command: ControlNext})
+	th.Require(err == nil, "next must succeed")
+	for i := 0; i < 500 && jukebox.Status().Index != 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	th.Require(jukebox.Status().Index == 1, "next must move to next song")
+
+	_, err = client.Send(&ControlRequest{Command: ControlStop})
+	th.Require(err == nil, "stop must succeed")
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop must end play")
+	}
+	th.RequireFalse(FileExists(defaultControlSocket), "socket must be removed after play")
+	th.RequireFalse(FileExists(jukeboxPidFileName), "pid file must be removed after play")
+}
@@ -0,0 +1,241 @@
+package jukebox
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultControlSocket = "jukebox.sock"
+	controlDialTimeout   = time.Second
+
+	ControlPause    = "pause"
+	ControlResume   = "resume"
+	ControlNext     = "next"
+	ControlPrevious = "previous"
+	ControlStop     = "stop"
+	ControlStatus   = "status"
+	ControlEnqueue  = "enqueue"
+	ControlPlayNext = "play-next"
+	ControlRemove   = "remove"
+	ControlMove     = "move"
+	ControlClear    = "clear"
+	ControlQueue    = "queue"
+	ControlVolume   = "volume"
+	ControlSleep    = "sleep"
+	ControlWake     = "cancel-sleep"
+)
+
+// ControlRequest is one line of the control protocol sent to the jukebox.
+type ControlRequest struct {
+	Command  string `json:"command"`
+	Song     string `json:"song,omitempty"`
+	Level    int    `json:"level,omitempty"`
+	Position int    `json:"position,omitempty"`
+	To       int    `json:"to,omitempty"`
+	Minutes  int    `json:"minutes,omitempty"`
+}
+
+// ControlResponse is the line that the jukebox sends back for a request.
+type ControlResponse struct {
+	Ok     bool          `json:"ok"`
+	Error  string        `json:"error,omitempty"`
+	Status *PlayerStatus `json:"status,omitempty"`
+	Queue  []*SongInfo   `json:"queue,omitempty"`
+}
+
+// ControlServer lets other processes control a playing jukebox over a
+// unix domain socket. requests and responses are JSON objects, one per
+// line, and a connection can send any number of requests.
+type ControlServer struct {
+	jukebox     *Jukebox
+	socketPath  string
+	listener    net.Listener
+	mutex       sync.Mutex
+	connections map[net.Conn]bool
+	waitGroup   sync.WaitGroup
+}
+
+func NewControlServer(jukebox *Jukebox, socketPath string) *ControlServer {
+	var controlServer ControlServer
+	controlServer.jukebox = jukebox
+	controlServer.socketPath = socketPath
+	controlServer.listener = nil
+	controlServer.connections = make(map[net.Conn]bool)
+	return &controlServer
+}
+
+// Start listens on the socket and serves requests in the background. a
+// socket left behind by a jukebox that's no longer running is replaced,
+// but returns false if another jukebox is listening on it.
+func (controlServer *ControlServer) Start() bool {
+	if FileExists(controlServer.socketPath) {
+		conn, err := net.DialTimeout("unix", controlServer.socketPath, controlDialTimeout)
+		if err == nil {
+			conn.Close()
+			fmt.Printf("error: another jukebox is listening on '%s'\n", controlServer.socketPath)
+			return false
+		}
+		fmt.Printf("removing stale control socket '%s'\n", controlServer.socketPath)
+		DeleteFile(controlServer.socketPath)
+	}
+
+	listener, err := net.Listen("unix", controlServer.socketPath)
+	if err != nil {
+		fmt.Printf("error: unable to listen on control socket '%s'\n", controlServer.socketPath)
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	// only the owner gets to control the jukebox
+	os.Chmod(controlServer.socketPath, 0600)
+	controlServer.listener = listener
+
+	controlServer.waitGroup.Add(1)
+	go controlServer.acceptConnections()
+	return true
+}
+
+// Stop closes the socket and any connections to it.
+func (controlServer *ControlServer) Stop() {
+	if controlServer.listener == nil {
+		return
+	}
+	controlServer.listener.Close()
+
+	controlServer.mutex.Lock()
+	for conn := range controlServer.connections {
+		conn.Close()
+	}
+	controlServer.mutex.Unlock()
+
+	controlServer.waitGroup.Wait()
+	controlServer.listener = nil
+	DeleteFile(controlServer.socketPath)
+}
+
+func (controlServer *ControlServer) acceptConnections() {
+	defer controlServer.waitGroup.Done()
+	for {
+		conn, err := controlServer.listener.Accept()
+		if err != nil {
+			return
+		}
+		controlServer.mutex.Lock()
+		controlServer.connections[conn] = true
+		controlServer.mutex.Unlock()
+
+		controlServer.waitGroup.Add(1)
+		go controlServer.serveConnection(conn)
+	}
+}
+
+func (controlServer *ControlServer) serveConnection(conn net.Conn) {
+	defer controlServer.waitGroup.Done()
+	defer func() {
+		controlServer.mutex.Lock()
+		delete(controlServer.connections, conn)
+		controlServer.mutex.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var response *ControlResponse
+		var request ControlRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response = controlError("request must be a JSON object")
+		} else {
+			response = controlServer.handleRequest(&request)
+		}
+		if encoder.Encode(response) != nil {
+			return
+		}
+	}
+}
+
+func controlError(message string) *ControlResponse {
+	var response ControlResponse
+	response.Ok = false
+	response.Error = message
+	return &response
+}
+
+// handleRequest carries out the request and returns the response for it.
+// requests respond with the status, and requests for the queue or that
+// change it also respond with the upcoming songs.
+func (controlServer *ControlServer) handleRequest(request *ControlRequest) *ControlResponse {
+	jukebox := controlServer.jukebox
+
+	switch request.Command {
+	case ControlPause:
+		jukebox.Pause()
+	case ControlResume:
+		jukebox.Resume()
+	case ControlNext:
+		jukebox.AdvanceToNextSong()
+	case ControlPrevious:
+		if !jukebox.PreviousSong() {
+			return controlError("no previous song")
+		}
+	case ControlStop:
+		jukebox.StopPlay()
+	case ControlStatus:
+	case ControlEnqueue:
+		if len(request.Song) == 0 {
+			return controlError("song must be given")
+		}
+		if !jukebox.EnqueueSong(request.Song) {
+			return controlError(fmt.Sprintf("unknown song '%s'", request.Song))
+		}
+	case ControlPlayNext:
+		if len(request.Song) == 0 {
+			return controlError("song must be given")
+		}
+		if !jukebox.PlayNext(request.Song) {
+			return controlError(fmt.Sprintf("unknown song '%s'", request.Song))
+		}
+	case ControlRemove:
+		if !jukebox.RemoveFromQueue(request.Position) {
+			return controlError(fmt.Sprintf("no song at position %d", request.Position))
+		}
+	case ControlMove:
+		if !jukebox.MoveInQueue(request.Position, request.To) {
+			return controlError("position and to must be positions in the queue")
+		}
+	case ControlClear:
+		jukebox.ClearQueue()
+	case ControlQueue:
+	case ControlVolume:
+		if request.Level < 0 || request.Level > 100 {
+			return controlError("level must be 0-100")
+		}
+		if !jukebox.SetVolume(request.Level) {
+			return controlError("unable to set volume")
+		}
+	case ControlSleep:
+		if !jukebox.SetSleepTimer(request.Minutes) {
+			return controlError("minutes must be a positive integer")
+		}
+	case ControlWake:
+		if !jukebox.CancelSleepTimer() {
+			return controlError("no sleep timer is set")
+		}
+	default:
+		return controlError(fmt.Sprintf("unknown command '%s'", request.Command))
+	}
+
+	var response ControlResponse
+	response.Ok = true
+	response.Status = jukebox.Status()
+	switch request.Command {
+	case ControlEnqueue, ControlPlayNext, ControlRemove, ControlMove, ControlClear, ControlQueue:
+		response.Queue = jukebox.UpcomingSongs()
+	}
+	return &response
+}
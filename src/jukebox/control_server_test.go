@@ -0,0 +1,153 @@
+package jukebox
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+)
+
+// newTestControlServer returns a jukebox with a few songs and a control
+// server for it that has been started.
+func newTestControlServer(t *testing.T) (*Jukebox, *ControlServer) {
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+	options.HttpAddress = ""
+	options.ControlSocket = ""
+	jukebox := newTestJukebox(t, options)
+	importTestSongs(t, jukebox, testApiSongs)
+
+	controlServer := NewControlServer(jukebox, PathJoin(t.TempDir(), defaultControlSocket))
+	if !controlServer.Start() {
+		t.Fatal("unable to start control server")
+	}
+	t.Cleanup(controlServer.Stop)
+	return jukebox, controlServer
+}
+
+func TestControlServerHandleRequest(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, controlServer := newTestControlServer(t)
+
+	response := controlServer.handleRequest(&ControlRequest{Command: ControlStatus})
+	th.Require(response.Ok && response.Status != nil, "status must succeed")
+	th.Require(response.Status.Song == nil, "nothing must be playing")
+
+	response = controlServer.handleRequest(&ControlRequest{Command: "rewind"})
+	th.RequireFalse(response.Ok, "unknown command must fail")
+	th.RequireStringEquals(response.Error, "unknown command 'rewind'", "error must name command")
+
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlEnqueue}).Ok,
+		"enqueue without song must fail")
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlEnqueue, Song: "missing.mp3"}).Ok,
+		"enqueue of unknown song must fail")
+
+	subscription := jukebox.Events().Subscribe()
+	defer subscription.Unsubscribe()
+	response = controlServer.handleRequest(&ControlRequest{Command: ControlEnqueue,
+		Song: "The-Who--Whos-Next--My-Wife.mp3"})
+	th.Require(response.Ok, "enqueue must succeed")
+	th.Require(response.Status.NumberSongs == 1, "enqueued song must be added")
+	th.RequireStringEquals((<-subscription.Events()).Type, EventQueueChanged, "enqueue must change queue")
+
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlVolume, Level: 101}).Ok,
+		"volume above 100 must fail")
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlPrevious}).Ok,
+		"previous must fail when nothing is playing")
+}
+
+func TestControlServerQueue(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, controlServer := newTestControlServer(t)
+	jukebox.queue.SetRepeatMode(RepeatNone)
+	jukebox.queue.Reset(jukebox.jukeboxDb.retrieveSongs("", ""))
+
+	response := controlServer.handleRequest(&ControlRequest{Command: ControlQueue})
+	th.Require(response.Ok && len(response.Queue) == 2, "queue must list upcoming songs")
+	last := response.Queue[1].Uid
+
+	response = controlServer.handleRequest(&ControlRequest{Command: ControlMove, Position: 1, To: 0})
+	th.Require(response.Ok, "move must succeed")
+	th.RequireStringEquals(response.Queue[0].Uid, last, "moved song must be first")
+
+	response = controlServer.handleRequest(&ControlRequest{Command: ControlRemove, Position: 0})
+	th.Require(response.Ok && len(response.Queue) == 1, "remove must take song out of queue")
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlRemove, Position: 3}).Ok,
+		"remove past the end must fail")
+
+	response = controlServer.handleRequest(&ControlRequest{Command: ControlPlayNext, Song: last})
+	th.Require(response.Ok, "play-next must succeed")
+	th.RequireStringEquals(response.Queue[0].Uid, last, "play-next song must be first")
+
+	response = controlServer.handleRequest(&ControlRequest{Command: ControlClear})
+	th.Require(response.Ok && len(response.Queue) == 0, "clear must empty queue")
+	th.Require(response.Status.Song != nil, "clear must keep current song")
+}
+
+func TestControlServerProtocol(t *testing.T) {
+	th := NewTestHelper(t)
+	_, controlServer := newTestControlServer(t)
+
+	conn, err := net.Dial("unix", controlServer.socketPath)
+	th.Require(err == nil, "must connect to control socket")
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	readResponse := func() *ControlResponse {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("unable to read response: %v", err)
+		}
+		var response ControlResponse
+		if json.Unmarshal(line, &response) != nil {
+			t.Fatalf("response must be JSON: %s", line)
+		}
+		return &response
+	}
+
+	conn.Write([]byte("pause please\n"))
+	th.RequireFalse(readResponse().Ok, "request that isn't JSON must fail")
+
+	// a connection can carry more than one request
+	conn.Write([]byte(`{"command":"status"}` + "\n" + `{"command":"enqueue","song":"The-Who--Whos-Next--My-Wife.mp3"}` + "\n"))
+	th.Require(readResponse().Ok, "status must succeed")
+	response := readResponse()
+	th.Require(response.Ok && response.Status.NumberSongs == 1, "enqueue must succeed")
+}
+
+func TestControlServerSocketFile(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, controlServer := newTestControlServer(t)
+
+	th.RequireFalse(NewControlServer(jukebox, controlServer.socketPath).Start(),
+		"Start must fail while another server is listening")
+
+	controlServer.Stop()
+	th.RequireFalse(FileExists(controlServer.socketPath), "Stop must remove socket")
+
+	// leave a socket behind like a jukebox that crashed
+	listener, err := net.Listen("unix", controlServer.socketPath)
+	th.Require(err == nil, "must create socket")
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	th.Require(FileExists(controlServer.socketPath), "stale socket must exist")
+
+	restarted := NewControlServer(jukebox, controlServer.socketPath)
+	th.Require(restarted.Start(), "Start must replace stale socket")
+	restarted.Stop()
+}
+
+func TestControlServerSleepTimer(t *testing.T) {
+	th := NewTestHelper(t)
+	_, controlServer := newTestControlServer(t)
+
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlSleep}).Ok,
+		"sleep without minutes must fail")
+	th.RequireFalse(controlServer.handleRequest(&ControlRequest{Command: ControlWake}).Ok,
+		"cancel must fail without sleep timer")
+
+	response := controlServer.handleRequest(&ControlRequest{Command: ControlSleep, Minutes: 20})
+	th.Require(response.Ok && response.Status.SleepSeconds == 20*60, "sleep must set timer")
+	response = controlServer.handleRequest(&ControlRequest{Command: ControlWake})
+	th.Require(response.Ok && response.Status.SleepSeconds == 0, "cancel must clear timer")
+}
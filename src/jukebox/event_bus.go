@@ -0,0 +1,131 @@
+package jukebox
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	EventSongStarted      = "song-started"
+	EventSongFinished     = "song-finished"
+	EventPaused           = "paused"
+	EventResumed          = "resumed"
+	EventDownloadStarted  = "download-started"
+	EventDownloadComplete = "download-complete"
+	EventIntegrityFailed  = "integrity-failed"
+	EventQueueChanged     = "queue-changed"
+
+	defaultEventBufferSize = 64
+)
+
+// JukeboxEvent is something that happened while playing.
+type JukeboxEvent struct {
+	Type            string    `json:"type"`
+	Time            time.Time `json:"time"`
+	Song            *SongInfo `json:"song,omitempty"`
+	PositionSeconds int       `json:"position-seconds,omitempty"`
+	Message         string    `json:"message,omitempty"`
+}
+
+func NewJukeboxEvent(eventType string, song *SongMetadata) *JukeboxEvent {
+	var event JukeboxEvent
+	event.Type = eventType
+	event.Time = time.Now()
+	if song != nil {
+		event.Song = NewSongInfo(song)
+	}
+	return &event
+}
+
+// EventSubscription receives the events published after it was created.
+type EventSubscription struct {
+	bus    *EventBus
+	events chan *JukeboxEvent
+}
+
+// Events returns the channel of events. it's closed when the
+// subscription ends.
+func (subscription *EventSubscription) Events() <-chan *JukeboxEvent {
+	return subscription.events
+}
+
+// Unsubscribe ends the subscription.
+func (subscription *EventSubscription) Unsubscribe() {
+	subscription.bus.unsubscribe(subscription)
+}
+
+// EventBus delivers jukebox events to any number of subscribers. the
+// jukebox never waits on a subscriber: a subscriber that falls more than
+// bufferSize events behind misses events rather than holding up play.
+type EventBus struct {
+	mutex         sync.Mutex
+	bufferSize    int
+	subscriptions map[*EventSubscription]bool
+	closed        bool
+}
+
+func NewEventBus(bufferSize int) *EventBus {
+	var bus EventBus
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	bus.bufferSize = bufferSize
+	bus.subscriptions = make(map[*EventSubscription]bool)
+	bus.closed = false
+	return &bus
+}
+
+func (bus *EventBus) Subscribe() *EventSubscription {
+	var subscription EventSubscription
+	subscription.bus = bus
+	subscription.events = make(chan *JukeboxEvent, bus.bufferSize)
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	if bus.closed {
+		close(subscription.events)
+	} else {
+		bus.subscriptions[&subscription] = true
+	}
+	return &subscription
+}
+
+func (bus *EventBus) unsubscribe(subscription *EventSubscription) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	if bus.subscriptions[subscription] {
+		delete(bus.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// SubscriberCount returns the number of current subscriptions.
+func (bus *EventBus) SubscriberCount() int {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	return len(bus.subscriptions)
+}
+
+// Publish sends the event to every subscriber that has room for it.
+func (bus *EventBus) Publish(event *JukeboxEvent) {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	for subscription := range bus.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			// subscriber isn't keeping up
+		}
+	}
+}
+
+// Close ends all subscriptions. nothing can subscribe after that.
+func (bus *EventBus) Close() {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+	bus.closed = true
+	for subscription := range bus.subscriptions {
+		close(subscription.events)
+	}
+	bus.subscriptions = make(map[*EventSubscription]bool)
+}
@@ -0,0 +1,50 @@
+package jukebox
+
+import (
+	"testing"
+)
+
+func TestEventBus(t *testing.T) {
+	th := NewTestHelper(t)
+	bus := NewEventBus(2)
+	first := bus.Subscribe()
+	second := bus.Subscribe()
+	th.Require(bus.SubscriberCount() == 2, "bus must have 2 subscribers")
+
+	bus.Publish(NewJukeboxEvent(EventPaused, nil))
+	for _, subscription := range []*EventSubscription{first, second} {
+		event := <-subscription.Events()
+		th.RequireStringEquals(event.Type, EventPaused, "every subscriber must receive event")
+	}
+
+	// a subscriber that doesn't keep up misses events instead of
+	// holding up the publisher
+	for i := 0; i < 5; i++ {
+		bus.Publish(NewJukeboxEvent(EventResumed, nil))
+	}
+	th.Require(len(first.Events()) == 2, "subscriber must only buffer bufferSize events")
+
+	first.Unsubscribe()
+	th.Require(bus.SubscriberCount() == 1, "unsubscribed subscriber must be removed")
+	for range first.Events() {
+	}
+	first.Unsubscribe()
+
+	bus.Close()
+	for range second.Events() {
+	}
+	_, ok := <-bus.Subscribe().Events()
+	th.RequireFalse(ok, "subscribing to closed bus must return closed channel")
+}
+
+func TestNewJukeboxEvent(t *testing.T) {
+	th := NewTestHelper(t)
+	song := NewSongMetadata()
+	song.Fm = NewFileMetadata()
+	song.Fm.FileUid = "The-Who--Whos-Next--My-Wife.mp3"
+
+	event := NewJukeboxEvent(EventSongStarted, song)
+	th.RequireStringEquals(event.Type, EventSongStarted, "event must have type")
+	th.Require(event.Song != nil && event.Song.Uid == song.Fm.FileUid, "event must have song")
+	th.Require(NewJukeboxEvent(EventQueueChanged, nil).Song == nil, "event without song must not have one")
+}
@@ -0,0 +1,155 @@
+package jukebox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+const (
+	flacBlockHeaderSize  = 4
+	flacLastBlockFlag    = 0x80
+	flacBlockTypeMask    = 0x7f
+	flacStreamInfoTag    = 0
+	flacVorbisCommentTag = 4
+	flacStreamInfoSize   = 34
+)
+
+// flacCommentFields are the fields that Vorbis comments are for. comment
+// names aren't case sensitive.
+var flacCommentFields = map[string]string{
+	"ARTIST":       "artist",
+	"ALBUMARTIST":  "album-artist",
+	"ALBUM ARTIST": "album-artist",
+	"ALBUM":        "album",
+	"TITLE":        "title",
+	"TRACKNUMBER":  "track",
+	"DISCNUMBER":   "disc",
+	"DATE":         "year",
+	"YEAR":         "year",
+	"GENRE":        "genre",
+}
+
+// flacBlock is where a metadata block's data is in the file.
+type flacBlock struct {
+	blockType byte
+	offset    int64
+	size      int64
+}
+
+// readFlacBlocks returns the metadata blocks that follow the "fLaC" marker
+// and the offset of the audio frames that follow them. returns nil if the
+// file isn't a flac file.
+func readFlacBlocks(file io.ReaderAt) ([]flacBlock, int64) {
+	// some programs put an ID3v2 tag in front of the flac data
+	offset := id3v2TagSize(file)
+	marker := make([]byte, 4)
+	if _, err := file.ReadAt(marker, offset); err != nil || !bytes.Equal(marker, []byte("fLaC")) {
+		return nil, 0
+	}
+	offset += 4
+
+	blocks := []flacBlock{}
+	header := make([]byte, flacBlockHeaderSize)
+	for {
+		if _, err := file.ReadAt(header, offset); err != nil {
+			return blocks, offset
+		}
+		var block flacBlock
+		block.blockType = header[0] & flacBlockTypeMask
+		block.offset = offset + flacBlockHeaderSize
+		block.size = int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		blocks = append(blocks, block)
+		offset = block.offset + block.size
+		if header[0]&flacLastBlockFlag != 0 {
+			return blocks, offset
+		}
+	}
+}
+
+// readFlacTags reads the Vorbis comments from the metadata blocks.
+func readFlacTags(file io.ReaderAt) *AudioTags {
+	blocks, _ := readFlacBlocks(file)
+	if blocks == nil {
+		return nil
+	}
+	tags := NewAudioTags()
+	for _, block := range blocks {
+		if block.blockType == flacVorbisCommentTag {
+			data := make([]byte, block.size)
+			if _, err := file.ReadAt(data, block.offset); err != nil {
+				return tags
+			}
+			readVorbisComments(data, tags)
+		}
+	}
+	return tags
+}
+
+// readFlacProperties reads the sample rate, channels and number of
+// samples from the STREAMINFO block, which is always the first block.
+func readFlacProperties(file io.ReaderAt, fileSize int64) *AudioProperties {
+	blocks, audioOffset := readFlacBlocks(file)
+	if len(blocks) == 0 || blocks[0].blockType != flacStreamInfoTag || blocks[0].size < flacStreamInfoSize {
+		return nil
+	}
+	streamInfo := make([]byte, flacStreamInfoSize)
+	if _, err := file.ReadAt(streamInfo, blocks[0].offset); err != nil {
+		return nil
+	}
+
+	// after the block and frame sizes: 20 bits of sample rate, 3 bits of
+	// channels - 1, 5 bits of bits per sample - 1, and 36 bits of samples
+	properties := NewAudioProperties()
+	properties.SampleRate = int(streamInfo[10])<<12 | int(streamInfo[11])<<4 | int(streamInfo[12])>>4
+	properties.Channels = int(streamInfo[12]>>1)&0x07 + 1
+	totalSamples := int64(streamInfo[13]&0x0f)<<32 | int64(binary.BigEndian.Uint32(streamInfo[14:18]))
+	if properties.SampleRate == 0 {
+		return nil
+	}
+	properties.DurationMs = totalSamples * 1000 / int64(properties.SampleRate)
+	properties.setBitrate(fileSize - audioOffset)
+	return properties
+}
+
+// readVorbisComments reads a comment block, which is the vendor string
+// followed by "NAME=value" comments. the lengths are little endian.
+func readVorbisComments(block []byte, tags *AudioTags) {
+	nextLength := func() (int, bool) {
+		if len(block) < 4 {
+			return 0, false
+		}
+		length := int(binary.LittleEndian.Uint32(block[0:4]))
+		block = block[4:]
+		return length, length >= 0 && length <= len(block)
+	}
+
+	vendorLength, ok := nextLength()
+	if !ok {
+		return
+	}
+	block = block[vendorLength:]
+	if len(block) < 4 {
+		return
+	}
+	numberComments := int(binary.LittleEndian.Uint32(block[0:4]))
+	block = block[4:]
+	for i := 0; i < numberComments; i++ {
+		commentLength, ok := nextLength()
+		if !ok {
+			return
+		}
+		comment := string(block[:commentLength])
+		block = block[commentLength:]
+
+		posEquals := strings.Index(comment, "=")
+		if posEquals < 0 {
+			continue
+		}
+		field, isKnown := flacCommentFields[strings.ToUpper(comment[:posEquals])]
+		if isKnown {
+			tags.setField(field, comment[posEquals+1:])
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package jukebox
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReadFlacTags(t *testing.T) {
+	th := NewTestHelper(t)
+	file, err := os.Open(tagFixturePath("vorbis.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tags := readFlacTags(file)
+	th.Require(tags != nil, "flac file must have tags")
+	if tags != nil {
+		th.RequireStringEquals(tags.Artist, "Miles Davis", "lowercase comment name")
+		th.RequireStringEquals(tags.Album, "Kind of Blue", "album")
+		th.RequireStringEquals(tags.Title, "So What", "mixed case comment name")
+		th.Require(tags.TrackNumber == 1, "track number")
+		th.Require(tags.DiscNumber == 1, "disc number")
+		th.Require(tags.Year == 1959, "year from date")
+		th.RequireStringEquals(tags.Genre, "Jazz", "genre")
+	}
+}
+
+func TestReadFlacTagsNotFlac(t *testing.T) {
+	th := NewTestHelper(t)
+	th.Require(readFlacTags(bytes.NewReader([]byte("not a flac file"))) == nil, "file without fLaC marker")
+
+	// a comment block whose length runs past the end of the file
+	truncated := append([]byte("fLaC"), 0x84, 0x00, 0x01, 0x00, 0x05, 0x00)
+	tags := readFlacTags(bytes.NewReader(truncated))
+	th.Require(tags != nil && tags.isEmpty(), "truncated comment block must be ignored")
+}
+
+func TestReadFlacProperties(t *testing.T) {
+	th := NewTestHelper(t)
+	contents, err := os.ReadFile(tagFixturePath("vorbis.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties := readFlacProperties(bytes.NewReader(contents), int64(len(contents)))
+	th.Require(properties != nil, "flac file must have properties")
+	if properties != nil {
+		th.Require(properties.DurationMs == 545500, "duration from number of samples")
+		th.Require(properties.SampleRate == 44100, "sample rate")
+		th.Require(properties.Channels == 2, "channels")
+	}
+
+	noStreamInfo := append([]byte("fLaC"), 0x84, 0x00, 0x00, 0x00)
+	th.Require(readFlacProperties(bytes.NewReader(noStreamInfo), int64(len(noStreamInfo))) == nil,
+		"flac file without STREAMINFO")
+}
@@ -1,8 +1,12 @@
 package jukebox
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 )
 
 type FSStorageSystem struct {
@@ -17,11 +21,34 @@ func NewFSStorageSystem(rootDir string, debugMode bool) *FSStorageSystem {
 	}
 }
 
-func (fs *FSStorageSystem) Enter() bool {
+// fsError maps file system errors onto the storage errors
+func fsError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	if os.IsPermission(err) {
+		return fmt.Errorf("%w: %v", ErrAuth, err)
+	}
+	return err
+}
+
+func (fs *FSStorageSystem) Enter(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if !DirectoryExists(fs.rootDir) {
 		CreateDirectory(fs.rootDir)
 	}
-	return DirectoryExists(fs.rootDir)
+	if !DirectoryExists(fs.rootDir) {
+		return fmt.Errorf("unable to create root directory '%s'", fs.rootDir)
+	}
+	return nil
 }
 
 func (fs *FSStorageSystem) Exit() {
@@ -31,207 +58,362 @@ func (fs *FSStorageSystem) ListAccountContainers() ([]string, error) {
 	return ListDirsInDirectory(fs.rootDir)
 }
 
-func (fs *FSStorageSystem) GetContainerNames() ([]string, error) {
-	return fs.ListAccountContainers()
+func (fs *FSStorageSystem) GetContainerNames(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	listContainers, err := fs.ListAccountContainers()
+	return listContainers, fsError(err)
 }
 
-func (fs *FSStorageSystem) HasContainer(containerName string) bool {
-	listContainers, err := fs.ListAccountContainers()
+func (fs *FSStorageSystem) HasContainer(ctx context.Context, containerName string) (bool, error) {
+	listContainers, err := fs.GetContainerNames(ctx)
 	if err != nil {
-		return false
+		return false, err
 	} else {
 		for _, container := range listContainers {
 			if containerName == container {
-				return true
+				return true, nil
 			}
 		}
-		return false
+		return false, nil
 	}
 }
 
-func (fs *FSStorageSystem) CreateContainer(containerName string) bool {
+func (fs *FSStorageSystem) containerDir(containerName string) (string, error) {
+	if len(containerName) == 0 {
+		return "", fmt.Errorf("%w: container name is missing", ErrInvalidArgument)
+	}
 	containerDir := PathJoin(fs.rootDir, containerName)
-	containerCreated := CreateDirectory(containerDir)
-	if containerCreated {
-		if fs.debugMode {
-			fmt.Printf("container created: '%s'\n", containerName)
-		}
+	if !DirectoryExists(containerDir) {
+		return "", fmt.Errorf("%w: '%s'", ErrContainerMissing, containerName)
 	}
-	return containerCreated
+	return containerDir, nil
 }
 
-func (fs *FSStorageSystem) DeleteContainer(containerName string) bool {
-	containerDir := PathJoin(fs.rootDir, containerName)
-	containerDeleted := DeleteDirectory(containerDir)
-	if containerDeleted {
-		if fs.debugMode {
-			fmt.Printf("container deleted: '%s'\n", containerName)
-		}
+func (fs *FSStorageSystem) objectPath(containerName string, objectName string) (string, error) {
+	containerDir, err := fs.containerDir(containerName)
+	if err != nil {
+		return "", err
+	}
+	if len(objectName) == 0 {
+		return "", fmt.Errorf("%w: object name is missing", ErrInvalidArgument)
 	}
-	return containerDeleted
+	return PathJoin(containerDir, objectName), nil
 }
 
-func (fs *FSStorageSystem) ListContainerContents(containerName string) ([]string, error) {
+func (fs *FSStorageSystem) CreateContainer(ctx context.Context, containerName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(containerName) == 0 {
+		return fmt.Errorf("%w: container name is missing", ErrInvalidArgument)
+	}
 	containerDir := PathJoin(fs.rootDir, containerName)
-	if DirectoryExists(containerDir) {
-		return ListFilesInDirectory(containerDir)
-	} else {
-		return nil, errors.New("container does not exist")
+	err := os.Mkdir(containerDir, 0755)
+	if err != nil {
+		return fsError(err)
 	}
+	if fs.debugMode {
+		fmt.Printf("container created: '%s'\n", containerName)
+	}
+	return nil
 }
 
-func (fs *FSStorageSystem) GetObjectMetadata(containerName string,
+func (fs *FSStorageSystem) DeleteContainer(ctx context.Context, containerName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	containerDir, err := fs.containerDir(containerName)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(containerDir)
+	if err != nil {
+		return fsError(err)
+	}
+	if fs.debugMode {
+		fmt.Printf("container deleted: '%s'\n", containerName)
+	}
+	return nil
+}
+
+func (fs *FSStorageSystem) ListContainerContents(ctx context.Context, containerName string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	containerDir, err := fs.containerDir(containerName)
+	if err != nil {
+		return nil, err
+	}
+	listFiles, err := ListFilesInDirectory(containerDir)
+	return listFiles, fsError(err)
+}
+
+func (fs *FSStorageSystem) GetObjectMetadata(ctx context.Context,
+	containerName string,
 	objectName string,
-	dictProps *PropertySet) bool {
-	if len(containerName) > 0 && len(objectName) > 0 {
-		containerDir := PathJoin(fs.rootDir, containerName)
-		if DirectoryExists(containerDir) {
-			objectPath := PathJoin(containerDir, objectName)
-			metaPath := objectPath + ".meta"
-			if FileExists(metaPath) {
-				return dictProps.ReadFromFile(metaPath)
-			}
-		}
+	dictProps *PropertySet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectPath, err := fs.objectPath(containerName, objectName)
+	if err != nil {
+		return err
+	}
+	metaPath := objectPath + ".meta"
+	if !FileExists(metaPath) {
+		return fmt.Errorf("%w: no metadata for %s/%s", ErrNotFound, containerName, objectName)
 	}
-	return false
+	if !dictProps.ReadFromFile(metaPath) {
+		return fmt.Errorf("unable to read metadata for %s/%s", containerName, objectName)
+	}
+	return nil
 }
 
-func (fs *FSStorageSystem) PutObject(containerName string,
+func (fs *FSStorageSystem) PutObject(ctx context.Context,
+	containerName string,
 	objectName string,
 	fileContents []byte,
-	headers *PropertySet) bool {
-	objectAdded := false
-	if len(containerName) > 0 && len(objectName) > 0 && len(fileContents) > 0 {
-		containerDir := PathJoin(fs.rootDir, containerName)
-		if DirectoryExists(containerDir) {
-			objectPath := PathJoin(containerDir, objectName)
-			objectAdded = FileWriteAllBytes(objectPath, fileContents)
-			if objectAdded {
-				if fs.debugMode {
-					fmt.Printf("object added: %s/%s\n", containerName, objectName)
-				}
-				if headers != nil {
-					if headers.Count() > 0 {
-						metaPath := objectPath + ".meta"
-						headers.WriteToFile(metaPath)
-					}
-				}
-			} else {
-				fmt.Println("FileWriteAllBytes failed to write object contents, put failed")
-			}
-		} else {
-			if fs.debugMode {
-				fmt.Println("container doesn't exist, can't put object")
-			}
+	headers *PropertySet) error {
+	return fs.PutObjectFromReader(ctx,
+		containerName,
+		objectName,
+		bytes.NewReader(fileContents),
+		int64(len(fileContents)),
+		headers)
+}
+
+func (fs *FSStorageSystem) PutObjectFromReader(ctx context.Context,
+	containerName string,
+	objectName string,
+	reader io.Reader,
+	contentLength int64,
+	headers *PropertySet) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectPath, err := fs.objectPath(containerName, objectName)
+	if err != nil {
+		if fs.debugMode {
+			fmt.Printf("can't put object: %v\n", err)
 		}
-	} else {
+		return err
+	}
+	if reader == nil || contentLength == 0 {
 		if fs.debugMode {
-			if len(containerName) == 0 {
-				fmt.Println("container name is missing, can't put object")
-			} else {
-				if len(objectName) == 0 {
-					fmt.Println("object name is missing, can't put object")
-				} else {
-					if len(fileContents) == 0 {
-						fmt.Println("object content is empty, can't put object")
-					}
-				}
-			}
+			fmt.Println("object content is empty, can't put object")
 		}
+		return fmt.Errorf("%w: object content is empty", ErrInvalidArgument)
 	}
-	return objectAdded
-}
-
-func (fs *FSStorageSystem) DeleteObject(containerName string,
-	objectName string) bool {
-	objectDeleted := false
-	if len(containerName) > 0 && len(objectName) > 0 {
-		containerDir := PathJoin(fs.rootDir, containerName)
-		objectPath := PathJoin(containerDir, objectName)
-		if FileExists(objectPath) {
-			objectDeleted = DeleteFile(objectPath)
-			if objectDeleted {
-				if fs.debugMode {
-					fmt.Printf("object deleted: %s/%s\n", containerName, objectName)
-				}
-				metaPath := objectPath + ".meta"
-				if FileExists(metaPath) {
-					DeleteFile(metaPath)
-				}
-			} else {
-				if fs.debugMode {
-					fmt.Println("delete of object file failed")
-				}
-			}
-		} else {
-			if fs.debugMode {
-				fmt.Println("cannot delete object, path doesn't exist")
-			}
+
+	err = fs.writeObjectFile(objectPath, newContextReader(ctx, reader), contentLength)
+	if err != nil {
+		fmt.Printf("unable to write object contents, put failed: %v\n", err)
+		return err
+	}
+
+	if fs.debugMode {
+		fmt.Printf("object added: %s/%s\n", containerName, objectName)
+	}
+	if headers != nil {
+		if headers.Count() > 0 {
+			metaPath := objectPath + ".meta"
+			headers.WriteToFile(metaPath)
 		}
+	}
+	return nil
+}
+
+func (fs *FSStorageSystem) writeObjectFile(objectPath string,
+	reader io.Reader,
+	contentLength int64) error {
+	f, err := os.Create(objectPath)
+	if err != nil {
+		return fsError(err)
+	}
+
+	var bytesWritten int64
+	if contentLength > 0 {
+		bytesWritten, err = io.CopyN(f, reader, contentLength)
 	} else {
+		bytesWritten, err = io.Copy(f, reader)
+	}
+	errClose := f.Close()
+	if err == nil {
+		err = errClose
+	}
+	if err == nil && bytesWritten == 0 {
+		err = fmt.Errorf("%w: object content is empty", ErrInvalidArgument)
+	}
+
+	if err != nil {
+		// don't leave a partial object behind
+		DeleteFile(objectPath)
+		return fsError(err)
+	}
+	return nil
+}
+
+func (fs *FSStorageSystem) DeleteObject(ctx context.Context,
+	containerName string,
+	objectName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	objectPath, err := fs.objectPath(containerName, objectName)
+	if err != nil {
+		if fs.debugMode {
+			fmt.Printf("cannot delete object: %v\n", err)
+		}
+		return err
+	}
+
+	err = os.Remove(objectPath)
+	if err != nil {
 		if fs.debugMode {
-			fmt.Println("cannot delete object, container name or object name is missing")
+			fmt.Printf("delete of object file failed: %v\n", err)
 		}
+		return fsError(err)
+	}
+
+	if fs.debugMode {
+		fmt.Printf("object deleted: %s/%s\n", containerName, objectName)
 	}
-	return objectDeleted
+	metaPath := objectPath + ".meta"
+	if FileExists(metaPath) {
+		DeleteFile(metaPath)
+	}
+	return nil
 }
 
-func (fs *FSStorageSystem) GetObject(containerName string,
+func (fs *FSStorageSystem) GetObject(ctx context.Context,
+	containerName string,
 	objectName string,
-	localFilePath string) int64 {
-	var bytesRetrieved int64
-	if len(containerName) > 0 &&
-		len(objectName) > 0 &&
-		len(localFilePath) > 0 {
-
-		containerDir := PathJoin(fs.rootDir, containerName)
-		objectPath := PathJoin(containerDir, objectName)
-		if FileExists(objectPath) {
-			objFileContents, err := FileReadAllBytes(objectPath)
-			if err == nil {
-				if fs.debugMode {
-					fmt.Printf("attempting to write object to '%s'\n", localFilePath)
-				}
-				if FileWriteAllBytes(localFilePath, objFileContents) {
-					bytesRetrieved = int64(len(objFileContents))
-				}
-			} else {
-				fmt.Printf("error: unable to read object file '%s'\n", objectPath)
-				fmt.Printf("error: %v\n", err)
-			}
-		}
+	localFilePath string) (int64, error) {
+	if len(localFilePath) == 0 {
+		return 0, fmt.Errorf("%w: local file path is missing", ErrInvalidArgument)
+	}
+	objectPath, err := fs.objectPath(containerName, objectName)
+	if err != nil {
+		return 0, err
+	}
+	if !FileExists(objectPath) {
+		return 0, fmt.Errorf("%w: %s/%s", ErrNotFound, containerName, objectName)
+	}
+
+	if fs.debugMode {
+		fmt.Printf("attempting to write object to '%s'\n", localFilePath)
+	}
+	localFile, err := os.Create(localFilePath)
+	if err != nil {
+		return 0, fsError(err)
+	}
+	bytesRetrieved, err := fs.GetObjectToWriter(ctx, containerName, objectName, localFile)
+	errClose := localFile.Close()
+	if err == nil {
+		err = errClose
 	}
-	return bytesRetrieved
+	if err != nil {
+		return 0, fsError(err)
+	}
+	return bytesRetrieved, nil
+}
+
+func (fs *FSStorageSystem) GetObjectToWriter(ctx context.Context,
+	containerName string,
+	objectName string,
+	writer io.Writer) (int64, error) {
+	return fs.GetObjectRange(ctx, containerName, objectName, 0, 0, writer)
 }
 
-func (fs *FSStorageSystem) RetrieveFile(fm *FileMetadata, localDirectory string) int64 {
-	if len(localDirectory) > 0 {
-		filePath := PathJoin(localDirectory, fm.FileUid)
+func (fs *FSStorageSystem) GetObjectRange(ctx context.Context,
+	containerName string,
+	objectName string,
+	offset int64,
+	length int64,
+	writer io.Writer) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if writer == nil {
+		return 0, fmt.Errorf("%w: writer is missing", ErrInvalidArgument)
+	}
+	objectPath, err := fs.objectPath(containerName, objectName)
+	if err != nil {
+		return 0, err
+	}
+
+	objFile, err := os.Open(objectPath)
+	if err != nil {
 		if fs.debugMode {
-			fmt.Printf("retrieving container=%s\n", fm.ContainerName)
-			fmt.Printf("retrieving object=%s\n", fm.ObjectName)
+			fmt.Printf("error: unable to open object file '%s'\n", objectPath)
+			fmt.Printf("error: %v\n", err)
 		}
-		return fs.GetObject(fm.ContainerName, fm.ObjectName, filePath)
+		return 0, fsError(err)
 	}
-	return 0
+	defer objFile.Close()
+
+	if offset != 0 {
+		objSize := GetFileSize(objectPath)
+		if offset < 0 || offset >= objSize {
+			return 0, fmt.Errorf("%w: offset %d outside of object size %d",
+				ErrInvalidArgument,
+				offset,
+				objSize)
+		}
+		_, err = objFile.Seek(offset, io.SeekStart)
+		if err != nil {
+			return 0, fsError(err)
+		}
+	}
+
+	var reader io.Reader = objFile
+	if length > 0 {
+		reader = io.LimitReader(objFile, length)
+	}
+
+	bytesRetrieved, err := io.Copy(writer, newContextReader(ctx, reader))
+	if err != nil {
+		fmt.Printf("error: unable to read object file '%s'\n", objectPath)
+		fmt.Printf("error: %v\n", err)
+		return bytesRetrieved, fsError(err)
+	}
+	return bytesRetrieved, nil
 }
 
-func (fs *FSStorageSystem) StoreFile(fm *FileMetadata, fileContents []byte) bool {
-	return fs.PutObject(fm.ContainerName,
+func (fs *FSStorageSystem) RetrieveFile(ctx context.Context, fm *FileMetadata, localDirectory string) (int64, error) {
+	if len(localDirectory) == 0 {
+		return 0, fmt.Errorf("%w: local directory is missing", ErrInvalidArgument)
+	}
+	filePath := PathJoin(localDirectory, fm.FileUid)
+	if fs.debugMode {
+		fmt.Printf("retrieving container=%s\n", fm.ContainerName)
+		fmt.Printf("retrieving object=%s\n", fm.ObjectName)
+	}
+	return fs.GetObject(ctx, fm.ContainerName, fm.ObjectName, filePath)
+}
+
+func (fs *FSStorageSystem) StoreFile(ctx context.Context, fm *FileMetadata, fileContents []byte) error {
+	return fs.PutObject(ctx,
+		fm.ContainerName,
 		fm.ObjectName,
 		fileContents,
 		fm.ToPropertySet())
 }
 
-func (fs *FSStorageSystem) AddFileFromPath(containerName string, objectName string, filePath string) bool {
-	fileContents, err := FileReadAllBytes(filePath)
+func (fs *FSStorageSystem) AddFileFromPath(ctx context.Context,
+	containerName string,
+	objectName string,
+	filePath string) error {
+	file, err := os.Open(filePath)
 	if err != nil {
 		if fs.debugMode {
 			fmt.Printf("error: unable to read file %s\n", filePath)
 		}
-
-		return false
+		return fsError(err)
 	} else {
-		return fs.PutObject(containerName, objectName, fileContents, nil)
+		defer file.Close()
+		return fs.PutObjectFromReader(ctx, containerName, objectName, file, GetFileSize(filePath), nil)
 	}
 }
@@ -1,6 +1,10 @@
 package jukebox
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -35,10 +39,111 @@ func TestGetObjectMetadata(t *testing.T) {
 }
 
 func TestPutObject(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fs := NewFSStorageSystem(t.TempDir(), false)
+	th.Require(fs.Enter(ctx) == nil, "Enter must succeed")
+	err := fs.PutObject(ctx, "songs", "a.mp3", []byte("abc"), nil)
+	th.Require(errors.Is(err, ErrContainerMissing), "PutObject into missing container must fail")
+	th.Require(fs.CreateContainer(ctx, "songs") == nil, "CreateContainer must succeed")
+	err = fs.PutObject(ctx, "songs", "a.mp3", []byte{}, nil)
+	th.Require(errors.Is(err, ErrInvalidArgument), "PutObject of empty content must fail")
+	th.Require(fs.PutObject(ctx, "songs", "a.mp3", []byte("abc"), nil) == nil, "PutObject must succeed")
+}
+
+func TestPutObjectFromReader(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fs := NewFSStorageSystem(t.TempDir(), false)
+	fs.Enter(ctx)
+	fs.CreateContainer(ctx, "songs")
+
+	contents := strings.Repeat("0123456789", 1000)
+	err := fs.PutObjectFromReader(ctx, "songs", "a.flac", strings.NewReader(contents), int64(len(contents)), nil)
+	th.Require(err == nil, "PutObjectFromReader with known length must succeed")
+	err = fs.PutObjectFromReader(ctx, "songs", "b.flac", strings.NewReader(contents), -1, nil)
+	th.Require(err == nil, "PutObjectFromReader with unknown length must succeed")
+	err = fs.PutObjectFromReader(ctx, "songs", "c.flac", strings.NewReader("short"), 100, nil)
+	th.Require(err != nil, "PutObjectFromReader must fail when reader is shorter than length")
+
+	contentList, _ := fs.ListContainerContents(ctx, "songs")
+	th.Require(len(contentList) == 2, "failed put must not leave a partial object")
+
+	var buffer bytes.Buffer
+	bytesRetrieved, err := fs.GetObjectToWriter(ctx, "songs", "a.flac", &buffer)
+	th.Require(err == nil && bytesRetrieved == int64(len(contents)),
+		"GetObjectToWriter must return number of bytes written")
+	th.RequireStringEquals(buffer.String(), contents, "retrieved content must match stored content")
 }
 
 func TestDeleteObject(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fs := NewFSStorageSystem(t.TempDir(), false)
+	fs.Enter(ctx)
+	fs.CreateContainer(ctx, "songs")
+	fs.PutObject(ctx, "songs", "a.mp3", []byte("abc"), nil)
+
+	th.Require(fs.DeleteObject(ctx, "songs", "a.mp3") == nil, "DeleteObject must succeed")
+	err := fs.DeleteObject(ctx, "songs", "a.mp3")
+	th.Require(errors.Is(err, ErrNotFound), "DeleteObject of missing object must report not found")
+	err = fs.DeleteObject(ctx, "albums", "a.mp3")
+	th.Require(errors.Is(err, ErrContainerMissing), "DeleteObject in missing container must report it")
 }
 
 func TestGetObject(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fs := NewFSStorageSystem(t.TempDir(), false)
+	fs.Enter(ctx)
+	fs.CreateContainer(ctx, "songs")
+	fs.PutObject(ctx, "songs", "a.mp3", []byte("song-binary"), nil)
+
+	localFile := PathJoin(t.TempDir(), "a.mp3")
+	bytesRetrieved, err := fs.GetObject(ctx, "songs", "a.mp3", localFile)
+	th.Require(err == nil && bytesRetrieved == 11, "GetObject must retrieve all bytes")
+	text, _ := FileReadAllText(localFile)
+	th.RequireStringEquals(text, "song-binary", "retrieved content must match stored content")
+	_, err = fs.GetObject(ctx, "songs", "missing.mp3", localFile)
+	th.Require(errors.Is(err, ErrNotFound), "GetObject of missing object must report not found")
+}
+
+func TestGetObjectToWriter(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fs := NewFSStorageSystem(t.TempDir(), false)
+	fs.Enter(ctx)
+	fs.CreateContainer(ctx, "songs")
+	fs.PutObject(ctx, "songs", "a.mp3", []byte("song-binary"), nil)
+
+	var buffer bytes.Buffer
+	_, err := fs.GetObjectToWriter(ctx, "songs", "missing.mp3", &buffer)
+	th.Require(errors.Is(err, ErrNotFound), "GetObjectToWriter of missing object must report not found")
+
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = fs.GetObjectToWriter(cancelledCtx, "songs", "a.mp3", &buffer)
+	th.Require(errors.Is(err, context.Canceled), "GetObjectToWriter must stop when context is cancelled")
+}
+
+func TestGetObjectRange(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fs := NewFSStorageSystem(t.TempDir(), false)
+	fs.Enter(ctx)
+	fs.CreateContainer(ctx, "songs")
+	fs.PutObject(ctx, "songs", "a.flac", []byte("0123456789"), nil)
+
+	var buffer bytes.Buffer
+	bytesRetrieved, err := fs.GetObjectRange(ctx, "songs", "a.flac", 4, 0, &buffer)
+	th.Require(err == nil && bytesRetrieved == 6, "GetObjectRange must retrieve rest of object")
+	th.RequireStringEquals(buffer.String(), "456789", "GetObjectRange must start at offset")
+
+	buffer.Reset()
+	bytesRetrieved, err = fs.GetObjectRange(ctx, "songs", "a.flac", 2, 3, &buffer)
+	th.Require(err == nil && bytesRetrieved == 3, "GetObjectRange must honor length")
+	th.RequireStringEquals(buffer.String(), "234", "GetObjectRange must retrieve requested range")
+
+	_, err = fs.GetObjectRange(ctx, "songs", "a.flac", 10, 0, &buffer)
+	th.Require(errors.Is(err, ErrInvalidArgument), "offset past end of object must be rejected")
 }
@@ -3,7 +3,8 @@ module swampbits.com/jukebox
 go 1.18
 
 require (
-	github.com/aws/aws-sdk-go v1.44.111 // indirect
-	github.com/jmespath/go-jmespath v0.4.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.15 // indirect
+	github.com/aws/aws-sdk-go v1.44.111
+	github.com/mattn/go-sqlite3 v1.14.15
 )
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
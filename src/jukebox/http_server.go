@@ -1,54 +1,462 @@
 package jukebox
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 )
 
+const (
+	defaultHttpAddress    = ":5309"
+	httpShutdownTimeout   = 5 * time.Second
+	apiPrefix             = "/api/v1"
+	libraryListArtists    = "artists"
+	libraryListAlbums     = "albums"
+	libraryListSongs      = "songs"
+	defaultLibraryLimit   = 50
+	maxLibraryLimit       = 500
+	httpReadHeaderTimeout = 10 * time.Second
+	sseKeepAliveInterval  = 15 * time.Second
+)
+
+// HttpServer serves the JSON control API of a playing jukebox.
 type HttpServer struct {
-	jukebox *Jukebox
-	port    int
+	jukebox      *Jukebox
+	address      string
+	server       *http.Server
+	listener     net.Listener
+	shutdown     chan bool
+	shutdownOnce sync.Once
+}
+
+// LibraryPage is one page of a library listing.
+type LibraryPage struct {
+	List   string      `json:"list"`
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+	Items  interface{} `json:"items"`
 }
 
-func NewHttpServer(jukebox *Jukebox, port int) *HttpServer {
+func NewHttpServer(jukebox *Jukebox, address string) *HttpServer {
 	var httpServer HttpServer
 	httpServer.jukebox = jukebox
-	httpServer.port = port
+	httpServer.address = address
+	httpServer.server = nil
+	httpServer.listener = nil
+	httpServer.shutdown = make(chan bool)
 	return &httpServer
 }
 
-func (httpServer *HttpServer) Run() bool {
-	http.HandleFunc("/songAdvance", func(w http.ResponseWriter, r *http.Request) {
-		if httpServer.jukebox != nil {
-			httpServer.jukebox.AdvanceToNextSong()
-			fmt.Fprintf(w, "<html><body>advanced to next song</body></html>")
+// Handler returns the handler for the API endpoints.
+func (httpServer *HttpServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix+"/status", httpServer.handleStatus)
+	mux.HandleFunc(apiPrefix+"/queue", httpServer.handleQueue)
+	mux.HandleFunc(apiPrefix+"/queue/append", httpServer.handleQueueAppend)
+	mux.HandleFunc(apiPrefix+"/queue/play-next", httpServer.handleQueuePlayNext)
+	mux.HandleFunc(apiPrefix+"/queue/remove", httpServer.handleQueueRemove)
+	mux.HandleFunc(apiPrefix+"/queue/move", httpServer.handleQueueMove)
+	mux.HandleFunc(apiPrefix+"/queue/clear", httpServer.handleQueueClear)
+	mux.HandleFunc(apiPrefix+"/events", httpServer.handleEvents)
+	mux.HandleFunc(apiPrefix+"/library", httpServer.handleLibrary)
+	mux.HandleFunc(apiPrefix+"/pause", httpServer.handlePause)
+	mux.HandleFunc(apiPrefix+"/resume", httpServer.handleResume)
+	mux.HandleFunc(apiPrefix+"/next", httpServer.handleNext)
+	mux.HandleFunc(apiPrefix+"/previous", httpServer.handlePrevious)
+	mux.HandleFunc(apiPrefix+"/stop", httpServer.handleStop)
+	mux.HandleFunc(apiPrefix+"/volume", httpServer.handleVolume)
+	mux.HandleFunc(apiPrefix+"/seek", httpServer.handleSeek)
+	mux.HandleFunc(apiPrefix+"/sleep", httpServer.handleSleep)
+	mux.HandleFunc(apiPrefix+"/sleep/cancel", httpServer.handleSleepCancel)
+	return mux
+}
+
+// Start listens on the server's address and serves requests in the
+// background. returns false if the address can't be listened on.
+func (httpServer *HttpServer) Start() bool {
+	listener, err := net.Listen("tcp", httpServer.address)
+	if err != nil {
+		fmt.Printf("error: unable to start http server on '%s'\n", httpServer.address)
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	httpServer.listener = listener
+	httpServer.server = &http.Server{
+		Handler:           httpServer.Handler(),
+		ReadHeaderTimeout: httpReadHeaderTimeout,
+	}
+	// event streams never finish on their own, so they have to be told
+	// to end for shutdown to be graceful
+	httpServer.server.RegisterOnShutdown(httpServer.endEventStreams)
+
+	go func() {
+		err := httpServer.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Println("error: http server failed")
+			fmt.Printf("error: %v\n", err)
 		}
+	}()
+
+	fmt.Printf("http server listening on %s\n", listener.Addr().String())
+	return true
+}
+
+// Address returns the address that the server is listening on.
+func (httpServer *HttpServer) Address() string {
+	if httpServer.listener != nil {
+		return httpServer.listener.Addr().String()
+	}
+	return httpServer.address
+}
+
+// Shutdown stops accepting requests and waits up to timeout for the
+// requests in progress to finish.
+func (httpServer *HttpServer) Shutdown(timeout time.Duration) bool {
+	if httpServer.server == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := httpServer.server.Shutdown(ctx)
+	if err != nil {
+		fmt.Println("error: unable to shut down http server")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	return true
+}
+
+func (httpServer *HttpServer) endEventStreams() {
+	httpServer.shutdownOnce.Do(func() {
+		close(httpServer.shutdown)
 	})
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeJsonError(w http.ResponseWriter, statusCode int, message string) {
+	writeJson(w, statusCode, map[string]string{"error": message})
+}
+
+// allowMethod reports whether the request uses the method. if not, the
+// request is answered with an error.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeJsonError(w, http.StatusMethodNotAllowed, "method must be "+method)
+		return false
+	}
+	return true
+}
+
+// intQueryValue returns the query parameter as an int, or defaultValue
+// when it isn't given.
+func intQueryValue(r *http.Request, name string, defaultValue int) (int, error) {
+	textValue := r.URL.Query().Get(name)
+	if len(textValue) == 0 {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(textValue)
+}
+
+func (httpServer *HttpServer) writeStatus(w http.ResponseWriter) {
+	writeJson(w, http.StatusOK, httpServer.jukebox.Status())
+}
+
+func (httpServer *HttpServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodGet) {
+		httpServer.writeStatus(w)
+	}
+}
+
+func (httpServer *HttpServer) writeQueue(w http.ResponseWriter) {
+	writeJson(w, http.StatusOK, httpServer.jukebox.UpcomingSongs())
+}
+
+func (httpServer *HttpServer) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodGet) {
+		httpServer.writeQueue(w)
+	}
+}
+
+func (httpServer *HttpServer) handleQueueAppend(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	songUid := r.URL.Query().Get("song")
+	if len(songUid) == 0 {
+		writeJsonError(w, http.StatusBadRequest, "song must be given")
+	} else if httpServer.jukebox.EnqueueSong(songUid) {
+		httpServer.writeQueue(w)
+	} else {
+		writeJsonError(w, http.StatusNotFound, fmt.Sprintf("unknown song '%s'", songUid))
+	}
+}
+
+func (httpServer *HttpServer) handleQueuePlayNext(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	songUid := r.URL.Query().Get("song")
+	if len(songUid) == 0 {
+		writeJsonError(w, http.StatusBadRequest, "song must be given")
+	} else if httpServer.jukebox.PlayNext(songUid) {
+		httpServer.writeQueue(w)
+	} else {
+		writeJsonError(w, http.StatusNotFound, fmt.Sprintf("unknown song '%s'", songUid))
+	}
+}
+
+func (httpServer *HttpServer) handleQueueRemove(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	position, err := strconv.Atoi(r.URL.Query().Get("position"))
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, "position must be given")
+	} else if httpServer.jukebox.RemoveFromQueue(position) {
+		httpServer.writeQueue(w)
+	} else {
+		writeJsonError(w, http.StatusNotFound, fmt.Sprintf("no song at position %d", position))
+	}
+}
+
+func (httpServer *HttpServer) handleQueueMove(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+	to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+	if errFrom != nil || errTo != nil {
+		writeJsonError(w, http.StatusBadRequest, "from and to must be given")
+	} else if httpServer.jukebox.MoveInQueue(from, to) {
+		httpServer.writeQueue(w)
+	} else {
+		writeJsonError(w, http.StatusNotFound, "from and to must be positions in the queue")
+	}
+}
+
+func (httpServer *HttpServer) handleQueueClear(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodPost) {
+		httpServer.jukebox.ClearQueue()
+		httpServer.writeQueue(w)
+	}
+}
+
+// handleEvents streams the jukebox events to the client as server-sent
+// events until the client goes away or the server shuts down.
+func (httpServer *HttpServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJsonError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	subscription := httpServer.jukebox.Events().Subscribe()
+	defer subscription.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// a comment lets the client know that it's subscribed
+	fmt.Fprint(w, ": subscribed\n\n")
+	flusher.Flush()
 
-	http.HandleFunc("/togglePausePlay", func(w http.ResponseWriter, r *http.Request) {
-		if httpServer.jukebox != nil {
-			httpServer.jukebox.TogglePausePlay()
-			fmt.Fprintf(w, "<html><body>toggled pause/play</body></html>")
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+			eventJson, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, eventJson)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-httpServer.shutdown:
+			return
 		}
-	})
+	}
+}
 
-	/*
-		http.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
-			httpServer.jukebox.TogglePausePlay()
-		})
+func (httpServer *HttpServer) handleLibrary(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	offset, errOffset := intQueryValue(r, "offset", 0)
+	limit, errLimit := intQueryValue(r, "limit", defaultLibraryLimit)
+	if errOffset != nil || offset < 0 {
+		writeJsonError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+		return
+	}
+	if errLimit != nil || limit < 1 || limit > maxLibraryLimit {
+		writeJsonError(w, http.StatusBadRequest,
+			fmt.Sprintf("limit must be an integer from 1 to %d", maxLibraryLimit))
+		return
+	}
+
+	query := r.URL.Query()
+	list := query.Get("list")
+	if len(list) == 0 {
+		list = libraryListArtists
+	}
+	artist := query.Get("artist")
+	album := query.Get("album")
+
+	var page LibraryPage
+	page.List = list
+	page.Offset = offset
+	page.Limit = limit
+
+	switch list {
+	case libraryListArtists:
+		artists := httpServer.jukebox.LibraryArtists()
+		start, end := pageBounds(len(artists), offset, limit)
+		page.Total = len(artists)
+		page.Items = artists[start:end]
+	case libraryListAlbums:
+		albums := httpServer.jukebox.LibraryAlbums(artist)
+		start, end := pageBounds(len(albums), offset, limit)
+		page.Total = len(albums)
+		page.Items = albums[start:end]
+	case libraryListSongs:
+		songs := httpServer.jukebox.LibrarySongs(artist, album)
+		start, end := pageBounds(len(songs), offset, limit)
+		page.Total = len(songs)
+		page.Items = songs[start:end]
+	default:
+		writeJsonError(w, http.StatusBadRequest,
+			fmt.Sprintf("list must be %s, %s or %s",
+				libraryListArtists, libraryListAlbums, libraryListSongs))
+		return
+	}
+
+	writeJson(w, http.StatusOK, page)
+}
 
-		http.HandleFunc("/api/memoryUsage", func(w http.ResponseWriter, r *http.Request) {
-			httpServer.jukebox.TogglePausePlay()
-		})
-	*/
+// pageBounds returns the slice bounds of a page of a listing.
+func pageBounds(total int, offset int, limit int) (int, int) {
+	start := offset
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
 
-	listenAddress := fmt.Sprintf(":%d", httpServer.port)
+func (httpServer *HttpServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodPost) {
+		httpServer.jukebox.Pause()
+		httpServer.writeStatus(w)
+	}
+}
 
-	err := http.ListenAndServe(listenAddress, nil)
+func (httpServer *HttpServer) handleResume(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodPost) {
+		httpServer.jukebox.Resume()
+		httpServer.writeStatus(w)
+	}
+}
+
+func (httpServer *HttpServer) handleNext(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodPost) {
+		httpServer.jukebox.AdvanceToNextSong()
+		httpServer.writeStatus(w)
+	}
+}
+
+func (httpServer *HttpServer) handlePrevious(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if !httpServer.jukebox.PreviousSong() {
+		writeJsonError(w, http.StatusConflict, "no previous song")
+		return
+	}
+	httpServer.writeStatus(w)
+}
+
+func (httpServer *HttpServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	if allowMethod(w, r, http.MethodPost) {
+		httpServer.jukebox.StopPlay()
+		httpServer.writeStatus(w)
+	}
+}
+
+func (httpServer *HttpServer) handleVolume(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	level, err := strconv.Atoi(r.URL.Query().Get("level"))
 	if err != nil {
-		return false
+		writeJsonError(w, http.StatusBadRequest, "level must be given as 0-100")
+	} else if httpServer.jukebox.SetVolume(level) {
+		httpServer.writeStatus(w)
 	} else {
-		fmt.Println("Http server listening...")
-		return true
+		writeJsonError(w, http.StatusConflict, "unable to set volume")
+	}
+}
+
+func (httpServer *HttpServer) handleSeek(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	seconds, err := strconv.Atoi(r.URL.Query().Get("seconds"))
+	if err != nil || seconds < 0 {
+		writeJsonError(w, http.StatusBadRequest, "seconds must be a non-negative integer")
+	} else if httpServer.jukebox.SeekTo(seconds) {
+		httpServer.writeStatus(w)
+	} else {
+		writeJsonError(w, http.StatusConflict, "unable to seek")
+	}
+}
+
+func (httpServer *HttpServer) handleSleep(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	minutes, err := strconv.Atoi(r.URL.Query().Get("minutes"))
+	if err != nil || !httpServer.jukebox.SetSleepTimer(minutes) {
+		writeJsonError(w, http.StatusBadRequest, "minutes must be a positive integer")
+	} else {
+		httpServer.writeStatus(w)
+	}
+}
+
+func (httpServer *HttpServer) handleSleepCancel(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if httpServer.jukebox.CancelSleepTimer() {
+		httpServer.writeStatus(w)
+	} else {
+		writeJsonError(w, http.StatusConflict, "no sleep timer is set")
 	}
 }
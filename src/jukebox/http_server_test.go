@@ -0,0 +1,308 @@
+package jukebox
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testApiSongs = map[string]string{
+	"The-Who--Whos-Next--My-Wife.mp3":              "my wife audio",
+	"The-Who--Whos-Next--Baba-ORiley.mp3":          "baba oriley audio",
+	"The-Kinks--Face-to-Face--Sunny-Afternoon.mp3": "sunny afternoon audio",
+}
+
+// newTestApiServer returns a jukebox with a few songs and a test server
+// for its API.
+func newTestApiServer(t *testing.T) (*Jukebox, *httptest.Server) {
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+	options.HttpAddress = ""
+	jukebox := newTestJukebox(t, options)
+	importTestSongs(t, jukebox, testApiSongs)
+
+	server := httptest.NewServer(NewHttpServer(jukebox, "").Handler())
+	t.Cleanup(server.Close)
+	return jukebox, server
+}
+
+func apiRequest(t *testing.T, server *httptest.Server, method string, path string, result interface{}) int {
+	request, err := http.NewRequest(method, server.URL+apiPrefix+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if result != nil && response.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+			t.Fatalf("unable to decode response of %s %s: %v", method, path, err)
+		}
+	}
+	return response.StatusCode
+}
+
+// waitForStatus polls the status until ready returns true.
+func waitForStatus(t *testing.T, server *httptest.Server, ready func(*PlayerStatus) bool) *PlayerStatus {
+	for i := 0; i < 500; i++ {
+		var status PlayerStatus
+		apiRequest(t, server, http.MethodGet, "/status", &status)
+		if ready(&status) {
+			return &status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("status never became ready")
+	return nil
+}
+
+func TestHttpServerLibrary(t *testing.T) {
+	th := NewTestHelper(t)
+	_, server := newTestApiServer(t)
+
+	var artists struct {
+		Total int              `json:"total"`
+		Items []*LibraryArtist `json:"items"`
+	}
+	th.Require(apiRequest(t, server, http.MethodGet, "/library", &artists) == http.StatusOK,
+		"artist listing must succeed")
+	th.Require(artists.Total == 2 && len(artists.Items) == 2, "library must have 2 artists")
+	th.RequireStringEquals(artists.Items[0].Artist, "The Kinks", "artists must be sorted")
+	th.Require(artists.Items[1].Songs == 2, "artist must have song count")
+
+	var albums struct {
+		Total int             `json:"total"`
+		Items []*LibraryAlbum `json:"items"`
+	}
+	th.Require(apiRequest(t, server, http.MethodGet, "/library?list=albums&artist=The+Who", &albums) == http.StatusOK,
+		"album listing must succeed")
+	th.Require(albums.Total == 1, "artist must have 1 album")
+	th.RequireStringEquals(albums.Items[0].Album, "Whos Next", "album must be named")
+
+	var songs struct {
+		Total  int         `json:"total"`
+		Offset int         `json:"offset"`
+		Items  []*SongInfo `json:"items"`
+	}
+	th.Require(apiRequest(t, server, http.MethodGet, "/library?list=songs&offset=1&limit=1", &songs) == http.StatusOK,
+		"song listing must succeed")
+	th.Require(songs.Total == 3 && songs.Offset == 1, "song page must report total and offset")
+	th.Require(len(songs.Items) == 1, "song page must be limited")
+	th.RequireStringEquals(songs.Items[0].Uid, "The-Who--Whos-Next--Baba-ORiley.mp3", "page must start at offset")
+
+	th.Require(apiRequest(t, server, http.MethodGet, "/library?list=songs&offset=10", &songs) == http.StatusOK,
+		"page past the end must succeed")
+	th.Require(len(songs.Items) == 0, "page past the end must be empty")
+
+	th.Require(apiRequest(t, server, http.MethodGet, "/library?list=genres", nil) == http.StatusBadRequest,
+		"unknown list must be rejected")
+	th.Require(apiRequest(t, server, http.MethodGet, "/library?limit=0", nil) == http.StatusBadRequest,
+		"invalid limit must be rejected")
+	th.Require(apiRequest(t, server, http.MethodPost, "/library", nil) == http.StatusMethodNotAllowed,
+		"library must only allow GET")
+}
+
+func TestHttpServerControl(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, server := newTestApiServer(t)
+	jukebox.songPlayLengthSeconds = 60
+
+	var status PlayerStatus
+	th.Require(apiRequest(t, server, http.MethodGet, "/status", &status) == http.StatusOK,
+		"status must succeed")
+	th.Require(status.Song == nil, "nothing must be playing yet")
+	th.Require(apiRequest(t, server, http.MethodPost, "/previous", nil) == http.StatusConflict,
+		"previous must fail when nothing is playing")
+
+	songList := jukebox.LibrarySongs("", "")
+	playDone := make(chan bool)
+	go func() {
+		jukebox.PlaySongs(false, "", "")
+		playDone <- true
+	}()
+
+	first := waitForStatus(t, server, func(s *PlayerStatus) bool { return s.Song != nil })
+	th.Require(first.Index == 0 && first.NumberSongs == len(songList), "first song must be playing")
+
+	var queue []*SongInfo
+	th.Require(apiRequest(t, server, http.MethodGet, "/queue", &queue) == http.StatusOK,
+		"queue must succeed")
+	th.Require(len(queue) == len(songList)-1, "queue must have the songs after the current one")
+
+	th.Require(apiRequest(t, server, http.MethodGet, "/next", nil) == http.StatusMethodNotAllowed,
+		"next must only allow POST")
+	th.Require(apiRequest(t, server, http.MethodPost, "/next", nil) == http.StatusOK, "next must succeed")
+	second := waitForStatus(t, server, func(s *PlayerStatus) bool { return s.Index == 1 })
+	th.RequireStringEquals(second.Song.Uid, queue[0].Uid, "next song must be first in queue")
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/previous", nil) == http.StatusOK,
+		"previous must succeed")
+	waitForStatus(t, server, func(s *PlayerStatus) bool { return s.Index == 0 })
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/pause", &status) == http.StatusOK, "pause must succeed")
+	th.Require(status.Paused, "pause must report paused")
+	th.Require(apiRequest(t, server, http.MethodPost, "/resume", &status) == http.StatusOK, "resume must succeed")
+	th.RequireFalse(status.Paused, "resume must report playing")
+
+	// moving to another song while paused resumes play
+	apiRequest(t, server, http.MethodPost, "/pause", nil)
+	th.Require(apiRequest(t, server, http.MethodPost, "/next", nil) == http.StatusOK, "next must succeed")
+	waitForStatus(t, server, func(s *PlayerStatus) bool { return s.Index == 1 && !s.Paused })
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/volume?level=50", nil) == http.StatusConflict,
+		"null player can't change volume")
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/stop", nil) == http.StatusOK, "stop must succeed")
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop must end play")
+	}
+}
+
+func TestHttpServerQueue(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, server := newTestApiServer(t)
+	jukebox.queue.SetRepeatMode(RepeatNone)
+	jukebox.queue.Reset(jukebox.jukeboxDb.retrieveSongs("", ""))
+
+	var queue []*SongInfo
+	th.Require(apiRequest(t, server, http.MethodGet, "/queue", &queue) == http.StatusOK,
+		"queue must succeed")
+	th.Require(len(queue) == 2, "queue must have the songs after the current one")
+	last := queue[1].Uid
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/move?from=1&to=0", &queue) == http.StatusOK,
+		"move must succeed")
+	th.RequireStringEquals(queue[0].Uid, last, "moved song must be first")
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/move?from=1&to=5", nil) == http.StatusNotFound,
+		"move past the end must fail")
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/remove?position=0", &queue) == http.StatusOK,
+		"remove must succeed")
+	th.Require(len(queue) == 1, "removed song must leave the queue")
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/remove", nil) == http.StatusBadRequest,
+		"remove without position must fail")
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/play-next?song="+last, &queue) == http.StatusOK,
+		"play-next must succeed")
+	th.Require(len(queue) == 2, "play-next must add song")
+	th.RequireStringEquals(queue[0].Uid, last, "play-next song must be first")
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/append?song="+last, &queue) == http.StatusOK,
+		"append must succeed")
+	th.Require(len(queue) == 3, "append must add song")
+	th.RequireStringEquals(queue[2].Uid, last, "appended song must be last")
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/append?song=missing.mp3", nil) == http.StatusNotFound,
+		"append of unknown song must fail")
+	th.Require(apiRequest(t, server, http.MethodGet, "/queue/append?song="+last, nil) == http.StatusMethodNotAllowed,
+		"append must only allow POST")
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/queue/clear", &queue) == http.StatusOK,
+		"clear must succeed")
+	th.Require(len(queue) == 0, "clear must empty queue")
+	th.Require(jukebox.currentSong() != nil, "clear must keep current song")
+}
+
+func TestHttpServerStartShutdown(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, _ := newTestApiServer(t)
+
+	httpServer := NewHttpServer(jukebox, "127.0.0.1:0")
+	th.Require(httpServer.Start(), "Start must succeed")
+	response, err := http.Get("http://" + httpServer.Address() + apiPrefix + "/status")
+	th.Require(err == nil && response.StatusCode == http.StatusOK, "server must answer requests")
+	th.Require(strings.HasPrefix(response.Header.Get("Content-Type"), "application/json"),
+		"response must be JSON")
+	response.Body.Close()
+
+	th.RequireFalse(NewHttpServer(jukebox, httpServer.Address()).Start(),
+		"Start must fail when address is in use")
+
+	th.Require(httpServer.Shutdown(time.Second), "Shutdown must succeed")
+	_, err = http.Get("http://" + httpServer.Address() + apiPrefix + "/status")
+	th.Require(err != nil, "server must not answer after shutdown")
+}
+
+// subscribeToEvents opens an event stream and waits until the server
+// has subscribed it.
+func subscribeToEvents(t *testing.T, url string) (*http.Response, *bufio.Reader) {
+	response, err := http.Get(url + apiPrefix + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(response.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != ": subscribed\n" {
+		t.Fatalf("event stream must start with subscribed comment: %q %v", line, err)
+	}
+	reader.ReadString('\n')
+	return response, reader
+}
+
+// readEvent returns the next event on the stream.
+func readEvent(t *testing.T, reader *bufio.Reader) (string, *JukeboxEvent) {
+	var eventType string
+	var event JukeboxEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unable to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			return eventType, &event
+		} else if strings.HasPrefix(line, "event: ") {
+			eventType = strings.TrimPrefix(line, "event: ")
+		} else if strings.HasPrefix(line, "data: ") {
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+		}
+	}
+}
+
+func TestHttpServerEvents(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, server := newTestApiServer(t)
+
+	firstResponse, firstReader := subscribeToEvents(t, server.URL)
+	defer firstResponse.Body.Close()
+	th.RequireStringEquals(firstResponse.Header.Get("Content-Type"), "text/event-stream",
+		"events must be sent as server-sent events")
+	secondResponse, secondReader := subscribeToEvents(t, server.URL)
+	defer secondResponse.Body.Close()
+	th.Require(jukebox.Events().SubscriberCount() == 2, "both clients must be subscribed")
+
+	jukebox.Pause()
+	for _, reader := range []*bufio.Reader{firstReader, secondReader} {
+		eventType, event := readEvent(t, reader)
+		th.RequireStringEquals(eventType, EventPaused, "every client must receive event")
+		th.RequireStringEquals(event.Type, EventPaused, "event data must be JSON event")
+	}
+
+	th.Require(apiRequest(t, server, http.MethodPost, "/events", nil) == http.StatusMethodNotAllowed,
+		"events must only allow GET")
+}
+
+func TestHttpServerShutdownEndsEvents(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, _ := newTestApiServer(t)
+
+	httpServer := NewHttpServer(jukebox, "127.0.0.1:0")
+	th.Require(httpServer.Start(), "Start must succeed")
+	response, reader := subscribeToEvents(t, "http://"+httpServer.Address())
+	defer response.Body.Close()
+
+	shutdownStart := time.Now()
+	th.Require(httpServer.Shutdown(5*time.Second), "Shutdown must succeed with open event stream")
+	th.Require(time.Since(shutdownStart) < 5*time.Second, "Shutdown must not wait for timeout")
+	_, err := io.ReadAll(reader)
+	th.Require(err == nil, "event stream must end cleanly")
+	th.Require(jukebox.Events().SubscriberCount() == 0, "client must be unsubscribed")
+}
@@ -0,0 +1,255 @@
+package jukebox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	id3v2HeaderSize = 10
+	id3v1TagSize    = 128
+
+	id3FlagUnsynchronisation = 0x80
+	id3FlagExtendedHeader    = 0x40
+
+	// ID3v2.4 frame format flags
+	id3FrameCompressed          = 0x08
+	id3FrameEncrypted           = 0x04
+	id3FrameUnsynchronised      = 0x02
+	id3FrameDataLengthIndicator = 0x01
+
+	// ID3v2.3 frame format flags
+	id3v23FrameCompressed = 0x80
+	id3v23FrameEncrypted  = 0x40
+)
+
+// id3FrameFields are the fields that ID3v2 text frames are for. ID3v2.2
+// has its own 3 character frame ids.
+var id3FrameFields = map[string]string{
+	"TPE1": "artist", "TP1": "artist",
+	"TPE2": "album-artist", "TP2": "album-artist",
+	"TALB": "album", "TAL": "album",
+	"TIT2": "title", "TT2": "title",
+	"TRCK": "track", "TRK": "track",
+	"TPOS": "disc", "TPA": "disc",
+	"TYER": "year", "TYE": "year", "TDRC": "year", "TORY": "year", "TDOR": "year",
+	"TCON": "genre", "TCO": "genre",
+}
+
+// readId3Tags reads the ID3v2 tag at the start of the file, filling in
+// anything that it doesn't have from the ID3v1 tag at the end of it.
+func readId3Tags(file io.ReaderAt, fileSize int64) *AudioTags {
+	tags := NewAudioTags()
+	readId3v2Tag(file, fileSize, tags)
+	readId3v1Tag(file, fileSize, tags)
+	return tags
+}
+
+// id3v2TagSize returns the size of the ID3v2 tag (including its header)
+// at the start of the file, or 0 if there isn't one.
+func id3v2TagSize(file io.ReaderAt) int64 {
+	header := make([]byte, id3v2HeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		return 0
+	}
+	return id3v2HeaderSize + int64(syncsafeInt(header[6:10]))
+}
+
+// readId3v2Tag reads the ID3v2 tag at the start of the file. the tag is
+// skipped when its header claims it's bigger than the file, rather than
+// allocating however much a damaged header asks for.
+func readId3v2Tag(file io.ReaderAt, fileSize int64, tags *AudioTags) {
+	tagSize := id3v2TagSize(file)
+	if tagSize == 0 || tagSize > fileSize {
+		return
+	}
+	header := make([]byte, id3v2HeaderSize)
+	file.ReadAt(header, 0)
+	version := header[3]
+	flags := header[5]
+	if version < 2 || version > 4 {
+		return
+	}
+
+	tag := make([]byte, tagSize-id3v2HeaderSize)
+	if _, err := file.ReadAt(tag, id3v2HeaderSize); err != nil {
+		return
+	}
+	if flags&id3FlagUnsynchronisation != 0 && version < 4 {
+		tag = removeUnsynchronisation(tag)
+	}
+	if flags&id3FlagExtendedHeader != 0 && version > 2 {
+		if len(tag) < 4 {
+			return
+		}
+		extendedSize := int(binary.BigEndian.Uint32(tag[0:4])) + 4
+		if version == 4 {
+			extendedSize = syncsafeInt(tag[0:4])
+		}
+		if extendedSize > len(tag) {
+			return
+		}
+		tag = tag[extendedSize:]
+	}
+
+	idLength, headerLength := 4, 10
+	if version == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for len(tag) >= headerLength && tag[0] != 0 {
+		frameId := string(tag[0:idLength])
+		var frameSize int
+		var formatFlags byte
+		switch version {
+		case 2:
+			frameSize = int(tag[3])<<16 | int(tag[4])<<8 | int(tag[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(tag[4:8]))
+			formatFlags = tag[9]
+		default:
+			frameSize = syncsafeInt(tag[4:8])
+			formatFlags = tag[9]
+		}
+		if frameSize < 0 || headerLength+frameSize > len(tag) {
+			return
+		}
+		frame := tag[headerLength : headerLength+frameSize]
+		tag = tag[headerLength+frameSize:]
+
+		field, isTextField := id3FrameFields[frameId]
+		if !isTextField {
+			continue
+		}
+		if version == 3 && formatFlags&(id3v23FrameCompressed|id3v23FrameEncrypted) != 0 {
+			continue
+		}
+		if version == 4 {
+			if formatFlags&(id3FrameCompressed|id3FrameEncrypted) != 0 {
+				continue
+			}
+			if formatFlags&id3FrameUnsynchronised != 0 {
+				frame = removeUnsynchronisation(frame)
+			}
+			if formatFlags&id3FrameDataLengthIndicator != 0 {
+				if len(frame) < 4 {
+					continue
+				}
+				frame = frame[4:]
+			}
+		}
+
+		value := id3TextFrameValue(frame)
+		if field == "genre" {
+			value = id3v2Genre(value)
+		}
+		tags.setField(field, value)
+	}
+}
+
+// syncsafeInt decodes the ID3v2 sizes that only use 7 bits of each byte.
+func syncsafeInt(data []byte) int {
+	value := 0
+	for _, b := range data {
+		value = value<<7 | int(b&0x7f)
+	}
+	return value
+}
+
+// removeUnsynchronisation undoes the 0x00 bytes that ID3v2 adds after
+// 0xff bytes so that the tag can't be mistaken for audio.
+func removeUnsynchronisation(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xff, 0x00}, []byte{0xff})
+}
+
+// id3TextFrameValue returns the (first) value of a text frame, which
+// starts with the byte that says how the text is encoded.
+func id3TextFrameValue(frame []byte) string {
+	if len(frame) < 1 {
+		return ""
+	}
+	encoding := frame[0]
+	text := frame[1:]
+	var value string
+	switch encoding {
+	case 0:
+		value = latin1String(text)
+	case 1, 2:
+		value = utf16String(text, encoding == 2)
+	default:
+		value = string(text)
+	}
+	// frames can hold several values separated by nulls
+	if posNull := strings.IndexRune(value, 0); posNull >= 0 {
+		value = value[:posNull]
+	}
+	return trimTagText(value)
+}
+
+// utf16String decodes UTF-16 text. the byte order comes from the byte
+// order mark, if there is one.
+func utf16String(data []byte, bigEndian bool) string {
+	if len(data) >= 2 {
+		if data[0] == 0xff && data[1] == 0xfe {
+			bigEndian = false
+			data = data[2:]
+		} else if data[0] == 0xfe && data[1] == 0xff {
+			bigEndian = true
+			data = data[2:]
+		}
+	}
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		var unit uint16
+		if bigEndian {
+			unit = binary.BigEndian.Uint16(data[i:])
+		} else {
+			unit = binary.LittleEndian.Uint16(data[i:])
+		}
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units))
+}
+
+// id3v2Genre turns genres that refer to the ID3v1 genres by number, like
+// "17", "(17)" or "(17)Rock", into the genre name.
+func id3v2Genre(value string) string {
+	if strings.HasPrefix(value, "(") {
+		posClose := strings.Index(value, ")")
+		if posClose > 0 {
+			if rest := strings.TrimSpace(value[posClose+1:]); len(rest) > 0 {
+				return rest
+			}
+			value = value[1:posClose]
+		}
+	}
+	if genreNumber, err := strconv.Atoi(value); err == nil {
+		return id3GenreName(genreNumber)
+	}
+	return value
+}
+
+func readId3v1Tag(file io.ReaderAt, fileSize int64, tags *AudioTags) {
+	if fileSize < id3v1TagSize {
+		return
+	}
+	tag := make([]byte, id3v1TagSize)
+	if _, err := file.ReadAt(tag, fileSize-id3v1TagSize); err != nil || !bytes.HasPrefix(tag, []byte("TAG")) {
+		return
+	}
+	tags.setField("title", trimTagText(latin1String(tag[3:33])))
+	tags.setField("artist", trimTagText(latin1String(tag[33:63])))
+	tags.setField("album", trimTagText(latin1String(tag[63:93])))
+	tags.setField("year", trimTagText(latin1String(tag[93:97])))
+	// ID3v1.1 puts the track number at the end of the comment
+	if tag[125] == 0 && tag[126] != 0 {
+		tags.setField("track", strconv.Itoa(int(tag[126])))
+	}
+	tags.setField("genre", id3GenreName(int(tag[127])))
+}
@@ -0,0 +1,118 @@
+package jukebox
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// readFixtureId3Tags reads the ID3 tags of a file in testdata/tags.
+func readFixtureId3Tags(t *testing.T, fileName string) *AudioTags {
+	file, err := os.Open(tagFixturePath(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readId3Tags(file, fileInfo.Size())
+}
+
+func TestReadId3v23Tags(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := readFixtureId3Tags(t, "id3v23.mp3")
+	th.RequireStringEquals(tags.Artist, "The Who", "latin-1 artist")
+	th.RequireStringEquals(tags.Album, "Who's Next", "latin-1 album")
+	th.RequireStringEquals(tags.Title, "Behind Blue Eyes", "UTF-16 title")
+	th.Require(tags.TrackNumber == 8, "track number")
+	th.Require(tags.DiscNumber == 1, "disc number")
+	th.Require(tags.Year == 1971, "year")
+	th.RequireStringEquals(tags.Genre, "Rock", "numbered genre")
+}
+
+func TestReadId3v24Tags(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := readFixtureId3Tags(t, "id3v24.mp3")
+	th.RequireStringEquals(tags.Artist, "Björk", "UTF-8 artist")
+	th.RequireStringEquals(tags.AlbumArtist, "Björk", "album artist")
+	th.RequireStringEquals(tags.Album, "Homogenic", "UTF-16BE album")
+	th.RequireStringEquals(tags.Title, "Jóga/Remix. Edit", "first of several values")
+	th.Require(tags.TrackNumber == 2, "track number")
+	th.Require(tags.DiscNumber == 2, "disc number after data length indicator")
+	th.Require(tags.Year == 1997, "year from recording time")
+	th.RequireStringEquals(tags.Genre, "Electronic", "genre")
+}
+
+func TestReadId3v22Tags(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := readFixtureId3Tags(t, "id3v22.mp3")
+	th.RequireStringEquals(tags.Artist, "Pink Floyd", "artist")
+	th.RequireStringEquals(tags.Album, "Animals", "album")
+	th.RequireStringEquals(tags.Title, "Dogs", "title")
+	th.Require(tags.TrackNumber == 3, "track number")
+	th.Require(tags.Year == 1977, "year")
+	th.RequireStringEquals(tags.Genre, "Progressive Rock", "genre")
+}
+
+func TestReadId3v1Tags(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := readFixtureId3Tags(t, "id3v1.mp3")
+	th.RequireStringEquals(tags.Artist, "The Who", "artist")
+	th.RequireStringEquals(tags.Album, "Who's Next", "album")
+	th.RequireStringEquals(tags.Title, "Baba O'Riley", "title")
+	th.Require(tags.TrackNumber == 1, "ID3v1.1 track number")
+	th.Require(tags.Year == 1971, "year")
+	th.RequireStringEquals(tags.Genre, "Rock", "genre")
+}
+
+func TestReadId3v2AndV1Tags(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := readFixtureId3Tags(t, "id3v2_and_v1.mp3")
+	th.RequireStringEquals(tags.Title, "Won't Get Fooled Again", "ID3v2 title must win")
+	th.RequireStringEquals(tags.Artist, "The Who", "artist from ID3v1")
+	th.Require(tags.TrackNumber == 9, "track number from ID3v1")
+}
+
+func TestReadId3TagsUntagged(t *testing.T) {
+	th := NewTestHelper(t)
+	tags := readFixtureId3Tags(t, "untagged.mp3")
+	th.Require(tags.isEmpty(), "untagged file must have empty tags")
+}
+
+func TestId3v2Genre(t *testing.T) {
+	th := NewTestHelper(t)
+	th.RequireStringEquals(id3v2Genre("(17)"), "Rock", "numbered in parentheses")
+	th.RequireStringEquals(id3v2Genre("17"), "Rock", "plain number")
+	th.RequireStringEquals(id3v2Genre("(17)Hard Rocking"), "Hard Rocking", "refinement")
+	th.RequireStringEquals(id3v2Genre("Shoegaze"), "Shoegaze", "named genre")
+}
+
+func TestSyncsafeInt(t *testing.T) {
+	th := NewTestHelper(t)
+	th.Require(syncsafeInt([]byte{0x00, 0x00, 0x02, 0x01}) == 257, "syncsafe size")
+	th.Require(syncsafeInt([]byte{0x7f, 0x7f, 0x7f, 0x7f}) == 0x0fffffff, "largest syncsafe size")
+}
+
+func TestRemoveUnsynchronisation(t *testing.T) {
+	th := NewTestHelper(t)
+	data := removeUnsynchronisation([]byte{0x01, 0xff, 0x00, 0xe0, 0xff, 0x00, 0x00})
+	th.Require(string(data) == string([]byte{0x01, 0xff, 0xe0, 0xff, 0x00}), "added zero bytes must be removed")
+}
+
+func TestUtf16String(t *testing.T) {
+	th := NewTestHelper(t)
+	th.RequireStringEquals(utf16String([]byte{0xff, 0xfe, 'H', 0, 'i', 0, 0, 0}, true), "Hi",
+		"little endian byte order mark")
+	th.RequireStringEquals(utf16String([]byte{0, 'H', 0, 'i'}, true), "Hi", "big endian without byte order mark")
+}
+
+func TestReadId3TagsSizeLargerThanFile(t *testing.T) {
+	th := NewTestHelper(t)
+	// the syncsafe size says the tag is about 256MB, in a 20 byte file
+	contents := []byte("ID3\x03\x00\x00\x7f\x7f\x7f\x7fTIT2")
+	contents = append(contents, make([]byte, 6)...)
+	tags := readId3Tags(bytes.NewReader(contents), int64(len(contents)))
+	th.Require(tags.isEmpty(), "tag bigger than the file must be skipped")
+}
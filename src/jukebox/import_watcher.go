@@ -0,0 +1,189 @@
+package jukebox
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultWatchIntervalSeconds = 5
+
+// longest wait before a file that failed to import is tried again
+const maxImportRetryDelay = 30 * time.Minute
+
+// ImportWatcher polls the song, playlist and album art import directories
+// and imports files once they've stopped changing. the files that are
+// ready on a poll are imported together, so the metadata DB is uploaded
+// at most once per poll. files that fail to import are tried again later,
+// as is an upload of the metadata DB that fails.
+type ImportWatcher struct {
+	jukebox       *Jukebox
+	pollInterval  time.Duration
+	settleTime    time.Duration
+	files         map[string]*watchedFile
+	uploadPending bool
+}
+
+// watchedFile is what a poll saw of a file in an import directory.
+type watchedFile struct {
+	size        int64
+	modTime     time.Time
+	stableSince time.Time
+	imported    bool
+	retryAt     time.Time
+	retryDelay  time.Duration
+}
+
+// NewImportWatcher returns a watcher that polls every pollInterval. a file
+// has to stay the same for a whole interval before it's imported, so that
+// files that are still being copied are left alone.
+func NewImportWatcher(jukebox *Jukebox, pollInterval time.Duration) *ImportWatcher {
+	var iw ImportWatcher
+	iw.jukebox = jukebox
+	iw.pollInterval = pollInterval
+	iw.settleTime = pollInterval
+	iw.files = make(map[string]*watchedFile)
+	iw.uploadPending = false
+	return &iw
+}
+
+// Run polls until the jukebox is asked to exit.
+func (iw *ImportWatcher) Run() {
+	jukebox := iw.jukebox
+	fmt.Printf("watching %s, %s and %s for new files\n",
+		jukebox.songImportDir,
+		jukebox.playlistImportDir,
+		jukebox.albumArtImportDir)
+
+	for !jukebox.isExitRequested() {
+		iw.Poll()
+		select {
+		case <-jukebox.ctx.Done():
+		case <-time.After(iw.pollInterval):
+		}
+	}
+}
+
+// Poll looks at the import directories once and imports the files that
+// have settled. it returns the number of files that were imported.
+func (iw *ImportWatcher) Poll() int {
+	jukebox := iw.jukebox
+	now := jukebox.clock()
+	present := make(map[string]bool)
+	readyFiles := make(map[string][]string)
+
+	for _, dirPath := range []string{jukebox.songImportDir, jukebox.playlistImportDir, jukebox.albumArtImportDir} {
+		fileNames, err := ListFilesInDirectory(dirPath)
+		if err != nil {
+			continue
+		}
+		for _, fileName := range fileNames {
+			filePath := PathJoin(dirPath, fileName)
+			fileInfo, errStat := os.Stat(filePath)
+			if errStat != nil {
+				continue
+			}
+			present[filePath] = true
+
+			file := iw.files[filePath]
+			if file == nil || file.size != fileInfo.Size() || !file.modTime.Equal(fileInfo.ModTime()) {
+				// new or changed since the last poll
+				var changedFile watchedFile
+				changedFile.size = fileInfo.Size()
+				changedFile.modTime = fileInfo.ModTime()
+				changedFile.stableSince = now
+				iw.files[filePath] = &changedFile
+			} else if !file.imported && now.Sub(file.stableSince) >= iw.settleTime &&
+				!now.Before(file.retryAt) {
+				readyFiles[dirPath] = append(readyFiles[dirPath], fileName)
+			}
+		}
+	}
+
+	// forget about files that are gone (e.g., moved away once imported)
+	for filePath := range iw.files {
+		if !present[filePath] {
+			delete(iw.files, filePath)
+		}
+	}
+
+	if len(readyFiles) == 0 && !iw.uploadPending {
+		return 0
+	}
+
+	importCount := 0
+	failedFiles := make(map[string]bool)
+	if fileNames := readyFiles[jukebox.songImportDir]; len(fileNames) > 0 {
+		songCount, songsFailed := jukebox.importSongFiles(fileNames)
+		importCount += songCount
+		iw.uploadPending = iw.uploadPending || songCount > 0
+		addFailedFiles(failedFiles, jukebox.songImportDir, songsFailed)
+	}
+	if fileNames := readyFiles[jukebox.playlistImportDir]; len(fileNames) > 0 {
+		playlistCount, playlistsFailed := jukebox.importPlaylistFiles(fileNames)
+		if playlistCount > 0 {
+			fmt.Printf("%d playlists imported\n", playlistCount)
+		}
+		importCount += playlistCount
+		iw.uploadPending = iw.uploadPending || playlistCount > 0
+		addFailedFiles(failedFiles, jukebox.playlistImportDir, playlistsFailed)
+	}
+	if fileNames := readyFiles[jukebox.albumArtImportDir]; len(fileNames) > 0 {
+		albumArtCount, albumArtFailed := jukebox.importAlbumArtFiles(fileNames)
+		if albumArtCount > 0 {
+			fmt.Printf("%d album art files imported\n", albumArtCount)
+		}
+		importCount += albumArtCount
+		addFailedFiles(failedFiles, jukebox.albumArtImportDir, albumArtFailed)
+	}
+
+	// the imported files are in the local metadata DB, so a failed upload
+	// of it is tried again on the next poll rather than importing them again
+	if iw.uploadPending {
+		if jukebox.UploadMetadataDb() {
+			iw.uploadPending = false
+		} else {
+			fmt.Println("error: unable to upload metadata db after import, trying again on next poll")
+		}
+	}
+
+	// files that were imported (or skipped) aren't looked at again until
+	// they change, the ones that failed are tried again later
+	for dirPath, fileNames := range readyFiles {
+		for _, fileName := range fileNames {
+			filePath := PathJoin(dirPath, fileName)
+			file := iw.files[filePath]
+			if file == nil {
+				continue
+			}
+			if failedFiles[filePath] {
+				iw.retryLater(filePath, file, now)
+			} else {
+				file.imported = true
+			}
+		}
+	}
+
+	return importCount
+}
+
+func addFailedFiles(failedFiles map[string]bool, dirPath string, fileNames []string) {
+	for _, fileName := range fileNames {
+		failedFiles[PathJoin(dirPath, fileName)] = true
+	}
+}
+
+// retryLater puts off the next attempt at importing a file that failed.
+// the wait starts at a poll interval and doubles each time it fails again.
+func (iw *ImportWatcher) retryLater(filePath string, file *watchedFile, now time.Time) {
+	if file.retryDelay == 0 {
+		file.retryDelay = iw.pollInterval
+	} else {
+		file.retryDelay *= 2
+	}
+	if file.retryDelay > maxImportRetryDelay {
+		file.retryDelay = maxImportRetryDelay
+	}
+	file.retryAt = now.Add(file.retryDelay)
+	fmt.Printf("error: unable to import %s, trying again in %v\n", filePath, file.retryDelay)
+}
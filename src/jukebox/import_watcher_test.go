@@ -0,0 +1,140 @@
+package jukebox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewImportWatcher(t *testing.T) {
+	th := NewTestHelper(t)
+	watcher := NewImportWatcher(nil, 3*time.Second)
+	th.Require(watcher.settleTime == 3*time.Second, "files must settle for a poll interval")
+	th.Require(len(watcher.files) == 0, "no files seen yet")
+}
+
+func TestImportWatcherPoll(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, nil)
+	clock := &fakeClock{now: time.Now()}
+	jukebox.clock = clock.Now
+	watcher := NewImportWatcher(jukebox, 5*time.Second)
+
+	songPath := PathJoin(jukebox.songImportDir, "The-Who--Whos-Next--My-Wife.mp3")
+	FileWriteAllText(songPath, "my wife")
+	FileWriteAllText(PathJoin(jukebox.playlistImportDir, "favorites.json"), `{"name": "Favorites"}`)
+	FileWriteAllText(PathJoin(jukebox.albumArtImportDir, "The-Who--Whos-Next.jpg"), "cover")
+	th.Require(watcher.Poll() == 0, "new files must settle before they're imported")
+
+	// the song is still being copied
+	clock.Advance(5 * time.Second)
+	FileWriteAllText(songPath, "my wife audio")
+	th.Require(watcher.Poll() == 2, "settled playlist and album art must be imported")
+	th.Require(jukebox.metadataDb() != nil, "db must stay open during upload")
+	th.Require(jukebox.jukeboxDb.getPlaylist("Favorites") != nil, "playlist must be stored")
+
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 1, "song must be imported once it stops changing")
+	song := jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--My-Wife.mp3")
+	th.Require(song != nil && song.Fm.OriginFileSize == int64(len("my wife audio")),
+		"song must be imported with its final contents")
+
+	metadataFiles, _ := jukebox.storageSystem.ListContainerContents(jukebox.ctx, jukebox.metadataContainer)
+	th.Require(stringInList(jukebox.metadataDbFile, metadataFiles), "metadata db must be uploaded")
+
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 0, "imported files must not be imported again")
+
+	// a file that's replaced is imported again once it settles
+	FileWriteAllText(songPath, "my wife audio, remastered")
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 0, "replaced file must settle")
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 1, "replaced file must be imported")
+
+	os.Remove(songPath)
+	watcher.Poll()
+	th.Require(watcher.files[songPath] == nil, "removed file must be forgotten")
+}
+
+// failingStorageSystem fails the next puts to the containers in failPuts
+// (container name -> number of puts to fail).
+type failingStorageSystem struct {
+	StorageSystemV2
+	mutex    sync.Mutex
+	failPuts map[string]int
+}
+
+func (fss *failingStorageSystem) PutObjectFromReader(ctx context.Context,
+	containerName string,
+	objectName string,
+	reader io.Reader,
+	contentLength int64,
+	headers *PropertySet) error {
+	fss.mutex.Lock()
+	fail := fss.failPuts[containerName] > 0
+	if fail {
+		fss.failPuts[containerName] -= 1
+	}
+	fss.mutex.Unlock()
+	if fail {
+		return errors.New("put failed")
+	}
+	return fss.StorageSystemV2.PutObjectFromReader(ctx, containerName, objectName, reader, contentLength, headers)
+}
+
+func TestImportWatcherRetry(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, nil)
+	clock := &fakeClock{now: time.Now()}
+	jukebox.clock = clock.Now
+	storageSystem := &failingStorageSystem{StorageSystemV2: jukebox.storageSystem,
+		failPuts: map[string]int{"w-artist-songs": 1, jukebox.metadataContainer: 1}}
+	jukebox.storageSystem = storageSystem
+	watcher := NewImportWatcher(jukebox, 5*time.Second)
+
+	songPath := PathJoin(jukebox.songImportDir, "The-Who--Whos-Next--My-Wife.mp3")
+	FileWriteAllText(songPath, "my wife audio")
+	watcher.Poll()
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 0, "song upload must fail")
+	th.Require(jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--My-Wife.mp3") == nil,
+		"song that failed to upload must not be stored")
+	th.Require(watcher.Poll() == 0, "failed song must wait before it's tried again")
+
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 1, "failed song must be imported on a later poll")
+	th.Require(jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--My-Wife.mp3") != nil, "song must be stored")
+	th.Require(watcher.uploadPending, "failed metadata db upload must be tried again")
+	metadataFiles, _ := jukebox.storageSystem.ListContainerContents(jukebox.ctx, jukebox.metadataContainer)
+	th.RequireFalse(stringInList(jukebox.metadataDbFile, metadataFiles), "metadata db upload must fail")
+
+	clock.Advance(5 * time.Second)
+	th.Require(watcher.Poll() == 0, "imported song must not be imported again")
+	th.RequireFalse(watcher.uploadPending, "metadata db must be uploaded on the next poll")
+	metadataFiles, _ = jukebox.storageSystem.ListContainerContents(jukebox.ctx, jukebox.metadataContainer)
+	th.Require(stringInList(jukebox.metadataDbFile, metadataFiles), "metadata db must be uploaded")
+}
+
+func TestImportWatcherRun(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, nil)
+	watcher := NewImportWatcher(jukebox, time.Hour)
+
+	done := make(chan bool)
+	go func() {
+		watcher.Run()
+		done <- true
+	}()
+	jukebox.requestExit()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		th.Require(false, "watcher must stop when exit is requested")
+	}
+}
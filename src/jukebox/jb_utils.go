@@ -1,6 +1,9 @@
 package jukebox
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+)
 
 const DoubleDashes string = "--"
 
@@ -21,6 +24,27 @@ func EncodeArtistAlbumSong(artist string, album string, song string) string {
 	return EncodeArtistAlbum(artist, album) + DoubleDashes + EncodeValue(song)
 }
 
+// EncodeTagValue encodes a value from an audio tag (which can have any
+// characters) so that it can be part of a song uid. characters that can't
+// be in a uid become spaces and runs of dashes become one dash, so that
+// the value can't be mistaken for the "--" between the uid's parts.
+func EncodeTagValue(value string) string {
+	cleanValue := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_,()", r) {
+			return r
+		}
+		if strings.ContainsRune("'!?&", r) {
+			return -1
+		}
+		return ' '
+	}, value)
+	encodedValue := strings.Join(strings.Fields(cleanValue), "-")
+	for strings.Contains(encodedValue, DoubleDashes) {
+		encodedValue = strings.Replace(encodedValue, DoubleDashes, "-", -1)
+	}
+	return strings.Trim(encodedValue, "-")
+}
+
 func RemovePunctuation(s string) string {
 	if strings.Contains(s, "'") {
 		s = strings.Replace(s, "'", "", -1)
@@ -35,3 +35,13 @@ func TestEncodeValue(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestEncodeTagValue(t *testing.T) {
+	th := NewTestHelper(t)
+	th.RequireStringEquals(EncodeTagValue("Who's Next"), "Whos-Next", "punctuation must be removed")
+	th.RequireStringEquals(EncodeTagValue("  Mr. Blue Sky "), "Mr-Blue-Sky", "dots must not be kept")
+	th.RequireStringEquals(EncodeTagValue("AC/DC"), "AC-DC", "slashes must not be kept")
+	th.RequireStringEquals(EncodeTagValue("Side A -- Part 2"), "Side-A-Part-2", "double dashes must not be kept")
+	th.RequireStringEquals(EncodeTagValue("Jóga (Live)"), "Jóga-(Live)", "letters and parentheses must be kept")
+	th.RequireStringEquals(EncodeTagValue("--"), "", "only dashes")
+}
@@ -1,21 +1,25 @@
 package jukebox
 
 import (
+	"context"
+	"crypto/md5"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
-	"os/exec"
 	"os/signal"
-	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 const (
 	downloadExtension   = ".download"
+	streamExtension     = ".stream"
+	uploadExtension     = ".upload"
 	albumContainer      = "albums"
 	albumArtContainer   = "album-art"
 	metadataContainer   = "music-metadata"
@@ -24,9 +28,15 @@ const (
 	albumArtImportDir   = "album-art-import"
 	playlistImportDir   = "playlist-import"
 	songImportDir       = "song-import"
+	songImportedDir     = "song-imported"
 	songPlayDir         = "song-play"
 	defaultDbFileName   = "jukebox_db.sqlite3"
 	jukeboxPidFileName  = "jukebox.pid"
+
+	previewCheckInterval = 250 * time.Millisecond
+
+	// players start at full volume
+	defaultVolume = 100
 )
 
 type AlbumTrack struct {
@@ -61,12 +71,15 @@ type Playlist struct {
 
 type Jukebox struct {
 	jukeboxOptions          *JukeboxOptions
-	storageSystem           StorageSystem
+	storageSystem           StorageSystemV2
+	ctx                     context.Context
+	cancel                  context.CancelFunc
 	debugPrint              bool
 	jukeboxDb               *JukeboxDB
 	containerPrefix         string
 	currentDir              string
 	songImportDir           string
+	songImportedDir         string
 	playlistImportDir       string
 	songPlayDir             string
 	albumArtImportDir       string
@@ -75,18 +88,30 @@ type Jukebox struct {
 	playlistContainer       string
 	albumContainer          string
 	albumArtContainer       string
-	songList                []*SongMetadata
-	numberSongs             int
-	songIndex               int
-	audioPlayerExeFileName  string
-	audioPlayerCommandArgs  string
-	audioPlayerProcess      *os.Process
+	queue                   *Queue
+	audioPlayer             AudioPlayer
 	songPlayLengthSeconds   int
+	songDownloader          *SongDownloader
+	eventBus                *EventBus
+	songCache               *SongCache
+	stateMutex              sync.Mutex
 	cumulativeDownloadBytes int64
 	cumulativeDownloadTime  float64
 	exitRequested           bool
 	isPaused                bool
 	songSecondsOffset       int
+	songStartTime           time.Time
+	songsPlayed             int
+	stopTimer               *time.Timer
+	sleepTimer              *time.Timer
+	sleepTime               time.Time
+	playSource              *PlaySource
+	volume                  int
+	keysFromTerminal        bool
+	sessionMutex            sync.Mutex
+	dbMutex                 sync.Mutex
+	uploadMutex             sync.Mutex
+	clock                   func() time.Time
 }
 
 func signalHandler(signalChannel chan os.Signal, jukebox *Jukebox) {
@@ -113,13 +138,14 @@ func (jukebox *Jukebox) installSignalHandlers() {
 }
 
 func NewJukebox(jbOptions *JukeboxOptions,
-	storageSys StorageSystem,
+	storageSys StorageSystemV2,
 	containerPrefix string,
 	debugPrint bool) *Jukebox {
 
 	var jukebox Jukebox
 	jukebox.jukeboxOptions = jbOptions
 	jukebox.storageSystem = storageSys
+	jukebox.ctx, jukebox.cancel = context.WithCancel(context.Background())
 	jukebox.debugPrint = debugPrint
 	jukebox.jukeboxDb = nil
 	jukebox.containerPrefix = containerPrefix
@@ -131,22 +157,30 @@ func NewJukebox(jbOptions *JukeboxOptions,
 		return nil
 	}
 	jukebox.songImportDir = PathJoin(jukebox.currentDir, songImportDir)
+	jukebox.songImportedDir = PathJoin(jukebox.currentDir, songImportedDir)
 	jukebox.playlistImportDir = PathJoin(jukebox.currentDir, playlistImportDir)
 	jukebox.songPlayDir = PathJoin(jukebox.currentDir, songPlayDir)
 	jukebox.albumArtImportDir = PathJoin(jukebox.currentDir, albumArtImportDir)
+	if jukebox.jukeboxOptions != nil {
+		jukebox.songCache = NewSongCache(PathJoin(jukebox.currentDir, songCacheDir),
+			jukebox.jukeboxOptions.SongCacheMaxBytes)
+	} else {
+		jukebox.songCache = NewSongCache(PathJoin(jukebox.currentDir, songCacheDir), 0)
+	}
 	jukebox.metadataDbFile = defaultDbFileName
-	jukebox.songList = []*SongMetadata{}
-	jukebox.numberSongs = 0
-	jukebox.songIndex = -1
-	jukebox.audioPlayerExeFileName = ""
-	jukebox.audioPlayerCommandArgs = ""
-	jukebox.audioPlayerProcess = nil
+	jukebox.queue = NewQueue()
+	jukebox.queue.SetChangeListener(jukebox.queueChanged)
+	jukebox.audioPlayer = nil
 	jukebox.songPlayLengthSeconds = 20
 	jukebox.cumulativeDownloadBytes = 0
 	jukebox.cumulativeDownloadTime = 0
 	jukebox.exitRequested = false
 	jukebox.isPaused = false
 	jukebox.songSecondsOffset = 0
+	jukebox.volume = defaultVolume
+	jukebox.keysFromTerminal = true
+	jukebox.clock = time.Now
+	jukebox.eventBus = NewEventBus(defaultEventBufferSize)
 	jukebox.metadataContainer = containerPrefix + metadataContainer
 	jukebox.playlistContainer = containerPrefix + playlistContainer
 	jukebox.albumContainer = containerPrefix + albumContainer
@@ -169,13 +203,26 @@ func NewJukebox(jbOptions *JukeboxOptions,
 
 func (jukebox *Jukebox) Enter() bool {
 	// look for stored metadata in the storage system
-	if jukebox.storageSystem != nil &&
-		jukebox.storageSystem.HasContainer(jukebox.metadataContainer) &&
-		!jukebox.jukeboxOptions.SuppressMetadataDownload {
+	if jukebox.storageSystem == nil {
+		return false
+	}
+
+	haveMetadataContainer, err := jukebox.storageSystem.HasContainer(jukebox.ctx, jukebox.metadataContainer)
+	if err != nil {
+		fmt.Printf("error: unable to check for metadata container\n")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+
+	if haveMetadataContainer && !jukebox.jukeboxOptions.SuppressMetadataDownload {
+		// a DB from an earlier Enter is closed before its file is replaced
+		if previousDb := jukebox.setMetadataDb(nil); previousDb != nil {
+			previousDb.exit()
+		}
 
 		// metadata container exists, retrieve container listing
 		metadataFileInContainer := false
-		containerContents, err := jukebox.storageSystem.ListContainerContents(jukebox.metadataContainer)
+		containerContents, err := jukebox.storageSystem.ListContainerContents(jukebox.ctx, jukebox.metadataContainer)
 		if err == nil && len(containerContents) > 0 {
 			for _, container := range containerContents {
 				if container == jukebox.metadataDbFile {
@@ -190,7 +237,11 @@ func (jukebox *Jukebox) Enter() bool {
 			// download it
 			metadataDbFilePath := jukebox.GetMetadataDbFilePath()
 			downloadFile := metadataDbFilePath + downloadExtension
-			if jukebox.storageSystem.GetObject(jukebox.metadataContainer, jukebox.metadataDbFile, downloadFile) > 0 {
+			bytesRetrieved, err := jukebox.storageSystem.GetObject(jukebox.ctx,
+				jukebox.metadataContainer,
+				jukebox.metadataDbFile,
+				downloadFile)
+			if err == nil && bytesRetrieved > 0 {
 				// have an existing metadata DB file?
 				if FileExists(metadataDbFilePath) {
 					if jukebox.debugPrint {
@@ -204,8 +255,9 @@ func (jukebox *Jukebox) Enter() bool {
 				}
 				RenameFile(downloadFile, metadataDbFilePath)
 			} else {
-				if jukebox.debugPrint {
-					fmt.Println("no metadata DB file in metadata container")
+				fmt.Println("error: unable to download metadata DB file")
+				if err != nil {
+					fmt.Printf("error: %v\n", err)
 				}
 			}
 		} else {
@@ -214,10 +266,12 @@ func (jukebox *Jukebox) Enter() bool {
 			}
 		}
 
-		jukebox.jukeboxDb = NewJukeboxDB(jukebox.GetMetadataDbFilePath(),
+		jukeboxDb := NewJukeboxDB(jukebox.GetMetadataDbFilePath(),
 			jukebox.debugPrint)
-		jukeboxDbSuccess := jukebox.jukeboxDb.enter()
-		if !jukeboxDbSuccess {
+		jukeboxDbSuccess := jukeboxDb.enter()
+		if jukeboxDbSuccess {
+			jukebox.setMetadataDb(jukeboxDb)
+		} else {
 			fmt.Println("unable to connect to database")
 		}
 		return jukeboxDbSuccess
@@ -227,62 +281,742 @@ func (jukebox *Jukebox) Enter() bool {
 }
 
 func (jukebox *Jukebox) Exit() {
-	if jukebox.jukeboxDb != nil {
-		jukebox.jukeboxDb.exit()
-		jukebox.jukeboxDb = nil
+	if jukeboxDb := jukebox.setMetadataDb(nil); jukeboxDb != nil {
+		jukeboxDb.exit()
+	}
+}
+
+// metadataDb returns the open metadata DB, or nil if there isn't one. the
+// play goroutine, the tui and the servers all use the DB, so the handle is
+// only read or replaced through metadataDb and setMetadataDb.
+func (jukebox *Jukebox) metadataDb() *JukeboxDB {
+	jukebox.dbMutex.Lock()
+	defer jukebox.dbMutex.Unlock()
+	if jukebox.jukeboxDb == nil || !jukebox.jukeboxDb.isOpen() {
+		return nil
+	}
+	return jukebox.jukeboxDb
+}
+
+// setMetadataDb replaces the metadata DB and returns the one it replaced.
+func (jukebox *Jukebox) setMetadataDb(jukeboxDb *JukeboxDB) *JukeboxDB {
+	jukebox.dbMutex.Lock()
+	defer jukebox.dbMutex.Unlock()
+	previousDb := jukebox.jukeboxDb
+	jukebox.jukeboxDb = jukeboxDb
+	return previousDb
+}
+
+func (jukebox *Jukebox) currentAudioPlayer() AudioPlayer {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	return jukebox.audioPlayer
+}
+
+func (jukebox *Jukebox) setAudioPlayer(audioPlayer AudioPlayer) {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	jukebox.audioPlayer = audioPlayer
+}
+
+// initAudioPlayer creates the audio player chosen in the options. if it
+// can't be created, songs are 'played' by the null player.
+func (jukebox *Jukebox) initAudioPlayer() {
+	playerName := DefaultAudioPlayerName()
+	playerCommand := ""
+	if jukebox.jukeboxOptions != nil {
+		playerName = jukebox.jukeboxOptions.AudioPlayer
+		playerCommand = jukebox.jukeboxOptions.AudioPlayerCommand
+	}
+
+	audioPlayer, err := NewAudioPlayer(playerName, playerCommand, jukebox.songPlayLengthSeconds)
+	if err != nil {
+		fmt.Printf("error: unable to create audio player '%s'\n", playerName)
+		fmt.Printf("error: %v\n", err)
+		audioPlayer = NewNullAudioPlayer(jukebox.songPlayLengthSeconds)
+	}
+	if jukebox.debugPrint {
+		fmt.Printf("using audio player '%s'\n", playerName)
 	}
+	jukebox.setAudioPlayer(audioPlayer)
 }
 
-func (jukebox *Jukebox) killAudioPlayerProcess() {
-	if jukebox.audioPlayerProcess != nil {
-		err := jukebox.audioPlayerProcess.Kill()
+func (jukebox *Jukebox) stopAudioPlayer() {
+	audioPlayer := jukebox.currentAudioPlayer()
+	if audioPlayer != nil {
+		err := audioPlayer.Stop()
 		if err != nil {
-			fmt.Println("error: unable to kill audio player process")
-		} else {
-			jukebox.audioPlayerProcess = nil
+			fmt.Println("error: unable to stop audio player")
+			fmt.Printf("error: %v\n", err)
+		}
+	}
+}
+
+func (jukebox *Jukebox) controllableAudioPlayer() ControllableAudioPlayer {
+	if audioPlayer, ok := jukebox.currentAudioPlayer().(ControllableAudioPlayer); ok {
+		return audioPlayer
+	}
+	return nil
+}
+
+// togglePauseInPlayer pauses or resumes a player that supports it without
+// stopping the song. returns false if that isn't possible.
+func (jukebox *Jukebox) togglePauseInPlayer() bool {
+	audioPlayer := jukebox.controllableAudioPlayer()
+	if audioPlayer == nil {
+		return false
+	}
+
+	pause := !jukebox.isPausedNow()
+	var err error
+	if pause {
+		err = audioPlayer.Pause()
+	} else {
+		err = audioPlayer.Resume()
+	}
+	if err != nil {
+		if jukebox.debugPrint {
+			fmt.Printf("unable to toggle pause in audio player: %v\n", err)
 		}
+		return false
 	}
+
+	jukebox.stateMutex.Lock()
+	jukebox.isPaused = pause
+	jukebox.stateMutex.Unlock()
+
+	positionSeconds := jukebox.currentSongPosition()
+	position := formatSongPosition(positionSeconds)
+	if pause {
+		fmt.Printf("paused at %s\n", position)
+	} else {
+		fmt.Printf("resuming play at %s\n", position)
+	}
+	jukebox.publishPauseEvent(pause, positionSeconds)
+	return true
+}
+
+func (jukebox *Jukebox) publishPauseEvent(isPaused bool, positionSeconds int) {
+	eventType := EventResumed
+	if isPaused {
+		eventType = EventPaused
+	}
+	event := NewJukeboxEvent(eventType, jukebox.currentSong())
+	event.PositionSeconds = positionSeconds
+	jukebox.publishEvent(event)
 }
 
 func (jukebox *Jukebox) TogglePausePlay() {
+	if jukebox.togglePauseInPlayer() {
+		return
+	}
+
+	jukebox.stateMutex.Lock()
 	jukebox.isPaused = !jukebox.isPaused
-	if jukebox.isPaused {
-		fmt.Println("paused")
-		//TODO: capture current song position (seconds into song)
-		jukebox.killAudioPlayerProcess()
+	isPaused := jukebox.isPaused
+	if isPaused && !jukebox.songStartTime.IsZero() {
+		// remember how far into the song we are so that play can resume there
+		jukebox.songSecondsOffset += int(jukebox.clock().Sub(jukebox.songStartTime).Seconds())
+		jukebox.songStartTime = time.Time{}
+	}
+	songSecondsOffset := jukebox.songSecondsOffset
+	jukebox.stateMutex.Unlock()
+
+	if isPaused {
+		fmt.Printf("paused at %s\n", formatSongPosition(songSecondsOffset))
+		jukebox.stopAudioPlayer()
 	} else {
-		fmt.Println("resuming play")
+		fmt.Printf("resuming play at %s\n", formatSongPosition(songSecondsOffset))
+	}
+	jukebox.publishPauseEvent(isPaused, songSecondsOffset)
+}
+
+func (jukebox *Jukebox) isPausedNow() bool {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	return jukebox.isPaused
+}
+
+// startSongPlay records that the current song starts (or resumes) playing
+// now. returns the number of seconds into the song to start at, which is
+// always 0 for players that can't start at an offset.
+func (jukebox *Jukebox) startSongPlay(canStartAtOffset bool) int {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if !canStartAtOffset {
+		jukebox.songSecondsOffset = 0
+	}
+	jukebox.songStartTime = jukebox.clock()
+	return jukebox.songSecondsOffset
+}
+
+// resetSongPosition is called when moving on to another song.
+func (jukebox *Jukebox) resetSongPosition() {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	jukebox.songSecondsOffset = 0
+	jukebox.songStartTime = time.Time{}
+}
+
+// currentSongPosition returns the number of seconds into the current song.
+// players that know where they are have the final say.
+func (jukebox *Jukebox) currentSongPosition() int {
+	if audioPlayer := jukebox.controllableAudioPlayer(); audioPlayer != nil {
+		if timePos, _, err := audioPlayer.Position(); err == nil {
+			return int(timePos)
+		}
+	}
+
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	position := jukebox.songSecondsOffset
+	if !jukebox.isPaused && !jukebox.songStartTime.IsZero() {
+		position += int(jukebox.clock().Sub(jukebox.songStartTime).Seconds())
 	}
+	return position
+}
+
+// currentSongLength returns the length of the current song in seconds, or
+// 0 if the player can't tell.
+func (jukebox *Jukebox) currentSongLength() int {
+	if audioPlayer := jukebox.controllableAudioPlayer(); audioPlayer != nil {
+		if _, length, err := audioPlayer.Position(); err == nil {
+			return int(length)
+		}
+	}
+	return 0
+}
+
+// queueSecondsLeft returns how long it will be until the end of the
+// queue (before it starts over, if it repeats), which is the rest of the
+// current song and the songs after it. returns 0 if a song's length isn't
+// known or the current song repeats.
+func (jukebox *Jukebox) queueSecondsLeft(positionSeconds int, durationSeconds int) int {
+	if durationSeconds <= 0 || jukebox.queue.RepeatMode() == RepeatOne {
+		return 0
+	}
+	secondsLeft := durationSeconds - positionSeconds
+	if secondsLeft < 0 {
+		secondsLeft = 0
+	}
+	index, numberSongs := jukebox.queue.Position()
+	for _, song := range jukebox.queue.Upcoming(numberSongs - index - 1) {
+		if song.DurationMs <= 0 {
+			return 0
+		}
+		secondsLeft += int(song.DurationMs / 1000)
+	}
+	return secondsLeft
+}
+
+func formatSongPosition(seconds int) string {
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// formatDuration formats lengths that can be an hour or more, like that
+// of an album or playlist.
+func formatDuration(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return formatSongPosition(seconds)
 }
 
 func (jukebox *Jukebox) AdvanceToNextSong() {
 	fmt.Println("advancing to next song")
-	jukebox.killAudioPlayerProcess()
+	jukebox.changeSong(false)
+}
+
+// PreviousSong goes back to the song played before the current one.
+// returns false if no song has been played before it.
+func (jukebox *Jukebox) PreviousSong() bool {
+	if !jukebox.queue.Previous() {
+		return false
+	}
+	fmt.Println("going back to previous song")
+	jukebox.publishEvent(NewJukeboxEvent(EventQueueChanged, nil))
+	jukebox.changeSong(true)
+	return true
+}
+
+// changeSong ends the current song so that the play loop moves on to the
+// current song of the queue, advancing the queue first unless it has
+// already been moved. a paused song is resumed first, since moving to
+// another song means that play continues.
+func (jukebox *Jukebox) changeSong(queueMoved bool) {
+	if jukebox.controllableAudioPlayer() != nil && jukebox.isPausedNow() {
+		// the song is paused in the player. resume it so that the
+		// next song plays once this one has been stopped.
+		jukebox.togglePauseInPlayer()
+	}
+
+	if !queueMoved {
+		jukebox.queue.Advance()
+	}
+
+	if jukebox.isPausedNow() {
+		// the audio player was stopped when pausing, so the play loop
+		// is waiting rather than playing
+		jukebox.stateMutex.Lock()
+		jukebox.isPaused = false
+		jukebox.stateMutex.Unlock()
+		jukebox.resetSongPosition()
+	}
+
+	jukebox.stopAudioPlayer()
+}
+
+// Pause pauses play. returns false if play was already paused.
+func (jukebox *Jukebox) Pause() bool {
+	if jukebox.isPausedNow() {
+		return false
+	}
+	jukebox.TogglePausePlay()
+	return true
+}
+
+// Resume resumes paused play. returns false if play wasn't paused.
+func (jukebox *Jukebox) Resume() bool {
+	if !jukebox.isPausedNow() {
+		return false
+	}
+	jukebox.TogglePausePlay()
+	return true
+}
+
+// currentSong returns the song being played (or paused), if any.
+func (jukebox *Jukebox) currentSong() *SongMetadata {
+	song, _ := jukebox.queue.Current()
+	return song
+}
+
+// Queue returns the queue of songs being played.
+func (jukebox *Jukebox) Queue() *Queue {
+	return jukebox.queue
+}
+
+// queueChanged is called when the upcoming songs change, so that the
+// right songs get downloaded.
+func (jukebox *Jukebox) queueChanged() {
+	jukebox.downloadSongs()
+	jukebox.publishEvent(NewJukeboxEvent(EventQueueChanged, nil))
+}
+
+// songForQueue looks up a song to add to the queue.
+func (jukebox *Jukebox) songForQueue(songUid string) *SongMetadata {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return nil
+	}
+	song := jukeboxDb.retrieveSong(songUid)
+	if song == nil {
+		fmt.Printf("error: unknown song '%s'\n", songUid)
+	}
+	return song
+}
+
+// EnqueueSong adds the song to the end of the queue. returns false if
+// there's no such song.
+func (jukebox *Jukebox) EnqueueSong(songUid string) bool {
+	song := jukebox.songForQueue(songUid)
+	if song == nil {
+		return false
+	}
+	jukebox.queue.Append(song)
+	fmt.Printf("enqueued %s\n", songUid)
+	return true
+}
+
+// enqueueSongs adds the songs to the end of the queue. when there's no
+// current song (nothing was queued, or play ran out of songs), the first
+// of them becomes the current song.
+func (jukebox *Jukebox) enqueueSongs(songs []*SongMetadata) {
+	if jukebox.currentSong() == nil {
+		queued := jukebox.queue.Songs()
+		jukebox.queue.Restore(append(queued, songs...), len(queued))
+	} else {
+		jukebox.queue.Append(songs...)
+	}
+}
+
+// PlayNext adds the song to the queue so that it plays after the current
+// song. returns false if there's no such song.
+func (jukebox *Jukebox) PlayNext(songUid string) bool {
+	song := jukebox.songForQueue(songUid)
+	if song == nil {
+		return false
+	}
+	jukebox.queue.InsertNext(song)
+	fmt.Printf("playing %s next\n", songUid)
+	return true
+}
+
+// RemoveFromQueue removes the upcoming song at position (0 is the next
+// song).
+func (jukebox *Jukebox) RemoveFromQueue(position int) bool {
+	return jukebox.queue.Remove(position)
+}
+
+// MoveInQueue moves the upcoming song at position from to position to.
+func (jukebox *Jukebox) MoveInQueue(from int, to int) bool {
+	return jukebox.queue.Move(from, to)
+}
+
+// ClearQueue removes the upcoming and previously played songs.
+func (jukebox *Jukebox) ClearQueue() {
+	jukebox.queue.Clear()
+	fmt.Println("queue cleared")
+}
+
+// Events returns the bus that the jukebox publishes its events on.
+func (jukebox *Jukebox) Events() *EventBus {
+	return jukebox.eventBus
+}
+
+func (jukebox *Jukebox) publishEvent(event *JukeboxEvent) {
+	if jukebox.eventBus != nil {
+		event.Time = jukebox.clock()
+		jukebox.eventBus.Publish(event)
+	}
+	switch event.Type {
+	case EventSongStarted, EventPaused, EventResumed, EventQueueChanged:
+		jukebox.saveSession()
+	}
+}
+
+// saveSession saves what's being played so that play can be resumed
+// after the jukebox is restarted. it's only saved while playing.
+func (jukebox *Jukebox) saveSession() {
+	jukebox.stateMutex.Lock()
+	playSource := jukebox.playSource
+	jukebox.stateMutex.Unlock()
+	if playSource == nil {
+		return
+	}
+
+	session := NewPlaySession()
+	session.Source = *playSource
+	for _, song := range jukebox.queue.Songs() {
+		session.Songs = append(session.Songs, song.Fm.FileUid)
+	}
+	session.Index, _ = jukebox.queue.Position()
+	session.OffsetSeconds = jukebox.currentSongPosition()
+	session.RepeatMode = jukebox.queue.RepeatMode()
+	session.SavedAt = jukebox.clock()
+
+	jukebox.sessionMutex.Lock()
+	defer jukebox.sessionMutex.Unlock()
+	session.Save(sessionFileName)
+}
+
+func (jukebox *Jukebox) setPlaySource(playSource *PlaySource) {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	jukebox.playSource = playSource
+}
+
+// ResumeSession carries on playing where the last play session stopped.
+// songs that have been deleted since then are skipped. returns false if
+// there's no session to resume.
+func (jukebox *Jukebox) ResumeSession() bool {
+	session := LoadPlaySession(sessionFileName)
+	if session == nil {
+		fmt.Println("error: no play session to resume")
+		return false
+	}
+
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
+	}
+
+	var songList []*SongMetadata
+	index := 0
+	offsetSeconds := session.OffsetSeconds
+	for i, songUid := range session.Songs {
+		song := jukeboxDb.retrieveSong(songUid)
+		if song == nil {
+			fmt.Printf("skipping deleted song %s\n", songUid)
+			if i == session.Index {
+				// the next song that's still around plays from its start
+				offsetSeconds = 0
+			}
+			continue
+		}
+		if i < session.Index {
+			index += 1
+		}
+		songList = append(songList, song)
+	}
+	if len(songList) == 0 {
+		fmt.Println("error: none of the songs in the play session are left")
+		return false
+	}
+	if index >= len(songList) {
+		index = 0
+		offsetSeconds = 0
+	}
+
+	fmt.Printf("resuming %s at song %d of %d (%s)\n", session.Source.Describe(),
+		index+1, len(songList), formatSongPosition(offsetSeconds))
+	jukebox.queue.SetRepeatMode(session.RepeatMode)
+	jukebox.queue.Restore(songList, index)
+	jukebox.stateMutex.Lock()
+	jukebox.songSecondsOffset = offsetSeconds
+	jukebox.songStartTime = time.Time{}
+	jukebox.stateMutex.Unlock()
+	return jukebox.playQueue(&session.Source)
+}
+
+// Status returns a snapshot of what's playing.
+func (jukebox *Jukebox) Status() *PlayerStatus {
+	status := NewPlayerStatus()
+	song, _ := jukebox.queue.Current()
+	status.Index, status.NumberSongs = jukebox.queue.Position()
+	status.Paused = jukebox.isPausedNow()
+	status.SleepSeconds = jukebox.sleepSecondsLeft()
+	if song != nil {
+		status.Song = NewSongInfo(song)
+		status.PositionSeconds = jukebox.currentSongPosition()
+		status.DurationSeconds = jukebox.currentSongLength()
+		if status.DurationSeconds == 0 {
+			// the player can't tell, so go by what was read at import
+			status.DurationSeconds = status.Song.DurationSeconds
+		}
+		status.RemainingSeconds = jukebox.queueSecondsLeft(status.PositionSeconds, status.DurationSeconds)
+	}
+	return status
+}
+
+// UpcomingSongs returns the songs that will be played after the current
+// one, in the order they'll be played.
+func (jukebox *Jukebox) UpcomingSongs() []*SongInfo {
+	upcoming := []*SongInfo{}
+	for _, song := range jukebox.queue.Upcoming(-1) {
+		upcoming = append(upcoming, NewSongInfo(song))
+	}
+	return upcoming
+}
+
+// SetVolume sets the volume of the audio player (0-100).
+func (jukebox *Jukebox) SetVolume(percent int) bool {
+	audioPlayer := jukebox.controllableAudioPlayer()
+	if audioPlayer == nil {
+		fmt.Println("error: audio player doesn't support changing volume")
+		return false
+	}
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	err := audioPlayer.SetVolume(percent)
+	if err != nil {
+		fmt.Println("error: unable to set volume")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	jukebox.stateMutex.Lock()
+	jukebox.volume = percent
+	jukebox.stateMutex.Unlock()
+	return true
+}
+
+// ChangeVolume raises (or with a negative change, lowers) the volume
+// from where it was last set.
+func (jukebox *Jukebox) ChangeVolume(change int) bool {
+	jukebox.stateMutex.Lock()
+	volume := jukebox.volume
+	jukebox.stateMutex.Unlock()
+	return jukebox.SetVolume(volume + change)
+}
+
+// Volume returns the volume that was last set (0-100).
+func (jukebox *Jukebox) Volume() int {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	return jukebox.volume
+}
+
+// SeekTo moves playback of the current song to offsetSeconds.
+func (jukebox *Jukebox) SeekTo(offsetSeconds int) bool {
+	audioPlayer := jukebox.controllableAudioPlayer()
+	if audioPlayer == nil {
+		fmt.Println("error: audio player doesn't support seeking")
+		return false
+	}
+	err := audioPlayer.Seek(offsetSeconds)
+	if err != nil {
+		fmt.Println("error: unable to seek")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	return true
 }
 
 func (jukebox *Jukebox) PrepareForTermination() {
 	fmt.Println("Ctrl-C detected, shutting down")
+	jukebox.requestExit()
+}
 
+// StopPlay stops the current song and ends play.
+func (jukebox *Jukebox) StopPlay() {
+	fmt.Println("stopping play")
+	jukebox.requestExit()
+}
+
+func (jukebox *Jukebox) requestExit() {
 	// indicate that it's time to shut down
+	jukebox.stateMutex.Lock()
 	jukebox.exitRequested = true
+	jukebox.stateMutex.Unlock()
+
+	// cancel any storage requests (e.g., song downloads) that are in flight
+	jukebox.cancel()
 
 	// terminate audio player if it's running
-	jukebox.killAudioPlayerProcess()
+	jukebox.stopAudioPlayer()
+}
+
+// stopForLimit ends play because one of the limits on how long to play
+// has been reached.
+func (jukebox *Jukebox) stopForLimit(reason string) {
+	fmt.Printf("%s, stopping play\n", reason)
+	jukebox.requestExit()
+}
+
+// startStopTimer starts the timer for the stop-after-minutes and stop-at
+// options, whichever comes first.
+func (jukebox *Jukebox) startStopTimer() {
+	now := jukebox.clock()
+	stopTime := time.Time{}
+	if jukebox.jukeboxOptions.StopAfterMinutes > 0 {
+		stopTime = now.Add(time.Duration(jukebox.jukeboxOptions.StopAfterMinutes) * time.Minute)
+	}
+	if len(jukebox.jukeboxOptions.StopAt) > 0 {
+		stopAt, err := nextTimeOfDay(jukebox.jukeboxOptions.StopAt, now)
+		if err == nil && (stopTime.IsZero() || stopAt.Before(stopTime)) {
+			stopTime = stopAt
+		}
+	}
+	if stopTime.IsZero() {
+		return
+	}
+
+	fmt.Printf("play will stop at %s\n", stopTime.Format("15:04"))
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	jukebox.stopTimer = time.AfterFunc(stopTime.Sub(now), func() {
+		jukebox.stopForLimit("stop time reached")
+	})
+}
+
+// stopTimers stops the timers that end play, once play has ended.
+func (jukebox *Jukebox) stopTimers() {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if jukebox.stopTimer != nil {
+		jukebox.stopTimer.Stop()
+		jukebox.stopTimer = nil
+	}
+	if jukebox.sleepTimer != nil {
+		jukebox.sleepTimer.Stop()
+		jukebox.sleepTimer = nil
+	}
+}
+
+// songPlayed counts a song that has been played to its end, and stops
+// play once the number of songs to play has been reached.
+func (jukebox *Jukebox) songPlayed() {
+	jukebox.stateMutex.Lock()
+	jukebox.songsPlayed += 1
+	songsPlayed := jukebox.songsPlayed
+	jukebox.stateMutex.Unlock()
+
+	numberSongs := jukebox.jukeboxOptions.NumberSongs
+	if numberSongs > 0 && songsPlayed >= numberSongs {
+		jukebox.stopForLimit(fmt.Sprintf("%d songs played", songsPlayed))
+	}
+}
+
+// SetSleepTimer stops play after the given number of minutes, replacing
+// any sleep timer that was already set.
+func (jukebox *Jukebox) SetSleepTimer(minutes int) bool {
+	if minutes < 1 {
+		return false
+	}
+	jukebox.setSleepTimer(time.Duration(minutes) * time.Minute)
+	fmt.Printf("sleep timer set for %d minutes\n", minutes)
+	return true
+}
+
+func (jukebox *Jukebox) setSleepTimer(duration time.Duration) {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if jukebox.sleepTimer != nil {
+		jukebox.sleepTimer.Stop()
+	}
+	jukebox.sleepTime = jukebox.clock().Add(duration)
+	jukebox.sleepTimer = time.AfterFunc(duration, func() {
+		jukebox.stopForLimit("sleep timer expired")
+	})
+}
+
+// CancelSleepTimer cancels the sleep timer. returns false if no sleep
+// timer was set.
+func (jukebox *Jukebox) CancelSleepTimer() bool {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if jukebox.sleepTimer == nil || !jukebox.sleepTimer.Stop() {
+		return false
+	}
+	jukebox.sleepTimer = nil
+	fmt.Println("sleep timer cancelled")
+	return true
+}
+
+// sleepSecondsLeft returns the number of seconds until the sleep timer
+// stops play, or 0 if no sleep timer is set.
+func (jukebox *Jukebox) sleepSecondsLeft() int {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if jukebox.sleepTimer == nil {
+		return 0
+	}
+	timeLeft := jukebox.sleepTime.Sub(jukebox.clock())
+	if timeLeft <= 0 {
+		return 0
+	}
+	// a timer that's part way into its last second still has it left
+	return int((timeLeft + time.Second - 1) / time.Second)
+}
+
+func (jukebox *Jukebox) isExitRequested() bool {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	return jukebox.exitRequested
 }
 
 func (jukebox *Jukebox) DisplayInfo() {
-	if len(jukebox.songList) > 0 {
-		maxIndex := len(jukebox.songList) - 1
-		if jukebox.songIndex+3 <= maxIndex {
-			fmt.Printf("----- songs on deck -----\n")
-			firstSong := jukebox.songList[jukebox.songIndex+1]
-			fmt.Printf("%s\n", firstSong.Fm.FileUid)
-			secondSong := jukebox.songList[jukebox.songIndex+2]
-			fmt.Printf("%s\n", secondSong.Fm.FileUid)
-			thirdSong := jukebox.songList[jukebox.songIndex+3]
-			fmt.Printf("%s\n", thirdSong.Fm.FileUid)
-			fmt.Printf("-------------------------\n")
+	currentSong := jukebox.currentSong()
+	if currentSong != nil {
+		position := formatSongPosition(jukebox.currentSongPosition())
+		if currentSong.DurationMs > 0 {
+			position += " / " + formatSongPosition(int(currentSong.DurationMs/1000))
+		}
+		if jukebox.isPausedNow() {
+			fmt.Printf("paused: %s (%s)\n", currentSong.Fm.FileUid, position)
+		} else {
+			fmt.Printf("now playing: %s (%s)\n", currentSong.Fm.FileUid, position)
+		}
+	}
+	songsOnDeck := jukebox.queue.Upcoming(3)
+	if len(songsOnDeck) > 0 {
+		fmt.Printf("----- songs on deck -----\n")
+		for _, song := range songsOnDeck {
+			fmt.Printf("%s\n", song.Fm.FileUid)
 		}
+		fmt.Printf("-------------------------\n")
 	}
 }
 
@@ -342,16 +1076,20 @@ func songFromFileName(fileName string) string {
 }
 
 func (jukebox *Jukebox) storeSongMetadata(fsSong *SongMetadata) bool {
-	dbSong := jukebox.jukeboxDb.retrieveSong(fsSong.Fm.FileUid)
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
+	}
+	dbSong := jukeboxDb.retrieveSong(fsSong.Fm.FileUid)
 	if dbSong != nil {
 		if !fsSong.Equals(dbSong) {
-			return jukebox.jukeboxDb.updateSong(fsSong)
+			return jukeboxDb.updateSong(fsSong)
 		} else {
 			return true // no insert or update needed (already up-to-date)
 		}
 	} else {
 		// song is not in the database, insert it
-		return jukebox.jukeboxDb.insertSong(fsSong)
+		return jukeboxDb.insertSong(fsSong)
 	}
 }
 
@@ -360,7 +1098,8 @@ func (jukebox *Jukebox) storeSongPlaylist(fileName string, fileContents []byte)
 	err := json.Unmarshal(fileContents, &playlist)
 	if err == nil {
 		if len(playlist.Name) > 0 {
-			return jukebox.jukeboxDb.insertPlaylist(fileName, playlist.Name, "")
+			jukeboxDb := jukebox.metadataDb()
+			return jukeboxDb != nil && jukeboxDb.insertPlaylist(fileName, playlist.Name, "")
 		} else {
 			fmt.Printf("error: playlist name is missing\n")
 			return false
@@ -396,8 +1135,107 @@ func (jukebox *Jukebox) containerForSong(songUid string) string {
 	return jukebox.containerPrefix + strings.ToLower(artistLetter) + songContainerSuffix
 }
 
+// songUidFromTags returns the song part of a uid made from tags. the disc
+// (when there's more than one) and track numbers go in front of the title
+// so that songs with the same title on an album get different uids.
+func songUidFromTags(song string, tags *AudioTags) string {
+	songUid := EncodeTagValue(song)
+	if tags.TrackNumber > 0 {
+		songUid = fmt.Sprintf("%02d-%s", tags.TrackNumber, songUid)
+		if tags.DiscNumber > 1 {
+			songUid = fmt.Sprintf("%d-%s", tags.DiscNumber, songUid)
+		}
+	}
+	return songUid
+}
+
+// songForImport returns the metadata for a song file that's being imported.
+// the artist, album and song come from the file's tags, falling back on
+// the Artist--Album--Song file name convention for anything the tags
+// don't have. a file that follows the convention keeps its name as uid
+// (so it matches what earlier imports stored), otherwise the uid is made
+// from the tags. returns nil and the reason if the file can't be imported.
+func (jukebox *Jukebox) songForImport(fullPath string, fileName string) (*SongMetadata, string) {
+	_, extension := PathSplitExt(fullPath)
+	if len(extension) == 0 {
+		return nil, "no file extension"
+	}
+	fileSize := GetFileSize(fullPath)
+	if fileSize <= 0 {
+		return nil, "empty file"
+	}
+
+	tags := ReadAudioTags(fullPath)
+	if tags == nil {
+		tags = NewAudioTags()
+	}
+	fileArtist, fileAlbum, fileSong := componentsFromFileName(fileName)
+	artist := tags.SongArtist()
+	album := tags.Album
+	song := tags.Title
+
+	haveFileName := len(fileArtist) > 0 && len(fileAlbum) > 0 && len(fileSong) > 0
+
+	// songs named by the file name convention keep the file name as uid
+	objectName := fileName
+	if len(artist) > 0 || len(album) > 0 || len(song) > 0 {
+		if len(artist) == 0 {
+			artist = fileArtist
+		}
+		if len(album) == 0 {
+			album = fileAlbum
+		}
+		if len(song) == 0 {
+			song = fileSong
+		}
+		if len(EncodeTagValue(artist)) == 0 || len(EncodeTagValue(album)) == 0 ||
+			len(EncodeTagValue(song)) == 0 {
+			return nil, "no artist, album and song in tags or file name"
+		}
+		if !haveFileName {
+			objectName = EncodeTagValue(artist) + DoubleDashes + EncodeTagValue(album) +
+				DoubleDashes + songUidFromTags(song, tags) + strings.ToLower(extension)
+		}
+	} else {
+		if !haveFileName {
+			return nil, "no tags and not named Artist--Album--Song"
+		}
+		artist, album, song = fileArtist, fileAlbum, fileSong
+	}
+
+	fsSong := NewSongMetadata()
+	fsSong.Fm = NewFileMetadata()
+	fsSong.Fm.FileUid = objectName
+	fsSong.AlbumUid = EncodeArtistAlbum(artist, album)
+	fsSong.Fm.OriginFileSize = fileSize
+	mtime, errTime := PathGetMtime(fullPath)
+	if errTime == nil {
+		fsSong.Fm.FileTime = mtime.Format(time.RFC3339)
+	}
+	fsSong.ArtistName = artist
+	fsSong.SongName = song
+	fsSong.TrackNumber = tags.TrackNumber
+	fsSong.DiscNumber = tags.DiscNumber
+	fsSong.Year = tags.Year
+	fsSong.Genre = tags.Genre
+	if properties := ReadAudioProperties(fullPath); properties != nil {
+		fsSong.DurationMs = properties.DurationMs
+		fsSong.Bitrate = properties.Bitrate
+		fsSong.SampleRate = properties.SampleRate
+		fsSong.Channels = properties.Channels
+	}
+	md5Hash, errHash := Md5ForFile(fullPath)
+	if errHash == nil {
+		fsSong.Fm.Md5Hash = md5Hash
+	}
+	fsSong.Fm.ObjectName = objectName
+	fsSong.Fm.PadCharCount = 0
+	fsSong.Fm.ContainerName = jukebox.containerForSong(objectName)
+	return fsSong, ""
+}
+
 func (jukebox *Jukebox) ImportSongs() {
-	if jukebox.jukeboxDb != nil && jukebox.jukeboxDb.isOpen() {
+	if jukebox.metadataDb() != nil {
 		if !DirectoryExists(jukebox.songImportDir) {
 			fmt.Printf("error: %s directory doesn't exist\n", jukebox.songImportDir)
 			return
@@ -406,8 +1244,28 @@ func (jukebox *Jukebox) ImportSongs() {
 		if err != nil {
 			return
 		}
-		numEntries := float32(len(dirListing))
-		progressbarChars := 0.0
+		if len(dirListing) == 0 {
+			fmt.Println("no songs found")
+			return
+		}
+		if importCount, _ := jukebox.importSongFiles(dirListing); importCount > 0 {
+			jukebox.UploadMetadataDb()
+		}
+	}
+}
+
+// importSongFiles imports the given files from the song import directory
+// and returns the number of songs that were stored along with the files
+// that failed to import (files that were skipped aren't failures). the
+// metadata DB isn't uploaded, that's left to the caller.
+func (jukebox *Jukebox) importSongFiles(fileNames []string) (int, []string) {
+	if jukebox.metadataDb() == nil {
+		return 0, fileNames
+	}
+	importCount := 0
+	failedFiles := []string{}
+	if len(fileNames) > 0 {
+		numEntries := float32(len(fileNames))
 		progressbarWidth := 40
 		progressCharsPerIteration := float32(progressbarWidth) / numEntries
 		progressbarChar := "#"
@@ -419,106 +1277,31 @@ func (jukebox *Jukebox) ImportSongs() {
 			fmt.Printf(strings.Repeat("\b", progressbarWidth+1)) // return to start of line, after '['
 		}
 
-		cumulativeUploadTime := float64(0)
-		cumulativeUploadBytes := 0
-		fileImportCount := 0
-
-		for _, listingEntry := range dirListing {
-			fullPath := PathJoin(jukebox.songImportDir, listingEntry)
-			// ignore it if it's not a file
-			if FileExists(fullPath) {
-				fileName := listingEntry
-				_, extension := PathSplitExt(fullPath)
-				if len(extension) > 0 {
-					fileSize := GetFileSize(fullPath)
-					artist := artistFromFileName(fileName)
-					album := albumFromFileName(fileName)
-					song := songFromFileName(fileName)
-					if fileSize > 0 && len(artist) > 0 && len(album) > 0 && len(song) > 0 {
-						objectName := fileName
-						fsSong := NewSongMetadata()
-						fsSong.Fm = NewFileMetadata()
-						fsSong.Fm.FileUid = objectName
-						fsSong.AlbumUid = ""
-						fsSong.Fm.OriginFileSize = fileSize
-						mtime, errTime := PathGetMtime(fullPath)
-						if errTime == nil {
-							fsSong.Fm.FileTime = mtime.Format(time.RFC3339)
-						}
-						fsSong.ArtistName = artist
-						fsSong.SongName = song
-						md5Hash, errHash := Md5ForFile(fullPath)
-						if errHash == nil {
-							fsSong.Fm.Md5Hash = md5Hash
-						}
-						fsSong.Fm.ObjectName = objectName
-						fsSong.Fm.PadCharCount = 0
-
-						fsSong.Fm.ContainerName = jukebox.containerForSong(fileName)
-
-						// read file contents
-						fileRead := false
-
-						fileContents, errFile := FileReadAllBytes(fullPath)
-						if errFile == nil {
-							fileRead = true
-						} else {
-							fmt.Printf("error: unable to read file %s\n", fullPath)
-						}
-
-						if fileRead && fileContents != nil {
-							// now that we have the data that will be stored, set the file size for
-							// what's being stored
-							fsSong.Fm.StoredFileSize = int64(len(fileContents))
-							startUploadTime := time.Now()
-
-							// store song file to storage system
-							containerName := jukebox.containerPrefix + fsSong.Fm.ContainerName
-							if jukebox.storageSystem.PutObject(containerName,
-								fsSong.Fm.ObjectName,
-								fileContents,
-								nil) {
-								uploadElapsedTime := time.Since(startUploadTime)
-								cumulativeUploadTime += uploadElapsedTime.Seconds()
-								cumulativeUploadBytes += len(fileContents)
-
-								// store song metadata in local database
-								if !jukebox.storeSongMetadata(fsSong) {
-									// we stored the song to the storage system, but were unable to store
-									// the metadata in the local database. we need to delete the song
-									// from the storage system since we won't have any way to access it
-									// since we can't store the song metadata locally.
-									fmt.Printf("unable to store metadata, deleting obj '%s'", fsSong.Fm.ObjectName)
-									jukebox.storageSystem.DeleteObject(containerName,
-										fsSong.Fm.ObjectName)
-								} else {
-									fileImportCount += 1
-								}
-							} else {
-								fmt.Printf("error: unable to upload '%s' to '%s'\n",
-									fsSong.Fm.ObjectName,
-									fsSong.Fm.ContainerName)
-							}
-						}
-					}
-				}
-
-				if !jukebox.debugPrint {
-					progressbarChars += float64(progressCharsPerIteration)
-					if int(progressbarChars) > barChars {
-						numNewChars := int(progressbarChars) - barChars
-						if numNewChars > 0 {
-							// update progress bar
-							for j := 0; j < numNewChars; j++ {
-								fmt.Print(progressbarChar)
-							}
-							barChars += numNewChars
-						}
-					}
+		numberWorkers := 1
+		batchSize := 1
+		afterImport := AfterImportKeep
+		if jukebox.jukeboxOptions != nil {
+			numberWorkers = jukebox.jukeboxOptions.ImportWorkers
+			batchSize = jukebox.jukeboxOptions.ImportBatchSize
+			afterImport = jukebox.jukeboxOptions.AfterImport
+		}
+		importer := NewSongImporter(jukebox, numberWorkers, batchSize)
+		importer.afterImport = afterImport
+		if !jukebox.debugPrint {
+			importer.onFileDone = func(filesDone int) {
+				progressbarChars := int(float32(filesDone) * progressCharsPerIteration)
+				if progressbarChars > barChars {
+					// update progress bar
+					fmt.Print(strings.Repeat(progressbarChar, progressbarChars-barChars))
+					barChars = progressbarChars
 				}
 			}
 		}
 
+		startImportTime := time.Now()
+		importer.Import(fileNames)
+		importElapsedTime := time.Since(startImportTime)
+
 		if !jukebox.debugPrint {
 			// if we haven't filled up the progress bar, fill it now
 			if barChars < progressbarWidth {
@@ -530,50 +1313,65 @@ func (jukebox *Jukebox) ImportSongs() {
 			fmt.Printf("\n")
 		}
 
-		if fileImportCount > 0 {
-			jukebox.UploadMetadataDb()
+		for _, skippedFile := range importer.SkippedFiles {
+			fmt.Printf("skipped %s\n", skippedFile)
 		}
 
-		fmt.Printf("%d song files imported\n", fileImportCount)
+		importCount = importer.ImportCount()
+		fmt.Printf("%d new, %d changed, %d unchanged, %d skipped, %d failed song files\n",
+			importer.NewCount,
+			importer.ChangedCount,
+			importer.UnchangedCount,
+			len(importer.SkippedFiles),
+			len(importer.FailedFiles))
 
-		if cumulativeUploadTime > 0 {
-			cumulativeUploadKb := cumulativeUploadBytes / 1000.0
+		if importer.UploadBytes > 0 && importElapsedTime.Seconds() > 0 {
+			uploadKb := importer.UploadBytes / 1000.0
 			fmt.Printf("average upload throughput = %f KB/sec\n",
-				float64(cumulativeUploadKb)/cumulativeUploadTime)
+				float64(uploadKb)/importElapsedTime.Seconds())
 		}
+		failedFiles = importer.FailedFiles
 	}
+	return importCount, failedFiles
 }
 
 func (jukebox *Jukebox) songPathInPlaylist(song *SongMetadata) string {
 	return PathJoin(jukebox.songPlayDir, song.Fm.FileUid)
 }
 
-func (jukebox *Jukebox) checkFileIntegrity(song *SongMetadata) bool {
+// checkFileIntegrity compares the MD5 hash of filePath with the song's
+// metadata. the check is made when data integrity checks are turned on,
+// or when forceCheck is set and the song has a hash (e.g., for a song that
+// has just been downloaded).
+func (jukebox *Jukebox) checkFileIntegrity(song *SongMetadata, filePath string, forceCheck bool) bool {
+	if song.Fm == nil {
+		fmt.Println("error: song has no file metadata, unable to check integrity")
+		return false
+	}
+
 	fileIntegrityPassed := true
 
-	if jukebox.jukeboxOptions != nil && jukebox.jukeboxOptions.CheckDataIntegrity {
-		filePath := jukebox.songPathInPlaylist(song)
+	checkDataIntegrity := jukebox.jukeboxOptions != nil && jukebox.jukeboxOptions.CheckDataIntegrity
+	if checkDataIntegrity || (forceCheck && len(song.Fm.Md5Hash) > 0) {
 		if FileExists(filePath) {
 			if jukebox.debugPrint {
 				fmt.Printf("checking integrity for %s\n", song.Fm.FileUid)
 			}
 
-			if song.Fm != nil {
-				playlistMd5, err := Md5ForFile(filePath)
-				if err != nil {
-					fmt.Printf("error: unable to calculate MD5 hash for file '%s'\n", filePath)
-					fmt.Printf("error: %v\n", err)
-					fileIntegrityPassed = false
-				} else {
-					if playlistMd5 == song.Fm.Md5Hash {
-						if jukebox.debugPrint {
-							fmt.Println("integrity check SUCCESS")
-						}
-						fileIntegrityPassed = true
-					} else {
-						fmt.Printf("file integrity check failed: %s\n", song.Fm.FileUid)
-						fileIntegrityPassed = false
+			playlistMd5, err := Md5ForFile(filePath)
+			if err != nil {
+				fmt.Printf("error: unable to calculate MD5 hash for file '%s'\n", filePath)
+				fmt.Printf("error: %v\n", err)
+				fileIntegrityPassed = false
+			} else {
+				if playlistMd5 == song.Fm.Md5Hash {
+					if jukebox.debugPrint {
+						fmt.Println("integrity check SUCCESS")
 					}
+					fileIntegrityPassed = true
+				} else {
+					fmt.Printf("file integrity check failed: %s\n", song.Fm.FileUid)
+					fileIntegrityPassed = false
 				}
 			}
 		} else {
@@ -581,357 +1379,678 @@ func (jukebox *Jukebox) checkFileIntegrity(song *SongMetadata) bool {
 			fmt.Println("file doesn't exist")
 			fileIntegrityPassed = false
 		}
-	} else {
-		if jukebox.debugPrint {
-			fmt.Println("file integrity bypassed, no jukebox options or check integrity not turned on")
+	} else {
+		if jukebox.debugPrint {
+			fmt.Println("file integrity bypassed, no jukebox options or check integrity not turned on")
+		}
+	}
+
+	return fileIntegrityPassed
+}
+
+func (jukebox *Jukebox) batchDownloadStart() {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	jukebox.cumulativeDownloadBytes = 0
+	jukebox.cumulativeDownloadTime = 0
+}
+
+func (jukebox *Jukebox) batchDownloadComplete() {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if !jukebox.exitRequested {
+		if jukebox.cumulativeDownloadTime > 0 {
+			cumulativeDownloadKb := jukebox.cumulativeDownloadBytes / 1000.0
+			fmt.Printf("average download throughput = %d KB/sec\n",
+				int64(float64(cumulativeDownloadKb)/jukebox.cumulativeDownloadTime))
+		}
+		jukebox.cumulativeDownloadBytes = 0
+		jukebox.cumulativeDownloadTime = 0
+	}
+}
+
+// retrieveFile downloads the object for fm to <dirPath>/<FileUid>.download.
+// if a partial download is already there, only the remaining bytes are
+// requested and appended to it. returns the number of bytes retrieved
+// by this call.
+func (jukebox *Jukebox) retrieveFile(fm *FileMetadata, dirPath string) int64 {
+	var bytesRetrieved int64
+	bytesRetrieved = 0
+
+	if jukebox.storageSystem != nil && fm != nil && len(dirPath) > 0 {
+		localFilePath := PathJoin(dirPath, fm.FileUid) + downloadExtension
+		var offset int64
+		offset = 0
+		if FileExists(localFilePath) {
+			offset = GetFileSize(localFilePath)
+			if fm.StoredFileSize > 0 && offset > fm.StoredFileSize {
+				// can't be a partial download of this object
+				DeleteFile(localFilePath)
+				offset = 0
+			}
+		}
+
+		if fm.StoredFileSize > 0 && offset == fm.StoredFileSize {
+			// nothing left to download
+			return 0
+		}
+
+		if offset > 0 && jukebox.debugPrint {
+			fmt.Printf("resuming download of '%s' at byte %d\n", fm.FileUid, offset)
+		}
+
+		flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if offset == 0 {
+			flags |= os.O_TRUNC
+		}
+		localFile, err := os.OpenFile(localFilePath, flags, 0644)
+		if err != nil {
+			fmt.Printf("error: unable to create file '%s'\n", localFilePath)
+			fmt.Printf("error: %v\n", err)
+			return 0
+		}
+		bytesRetrieved, err = jukebox.storageSystem.GetObjectRange(jukebox.ctx,
+			jukebox.containerPrefix+fm.ContainerName,
+			fm.ObjectName,
+			offset,
+			0,
+			localFile)
+		if localFile.Close() != nil && err == nil {
+			err = fmt.Errorf("unable to close file '%s'", localFilePath)
+		}
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				fmt.Printf("error: unable to retrieve '%s'\n", fm.ObjectName)
+				fmt.Printf("error: %v\n", err)
+			}
+			// keep what we have so the download can be resumed, unless
+			// the object is gone or doesn't match what we have
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidArgument) {
+				DeleteFile(localFilePath)
+			}
+			bytesRetrieved = 0
+		}
+	}
+
+	return bytesRetrieved
+}
+
+// downloadSong retrieves the song into the song-play directory. the song
+// is only given its final name once its size and MD5 hash match the
+// song's metadata, so a song file that exists is always complete.
+func (jukebox *Jukebox) downloadSong(song *SongMetadata) bool {
+	if jukebox.isExitRequested() {
+		return false
+	}
+
+	if song != nil {
+		filePath := jukebox.songPathInPlaylist(song)
+		downloadFilePath := filePath + downloadExtension
+		jukebox.publishEvent(NewJukeboxEvent(EventDownloadStarted, song))
+
+		// no need to download the song if we still have a copy of it
+		if jukebox.songCache.Retrieve(song.Fm, downloadFilePath) {
+			if RenameFile(downloadFilePath, filePath) {
+				if jukebox.debugPrint {
+					fmt.Printf("song cache hit: %s\n", song.Fm.FileUid)
+				}
+				jukebox.publishEvent(NewJukeboxEvent(EventDownloadComplete, song))
+				return true
+			}
+		}
+
+		downloadStartTime := time.Now()
+		songBytesRetrieved := jukebox.retrieveFile(song.Fm, jukebox.songPlayDir)
+		if jukebox.isExitRequested() {
+			return false
+		}
+
+		if jukebox.debugPrint {
+			fmt.Printf("bytes retrieved: %d\n", songBytesRetrieved)
+		}
+
+		if songBytesRetrieved > 0 {
+			downloadElapsedTime := time.Since(downloadStartTime)
+			jukebox.stateMutex.Lock()
+			jukebox.cumulativeDownloadTime += downloadElapsedTime.Seconds()
+			jukebox.cumulativeDownloadBytes += songBytesRetrieved
+			jukebox.stateMutex.Unlock()
+		}
+
+		if !FileExists(downloadFilePath) {
+			return false
+		}
+
+		downloadedFileSize := GetFileSize(downloadFilePath)
+		if downloadedFileSize == 0 {
+			return false
+		}
+
+		// verify that we have the same length that has been stored
+		if song.Fm.StoredFileSize > 0 && downloadedFileSize != song.Fm.StoredFileSize {
+			fmt.Printf("error: file size check failed for '%s'\n", filePath)
+			if downloadedFileSize > song.Fm.StoredFileSize {
+				DeleteFile(downloadFilePath)
+				jukebox.publishIntegrityFailed(song, "file size mismatch")
+			}
+			return false
+		}
+
+		// the hash is always checked, since a download of the right size can
+		// still be corrupt (or a resumed download may not match the object)
+		if jukebox.checkFileIntegrity(song, downloadFilePath, true) {
+			if RenameFile(downloadFilePath, filePath) {
+				jukebox.songCache.Store(song.Fm, filePath)
+				jukebox.publishEvent(NewJukeboxEvent(EventDownloadComplete, song))
+				return true
+			}
+			fmt.Printf("error: unable to rename '%s' to '%s'\n", downloadFilePath, filePath)
+		} else {
+			// we retrieved the file, but it failed our integrity check
+			DeleteFile(downloadFilePath)
+			jukebox.publishIntegrityFailed(song, "md5 hash mismatch")
+		}
+	}
+
+	return false
+}
+
+func (jukebox *Jukebox) publishIntegrityFailed(song *SongMetadata, message string) {
+	event := NewJukeboxEvent(EventIntegrityFailed, song)
+	event.Message = message
+	jukebox.publishEvent(event)
+}
+
+// canStreamSongs reports whether songs can be played while they're
+// being retrieved instead of waiting for them to be downloaded.
+func (jukebox *Jukebox) canStreamSongs() bool {
+	audioPlayer := jukebox.currentAudioPlayer()
+	return jukebox.jukeboxOptions != nil &&
+		jukebox.jukeboxOptions.StreamSongs &&
+		audioPlayer != nil &&
+		audioPlayer.ReadsStdin()
+}
+
+// streamSong plays the song while it's being retrieved by piping the
+// object straight into the audio player. the MD5 hash is computed as the
+// song streams, and a verified song is added to the song cache. returns
+// false if the audio player couldn't be started.
+func (jukebox *Jukebox) streamSong(song *SongMetadata) bool {
+	audioPlayer := jukebox.currentAudioPlayer()
+	playerInput, streamOutput := io.Pipe()
+
+	// keep a copy of what's streamed so that it can go in the song cache.
+	// it's kept apart from the .download file in case the song is being
+	// downloaded at the same time.
+	streamFilePath := jukebox.songPathInPlaylist(song) + streamExtension
+	hasher := md5.New()
+	writers := []io.Writer{streamOutput, hasher}
+	streamFile, errCreate := os.Create(streamFilePath)
+	if errCreate == nil {
+		writers = append(writers, streamFile)
+	}
+
+	var bytesStreamed int64
+	var errStream error
+	streamDone := make(chan bool, 1)
+	go func() {
+		bytesStreamed, errStream = jukebox.storageSystem.GetObjectRange(jukebox.ctx,
+			jukebox.containerPrefix+song.Fm.ContainerName,
+			song.Fm.ObjectName,
+			0,
+			0,
+			io.MultiWriter(writers...))
+		streamOutput.CloseWithError(errStream)
+		streamDone <- true
+	}()
+
+	errPlay := audioPlayer.PlayStream(playerInput)
+
+	// the player is done, so whatever is still being streamed has nowhere to go
+	playerInput.CloseWithError(io.ErrClosedPipe)
+	<-streamDone
+	if streamFile != nil {
+		streamFile.Close()
+		defer DeleteFile(streamFilePath)
+	}
+
+	if errPlay != nil {
+		fmt.Printf("error: unable to start audio player\n")
+		fmt.Printf("error: %v\n", errPlay)
+		return false
+	}
+
+	if errStream == nil {
+		streamedMd5 := fmt.Sprintf("%x", hasher.Sum(nil))
+		if (song.Fm.StoredFileSize > 0 && bytesStreamed != song.Fm.StoredFileSize) ||
+			(len(song.Fm.Md5Hash) > 0 && streamedMd5 != song.Fm.Md5Hash) {
+			fmt.Printf("file integrity check failed: %s\n", song.Fm.FileUid)
+			jukebox.publishIntegrityFailed(song, "streamed song doesn't match")
+		} else if streamFile != nil {
+			jukebox.songCache.Store(song.Fm, streamFilePath)
+		}
+	} else if !errors.Is(errStream, context.Canceled) && !errors.Is(errStream, io.ErrClosedPipe) {
+		// a closed pipe just means the audio player was stopped
+		fmt.Printf("error: unable to stream '%s'\n", song.Fm.ObjectName)
+		fmt.Printf("error: %v\n", errStream)
+	}
+	return true
+}
+
+// playSong plays the song. returns false if the song couldn't be played.
+func (jukebox *Jukebox) playSong(song *SongMetadata) bool {
+	songFilePath := jukebox.songPathInPlaylist(song)
+	if jukebox.currentAudioPlayer() == nil {
+		jukebox.initAudioPlayer()
+	}
+
+	// the song may still be downloading, having been prefetched while the
+	// previous song played
+	if jukebox.songDownloader != nil {
+		jukebox.songDownloader.Wait(song.Fm.FileUid)
+	}
+
+	// a song being resumed part way through is played from a local copy
+	if !FileExists(songFilePath) && jukebox.canStreamSongs() && jukebox.currentSongPosition() == 0 {
+		fmt.Printf("streaming %s\n", song.Fm.FileUid)
+		jukebox.startSongPlay(false)
+		jukebox.publishEvent(NewJukeboxEvent(EventSongStarted, song))
+		endPreview := jukebox.startPreview(jukebox.currentAudioPlayer(), 0, 0)
+		streamed := jukebox.streamSong(song)
+		endPreview()
+		if streamed {
+			jukebox.publishSongFinished(song)
+			return true
+		}
+		// fall back to playing a downloaded copy
+		jukebox.downloadSong(song)
+	} else if !FileExists(songFilePath) && !jukebox.isExitRequested() {
+		// the song wasn't prefetched (or the prefetch failed)
+		jukebox.downloadSong(song)
+	}
+
+	if FileExists(songFilePath) {
+		audioPlayer := jukebox.currentAudioPlayer()
+		previewStart := 0
+		if jukebox.jukeboxOptions.PreviewSeconds > 0 && audioPlayer.SupportsStartOffset() {
+			previewStart = jukebox.jukeboxOptions.PreviewOffset
+			jukebox.startPreviewAt(previewStart)
+		}
+		offsetSeconds := jukebox.startSongPlay(audioPlayer.SupportsStartOffset())
+		if offsetSeconds > 0 {
+			fmt.Printf("resuming %s at %s\n", song.Fm.FileUid, formatSongPosition(offsetSeconds))
+		} else {
+			fmt.Printf("playing %s\n", song.Fm.FileUid)
+		}
+		startedEvent := NewJukeboxEvent(EventSongStarted, song)
+		startedEvent.PositionSeconds = offsetSeconds
+		jukebox.publishEvent(startedEvent)
+		endPreview := jukebox.startPreview(audioPlayer, previewStart, offsetSeconds)
+		err := audioPlayer.PlayFrom(songFilePath, offsetSeconds)
+		played := true
+		if errors.Is(err, ErrPlaybackFailed) {
+			// the player started but couldn't play this song, so keep
+			// the player for the next one
+			fmt.Printf("error: unable to play song '%s'\n", song.Fm.FileUid)
+			fmt.Printf("error: %v\n", err)
+			played = false
+		} else if err != nil {
+			fmt.Printf("error: unable to start audio player\n")
+			fmt.Printf("error: %v\n", err)
+
+			// if the audio player failed or is not present, just sleep
+			// for the length of time that audio would be played
+			nullPlayer := NewNullAudioPlayer(jukebox.songPlayLengthSeconds)
+			jukebox.setAudioPlayer(nullPlayer)
+			nullPlayer.PlayFrom(songFilePath, offsetSeconds)
+		}
+		endPreview()
+
+		if !jukebox.isPausedNow() {
+			// delete the song file from the play list directory
+			DeleteFile(songFilePath)
+		}
+		jukebox.publishSongFinished(song)
+		return played
+	}
+
+	fmt.Printf("song file doesn't exist: '%s'\n", songFilePath)
+	FileAppendText("404.txt", songFilePath+"\n")
+	return false
+}
+
+// startPreviewAt starts a song that hasn't been played yet at the preview
+// offset.
+func (jukebox *Jukebox) startPreviewAt(previewStart int) {
+	jukebox.stateMutex.Lock()
+	defer jukebox.stateMutex.Unlock()
+	if jukebox.songSecondsOffset == 0 && jukebox.songStartTime.IsZero() {
+		jukebox.songSecondsOffset = previewStart
+	}
+}
+
+// startPreview limits the song about to be played to the preview seconds
+// (when previewing), starting from previewStart. the player is asked to
+// stop by itself if it can, but is stopped once the preview has played
+// in any case. the function returned is called when the song is done.
+func (jukebox *Jukebox) startPreview(audioPlayer AudioPlayer, previewStart int, offsetSeconds int) func() {
+	previewSeconds := jukebox.jukeboxOptions.PreviewSeconds
+	if previewSeconds <= 0 {
+		return func() {}
+	}
+	previewEnd := previewStart + previewSeconds
+	if durationPlayer, ok := audioPlayer.(DurationLimitedAudioPlayer); ok {
+		durationPlayer.SetPlayDuration(previewEnd - offsetSeconds)
+	}
+
+	done := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(previewCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if !jukebox.isPausedNow() && jukebox.currentSongPosition() >= previewEnd {
+					jukebox.stopAudioPlayer()
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// publishSongFinished is called when the audio player is done with the
+// song. a song that stopped because of a pause isn't finished.
+func (jukebox *Jukebox) publishSongFinished(song *SongMetadata) {
+	if !jukebox.isPausedNow() {
+		jukebox.publishEvent(NewJukeboxEvent(EventSongFinished, song))
+	}
+}
+
+// songsToPrefetch returns the upcoming songs in the queue (up to
+// FileCacheCount of them) that haven't been downloaded yet, in the
+// order that they'll be played.
+func (jukebox *Jukebox) songsToPrefetch() []*SongMetadata {
+	var dlSongs []*SongMetadata
+
+	currentSong := jukebox.currentSong()
+	fileCacheCount := jukebox.jukeboxOptions.FileCacheCount
+	for _, si := range jukebox.queue.Upcoming(fileCacheCount) {
+		if si == currentSong {
+			continue
+		}
+		if !FileExists(jukebox.songPathInPlaylist(si)) {
+			dlSongs = append(dlSongs, si)
 		}
 	}
 
-	return fileIntegrityPassed
+	return dlSongs
 }
 
-func (jukebox *Jukebox) batchDownloadStart() {
-	jukebox.cumulativeDownloadBytes = 0
-	jukebox.cumulativeDownloadTime = 0
+// downloadSongs tells the song downloader which songs should be in the
+// song-play directory next. it's called each time the position in the
+// queue changes.
+func (jukebox *Jukebox) downloadSongs() {
+	if jukebox.songDownloader != nil {
+		jukebox.songDownloader.Schedule(jukebox.songsToPrefetch())
+	}
 }
 
-func (jukebox *Jukebox) batchDownloadComplete() {
-	if !jukebox.exitRequested {
-		if jukebox.cumulativeDownloadTime > 0 {
-			cumulativeDownloadKb := jukebox.cumulativeDownloadBytes / 1000.0
-			fmt.Printf("average download throughput = %d KB/sec\n",
-				int64(float64(cumulativeDownloadKb)/jukebox.cumulativeDownloadTime))
+// clearSongPlayDir deletes the files in the song-play directory. partial
+// downloads of songs in the queue are kept so that they can be resumed.
+func (jukebox *Jukebox) clearSongPlayDir() {
+	partialDownloads := make(map[string]bool)
+	for _, song := range jukebox.queue.Songs() {
+		partialDownloads[song.Fm.FileUid+downloadExtension] = true
+	}
+
+	dirListing, err := ListFilesInDirectory(jukebox.songPlayDir)
+	if err != nil {
+		fmt.Printf("error: unable to list files in %s\n", jukebox.songPlayDir)
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+
+	for _, fileName := range dirListing {
+		if !partialDownloads[fileName] {
+			DeleteFile(PathJoin(jukebox.songPlayDir, fileName))
 		}
-		jukebox.cumulativeDownloadBytes = 0
-		jukebox.cumulativeDownloadTime = 0
 	}
 }
 
-func (jukebox *Jukebox) retrieveFile(fm *FileMetadata, dirPath string) int64 {
-	var bytesRetrieved int64
-	bytesRetrieved = 0
+// shuffleSongs returns the songs in the order of the shuffle mode.
+func (jukebox *Jukebox) shuffleSongs(songList []*SongMetadata) []*SongMetadata {
+	shuffler := NewShuffler(jukebox.jukeboxOptions.ShuffleMode, jukebox.jukeboxOptions.ShuffleSeed)
+	shuffler.SetArtistGap(jukebox.jukeboxOptions.ArtistGap)
+	fmt.Printf("%s shuffle (seed %d)\n", jukebox.jukeboxOptions.ShuffleMode, shuffler.Seed())
+	return shuffler.Shuffle(songList)
+}
 
-	if jukebox.storageSystem != nil && fm != nil && len(dirPath) > 0 {
-		localFilePath := PathJoin(dirPath, fm.FileUid)
-		bytesRetrieved = jukebox.storageSystem.GetObject(jukebox.containerPrefix+fm.ContainerName, fm.ObjectName, localFilePath)
+// PlaySongs plays the songs in the library (optionally limited to an
+// artist and album). returns false if they couldn't be played.
+func (jukebox *Jukebox) PlaySongs(shuffle bool, artist string, album string) bool {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
 	}
+	songList := jukeboxDb.retrieveSongs(artist, album)
+	playSource := NewPlaySource(SourceSongs)
+	playSource.Artist = artist
+	playSource.Album = album
+	return jukebox.playSongList(songList, shuffle, playSource)
+}
 
-	return bytesRetrieved
+func (jukebox *Jukebox) playSongList(songList []*SongMetadata, shuffle bool, playSource *PlaySource) bool {
+	if songList != nil {
+
+		if len(songList) == 0 {
+			fmt.Println("no songs in jukebox")
+			return false
+		}
+
+		if shuffle {
+			songList = jukebox.shuffleSongs(songList)
+			playSource.ShuffleMode = jukebox.jukeboxOptions.ShuffleMode
+		}
+		jukebox.queue.SetRepeatMode(jukebox.jukeboxOptions.RepeatMode)
+		jukebox.queue.Reset(songList)
+		return jukebox.playQueue(playSource)
+	}
+	return false
 }
 
-func (jukebox *Jukebox) downloadSong(song *SongMetadata) bool {
-	if jukebox.exitRequested {
+// playQueue plays the songs in the queue until play is stopped or the
+// queue runs out. returns false if play couldn't be started.
+func (jukebox *Jukebox) playQueue(playSource *PlaySource) bool {
+	if pid := RunningJukeboxPid(jukeboxPidFileName); pid > 0 && pid != os.Getpid() {
+		fmt.Printf("error: another jukebox is already running (pid %d)\n", pid)
 		return false
 	}
 
-	if song != nil {
-		filePath := jukebox.songPathInPlaylist(song)
-		downloadStartTime := time.Now()
-		songBytesRetrieved := jukebox.retrieveFile(song.Fm, jukebox.songPlayDir)
-		if jukebox.exitRequested {
+	// does play list directory exist?
+	if !DirectoryExists(jukebox.songPlayDir) {
+		if jukebox.debugPrint {
+			fmt.Println("song-play directory does not exist, creating it")
+		}
+		err := os.Mkdir(jukebox.songPlayDir, os.ModePerm)
+		if err != nil {
+			fmt.Printf("error: unable to create directory %s\n", jukebox.songPlayDir)
 			return false
 		}
-
+	} else {
+		// play list directory exists, delete any files in it
 		if jukebox.debugPrint {
-			fmt.Printf("bytes retrieved: %d\n", songBytesRetrieved)
+			fmt.Println("deleting existing files in song-play directory")
 		}
+		jukebox.clearSongPlayDir()
+	}
 
-		if songBytesRetrieved > 0 {
-			downloadElapsedTime := time.Since(downloadStartTime)
-			jukebox.cumulativeDownloadTime += downloadElapsedTime.Seconds()
-			jukebox.cumulativeDownloadBytes += songBytesRetrieved
-
-			// are we checking data integrity?
-			// if so, verify that the storage system retrieved the same length that has been stored
-			if jukebox.jukeboxOptions != nil && jukebox.jukeboxOptions.CheckDataIntegrity {
-				if jukebox.debugPrint {
-					fmt.Println("verifying data integrity")
-				}
+	jukebox.installSignalHandlers()
 
-				if songBytesRetrieved != song.Fm.StoredFileSize {
-					fmt.Printf("error: file size check failed for '%s'\n", filePath)
-					return false
-				}
-			}
+	jukebox.initAudioPlayer()
 
-			if jukebox.checkFileIntegrity(song) {
-				return true
-			} else {
-				// we retrieved the file, but it failed our integrity check
-				// if file exists, remove it
-				if FileExists(filePath) {
-					DeleteFile(filePath)
-				}
-			}
-		}
+	streamSongs := jukebox.canStreamSongs()
+	if !streamSongs {
+		fmt.Println("downloading first song...")
 	}
 
-	return false
-}
+	// when streaming, there's no need to wait for the first song
+	if streamSongs || jukebox.downloadSong(jukebox.currentSong()) {
+		fmt.Println("first song ready. starting playing now.")
 
-func (jukebox *Jukebox) playSong(song *SongMetadata) {
-	songFilePath := jukebox.songPathInPlaylist(song)
+		jukebox.songDownloader = NewSongDownloader(jukebox, jukebox.jukeboxOptions.DownloadWorkers)
+		jukebox.songDownloader.Start()
+		defer jukebox.songDownloader.Stop()
 
-	if FileExists(songFilePath) {
-		fmt.Printf("playing %s\n", song.Fm.FileUid)
-		if len(jukebox.audioPlayerExeFileName) > 0 {
-			var args []string
-			if len(jukebox.audioPlayerCommandArgs) > 0 {
-				vecExtraArgs := strings.Split(jukebox.audioPlayerCommandArgs, " ")
-				for _, extraArg := range vecExtraArgs {
-					args = append(args, extraArg)
-				}
-			}
-			args = append(args, songFilePath)
-
-			exitCode := -1
-			startedAudioPlayer := false
-			var cmd *exec.Cmd
-			playerExe := jukebox.audioPlayerExeFileName
-
-			numArgs := len(args)
-			if numArgs == 1 {
-				cmd = exec.Command(playerExe, args[0])
-			} else if numArgs == 2 {
-				cmd = exec.Command(playerExe, args[0], args[1])
-			} else if numArgs == 3 {
-				cmd = exec.Command(playerExe, args[0], args[1], args[2])
-			} else if numArgs == 4 {
-				cmd = exec.Command(playerExe, args[0], args[1], args[2], args[3])
-			} else if numArgs == 5 {
-				cmd = exec.Command(playerExe,
-					args[0],
-					args[1],
-					args[2],
-					args[3],
-					args[4])
-			} else if numArgs == 6 {
-				cmd = exec.Command(playerExe,
-					args[0],
-					args[1],
-					args[2],
-					args[3],
-					args[4],
-					args[5])
-			} else if numArgs == 7 {
-				cmd = exec.Command(playerExe,
-					args[0],
-					args[1],
-					args[2],
-					args[3],
-					args[4],
-					args[5],
-					args[6])
-			} else if numArgs == 8 {
-				cmd = exec.Command(playerExe,
-					args[0],
-					args[1],
-					args[2],
-					args[3],
-					args[4],
-					args[5],
-					args[6],
-					args[7])
-			} else {
-				fmt.Printf("error: too many arguments specified for audio player (max 8)\n")
-				return
-			}
+		WritePidFile(jukeboxPidFileName)
+		defer DeleteFile(jukeboxPidFileName)
 
-			err := cmd.Start() // will not wait for process to run and exit
-			if err == nil {
-				startedAudioPlayer = true
-				jukebox.audioPlayerProcess = cmd.Process
-				errWait := cmd.Wait()
-				if errWait != nil {
-					if jukebox.debugPrint {
-						fmt.Printf("error: unable to wait for audio player process\n")
-						fmt.Printf("error: %v\n", errWait)
-					}
-				}
-				jukebox.audioPlayerProcess = nil
-			} else {
-				fmt.Printf("error: unable to start audio player\n")
-				fmt.Printf("error: %v\n", err)
-				jukebox.audioPlayerExeFileName = ""
-				jukebox.audioPlayerCommandArgs = ""
+		controlSocket := jukebox.jukeboxOptions.ControlSocket
+		if len(controlSocket) > 0 {
+			controlServer := NewControlServer(jukebox, controlSocket)
+			if controlServer.Start() {
+				defer controlServer.Stop()
 			}
+		}
 
-			// if the audio player failed or is not present, just sleep
-			// for the length of time that audio would be played
-			if !startedAudioPlayer && exitCode != 0 {
-				TimeSleepSeconds(jukebox.songPlayLengthSeconds)
+		httpAddress := jukebox.jukeboxOptions.HttpAddress
+		if len(httpAddress) > 0 {
+			httpServer := NewHttpServer(jukebox, httpAddress)
+			if httpServer.Start() {
+				defer httpServer.Shutdown(httpShutdownTimeout)
 			}
-		} else {
-			// we don't know about an audio player, so simulate a
-			// song being played by sleeping
-			TimeSleepSeconds(jukebox.songPlayLengthSeconds)
 		}
 
-		if !jukebox.isPaused {
-			// delete the song file from the play list directory
-			DeleteFile(songFilePath)
+		if jukebox.keysFromTerminal {
+			keyboardControl := NewKeyboardControl(jukebox, os.Stdin, os.Stdout)
+			if keyboardControl.Start() {
+				defer keyboardControl.Stop()
+			}
 		}
-	} else {
-		fmt.Printf("song file doesn't exist: '%s'\n", songFilePath)
-		FileAppendText("404.txt", songFilePath+"\n")
-	}
-}
-
-func (jukebox *Jukebox) downloadSongs() {
-	// scan the play list directory to see if we need to download more songs
-	dirListing, err := ListFilesInDirectory(jukebox.songPlayDir)
-	if err != nil {
-		// log error
-		return
-	}
-
-	var dlSongs []*SongMetadata
 
-	songFileCount := 0
-	for _, fileName := range dirListing {
-		extension := filepath.Ext(fileName)
-		if len(extension) > 0 && extension != downloadExtension {
-			songFileCount += 1
-		}
-	}
+		jukebox.startStopTimer()
+		defer jukebox.stopTimers()
 
-	fileCacheCount := jukebox.jukeboxOptions.FileCacheCount
+		jukebox.setPlaySource(playSource)
+		defer jukebox.endSession()
 
-	if songFileCount < fileCacheCount {
-		// start looking at the next song in the list
-		checkIndex := jukebox.songIndex + 1
-		for j := 0; j < jukebox.numberSongs; j++ {
-			if checkIndex >= jukebox.numberSongs {
-				checkIndex = 0
+		for !jukebox.isExitRequested() {
+			song, generation := jukebox.queue.Current()
+			if song == nil {
+				fmt.Println("no more songs in queue")
+				break
 			}
-			if checkIndex != jukebox.songIndex {
-				si := jukebox.songList[checkIndex]
-				filePath := jukebox.songPathInPlaylist(si)
-				if !FileExists(filePath) {
-					dlSongs = append(dlSongs, si)
-					if len(dlSongs) >= fileCacheCount {
-						break
-					}
+			songWasPlayed := false
+			if !jukebox.isPausedNow() {
+				jukebox.downloadSongs()
+				songWasPlayed = jukebox.playSong(song)
+			}
+			if jukebox.isExitRequested() {
+				break
+			}
+			if !jukebox.isPausedNow() {
+				// a song that was skipped or gone back from moved the
+				// queue on already, and doesn't count as played
+				if jukebox.queue.Generation() != generation {
+					songWasPlayed = false
+				}
+				jukebox.queue.SongFinished(generation)
+				jukebox.resetSongPosition()
+				if songWasPlayed {
+					jukebox.songPlayed()
 				}
+			} else {
+				time.Sleep(1 * time.Second)
 			}
-			checkIndex += 1
 		}
+	} else {
+		fmt.Println("error: unable to download songs")
+		return false
 	}
-
-	if len(dlSongs) > 0 {
-		go downloadSongs(jukebox, dlSongs)
-	}
+	return true
 }
 
-func downloadSongs(jukebox *Jukebox, dlSongs []*SongMetadata) {
-	downloader := NewSongDownloader(jukebox, dlSongs)
-	downloader.run()
+// endSession saves the session once play has ended, so that it can be
+// resumed. there's nothing to resume when the queue has run out.
+func (jukebox *Jukebox) endSession() {
+	if jukebox.currentSong() != nil {
+		jukebox.saveSession()
+	} else {
+		DeleteFile(sessionFileName)
+	}
+	jukebox.setPlaySource(nil)
 }
 
-func (jukebox *Jukebox) PlaySongs(shuffle bool, artist string, album string) {
-	songList := jukebox.jukeboxDb.retrieveSongs(artist, album)
-	jukebox.playSongList(songList, shuffle)
+// LibrarySongs returns the songs in the library (optionally limited to
+// an artist and album) ordered by their uid.
+func (jukebox *Jukebox) LibrarySongs(artist string, album string) []*SongInfo {
+	songs := []*SongInfo{}
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return songs
+	}
+	for _, song := range jukeboxDb.retrieveSongs(artist, album) {
+		songs = append(songs, NewSongInfo(song))
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		return songs[i].Uid < songs[j].Uid
+	})
+	return songs
 }
 
-func (jukebox *Jukebox) playSongList(songList []*SongMetadata, shuffle bool) {
-	jukebox.songList = songList
-	if jukebox.songList != nil {
-		jukebox.numberSongs = len(jukebox.songList)
-
-		if jukebox.numberSongs == 0 {
-			fmt.Println("no songs in jukebox")
-			return
-		}
-
-		// does play list directory exist?
-		if !DirectoryExists(jukebox.songPlayDir) {
-			if jukebox.debugPrint {
-				fmt.Println("song-play directory does not exist, creating it")
-			}
-			err := os.Mkdir(jukebox.songPlayDir, os.ModePerm)
-			if err != nil {
-				fmt.Printf("error: unable to create directory %s\n", jukebox.songPlayDir)
-				return
-			}
+// LibraryArtists returns the artists in the library ordered by name.
+func (jukebox *Jukebox) LibraryArtists() []*LibraryArtist {
+	artists := []*LibraryArtist{}
+	artistIndex := make(map[string]int)
+	for _, song := range jukebox.LibrarySongs("", "") {
+		if i, ok := artistIndex[song.Artist]; ok {
+			artists[i].Songs += 1
 		} else {
-			// play list directory exists, delete any files in it
-			if jukebox.debugPrint {
-				fmt.Println("deleting existing files in song-play directory")
-			}
-			DeleteFilesInDirectory(jukebox.songPlayDir)
-		}
-
-		jukebox.songIndex = 0
-		jukebox.installSignalHandlers()
-
-		osId := strings.ToLower(runtime.GOOS)
-		if strings.HasPrefix(osId, "darwin") {
-			jukebox.audioPlayerExeFileName = "afplay"
-			jukebox.audioPlayerCommandArgs = ""
-		} else if strings.HasPrefix(osId, "linux") ||
-			strings.HasPrefix(osId, "freebsd") ||
-			strings.HasPrefix(osId, "netbsd") ||
-			strings.HasPrefix(osId, "openbsd") {
-
-			jukebox.audioPlayerExeFileName = "/usr/bin/mplayer"
-			jukebox.audioPlayerCommandArgs = "-novideo -nolirc -really-quiet"
-		} else if strings.HasPrefix(osId, "windows") {
-			jukebox.audioPlayerExeFileName = "C:\\Program Files\\MPC-HC\\mpc-hc64.exe"
-			jukebox.audioPlayerCommandArgs = "/play /close /minimized"
-		} else {
-			fmt.Printf("error: %s is not a supported OS\n", osId)
-			os.Exit(1)
+			artistIndex[song.Artist] = len(artists)
+			artists = append(artists, &LibraryArtist{Artist: song.Artist, Songs: 1})
 		}
+	}
+	sort.Slice(artists, func(i, j int) bool {
+		return artists[i].Artist < artists[j].Artist
+	})
+	return artists
+}
 
-		fmt.Println("downloading first song...")
-
-		if shuffle {
-			rand.Seed(time.Now().UnixNano())
-			rand.Shuffle(len(jukebox.songList), func(i, j int) {
-				jukebox.songList[i], jukebox.songList[j] = jukebox.songList[j], jukebox.songList[i]
-			})
+// LibraryAlbums returns the albums in the library (optionally limited to
+// an artist) ordered by artist and album.
+func (jukebox *Jukebox) LibraryAlbums(artist string) []*LibraryAlbum {
+	albums := []*LibraryAlbum{}
+	albumIndex := make(map[string]int)
+	for _, song := range jukebox.LibrarySongs(artist, "") {
+		if len(song.Album) == 0 {
+			continue
 		}
-
-		if jukebox.downloadSong(jukebox.songList[0]) {
-			fmt.Println("first song downloaded. starting playing now.")
-
-			pidAsText := fmt.Sprintf("%d\n", os.Getpid())
-			FileWriteAllText(jukeboxPidFileName, pidAsText)
-			defer DeleteFile(jukeboxPidFileName)
-
-			httpServer := NewHttpServer(jukebox, 5309)
-			if httpServer != nil {
-				go httpServer.Run()
-			}
-
-			for true {
-				if !jukebox.exitRequested {
-					if !jukebox.isPaused {
-						jukebox.downloadSongs()
-						jukebox.playSong(jukebox.songList[jukebox.songIndex])
-					}
-					if !jukebox.isPaused {
-						jukebox.songIndex += 1
-						if jukebox.songIndex >= jukebox.numberSongs {
-							jukebox.songIndex = 0
-						}
-					} else {
-						time.Sleep(1 * time.Second)
-					}
-				} else {
-					break
-				}
-			}
+		key := song.Artist + DoubleDashes + song.Album
+		if i, ok := albumIndex[key]; ok {
+			albums[i].Songs += 1
 		} else {
-			fmt.Println("error: unable to download songs")
-			os.Exit(1)
+			albumIndex[key] = len(albums)
+			albums = append(albums, &LibraryAlbum{Artist: song.Artist, Album: song.Album, Songs: 1})
 		}
 	}
+	sort.Slice(albums, func(i, j int) bool {
+		if albums[i].Artist != albums[j].Artist {
+			return albums[i].Artist < albums[j].Artist
+		}
+		return albums[i].Album < albums[j].Album
+	})
+	return albums
 }
 
 func (jukebox *Jukebox) ShowListContainers() {
 	if jukebox.storageSystem != nil {
-		listContainers, err := jukebox.storageSystem.GetContainerNames()
+		listContainers, err := jukebox.storageSystem.GetContainerNames(jukebox.ctx)
 		if err == nil {
 			for _, containerName := range listContainers {
 				fmt.Println(containerName)
@@ -944,26 +2063,26 @@ func (jukebox *Jukebox) ShowListContainers() {
 }
 
 func (jukebox *Jukebox) ShowListings() {
-	if jukebox.jukeboxDb != nil {
-		jukebox.jukeboxDb.showListings()
+	if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+		jukeboxDb.showListings()
 	}
 }
 
 func (jukebox *Jukebox) ShowArtists() {
-	if jukebox.jukeboxDb != nil {
-		jukebox.jukeboxDb.showArtists()
+	if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+		jukeboxDb.showArtists()
 	}
 }
 
 func (jukebox *Jukebox) ShowGenres() {
-	if jukebox.jukeboxDb != nil {
-		jukebox.jukeboxDb.showGenres()
+	if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+		jukeboxDb.showGenres()
 	}
 }
 
 func (jukebox *Jukebox) ShowAlbums() {
-	if jukebox.jukeboxDb != nil {
-		jukebox.jukeboxDb.showAlbums()
+	if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+		jukeboxDb.showAlbums()
 	}
 }
 
@@ -983,30 +2102,61 @@ func (jukebox *Jukebox) readFileContents(filePath string) (bool, []byte, int) {
 	return fileRead, fileContents, padChars
 }
 
+// haveOrCreateContainer makes sure that the container exists, creating
+// it if needed
+func (jukebox *Jukebox) haveOrCreateContainer(containerName string) bool {
+	haveContainer, err := jukebox.storageSystem.HasContainer(jukebox.ctx, containerName)
+	if err == nil && !haveContainer {
+		err = jukebox.storageSystem.CreateContainer(jukebox.ctx, containerName)
+		haveContainer = err == nil
+	}
+	if err != nil {
+		fmt.Printf("error: unable to create container '%s'\n", containerName)
+		fmt.Printf("error: %v\n", err)
+	}
+	return haveContainer
+}
+
+// UploadMetadataDb uploads the metadata DB file. the DB stays open, since
+// the tui and servers may be using it, so a copy of it is uploaded.
 func (jukebox *Jukebox) UploadMetadataDb() bool {
 	metadataDbUpload := false
-	haveMetadataContainer := false
-	if !jukebox.storageSystem.HasContainer(jukebox.metadataContainer) {
-		haveMetadataContainer = jukebox.storageSystem.CreateContainer(jukebox.metadataContainer)
-	} else {
-		haveMetadataContainer = true
-	}
 
-	if haveMetadataContainer {
+	// the copy always has the same name, so only one upload at a time
+	jukebox.uploadMutex.Lock()
+	defer jukebox.uploadMutex.Unlock()
+
+	if jukebox.haveOrCreateContainer(jukebox.metadataContainer) {
 		if jukebox.debugPrint {
 			fmt.Println("uploading metadata db file to storage system")
 		}
 
-		jukebox.jukeboxDb.close()
-		jukebox.jukeboxDb = nil
-
 		dbFilePath := jukebox.GetMetadataDbFilePath()
-		dbFileContents, errFile := FileReadAllBytes(dbFilePath)
+		if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+			copyFilePath := dbFilePath + uploadExtension
+			if !jukeboxDb.copyTo(copyFilePath) {
+				fmt.Printf("error: unable to copy metadata db file\n")
+				return false
+			}
+			defer DeleteFile(copyFilePath)
+			dbFilePath = copyFilePath
+		}
+
+		dbFile, errFile := os.Open(dbFilePath)
 		if errFile == nil {
-			metadataDbUpload = jukebox.storageSystem.PutObject(jukebox.metadataContainer,
+			errPut := jukebox.storageSystem.PutObjectFromReader(jukebox.ctx,
+				jukebox.metadataContainer,
 				jukebox.metadataDbFile,
-				dbFileContents,
+				dbFile,
+				GetFileSize(dbFilePath),
 				nil)
+			dbFile.Close()
+			if errPut == nil {
+				metadataDbUpload = true
+			} else {
+				fmt.Printf("error: unable to upload metadata db file\n")
+				fmt.Printf("error: %v\n", errPut)
+			}
 		} else {
 			fmt.Printf("error: unable to read metadata db file\n")
 			fmt.Printf("error: %v\n", errFile)
@@ -1025,8 +2175,7 @@ func (jukebox *Jukebox) UploadMetadataDb() bool {
 }
 
 func (jukebox *Jukebox) ImportPlaylists() {
-	if jukebox.jukeboxDb != nil && jukebox.jukeboxDb.isOpen() {
-		fileImportCount := 0
+	if jukebox.metadataDb() != nil {
 		dirListing, err := ListFilesInDirectory(jukebox.playlistImportDir)
 		if err != nil {
 			return
@@ -1036,52 +2185,68 @@ func (jukebox *Jukebox) ImportPlaylists() {
 			return
 		}
 
-		haveContainer := false
-		if !jukebox.storageSystem.HasContainer(jukebox.playlistContainer) {
-			haveContainer = jukebox.storageSystem.CreateContainer(jukebox.playlistContainer)
+		fileImportCount, _ := jukebox.importPlaylistFiles(dirListing)
+		if fileImportCount > 0 {
+			fmt.Printf("%d playlists imported\n", fileImportCount)
+			jukebox.UploadMetadataDb()
 		} else {
-			haveContainer = true
+			fmt.Println("no files imported")
 		}
+	}
+}
 
-		if !haveContainer {
-			fmt.Println("error: unable to create container for playlists. unable to import")
-			return
-		}
+// importPlaylistFiles imports the given files from the playlist import
+// directory and returns the number of playlists that were stored along
+// with the files that failed to import. the metadata DB isn't uploaded,
+// that's left to the caller.
+func (jukebox *Jukebox) importPlaylistFiles(fileNames []string) (int, []string) {
+	if jukebox.metadataDb() == nil {
+		return 0, fileNames
+	}
+	if !jukebox.haveOrCreateContainer(jukebox.playlistContainer) {
+		fmt.Println("error: unable to create container for playlists. unable to import")
+		return 0, fileNames
+	}
 
-		for _, fileName := range dirListing {
-			fullPath := PathJoin(jukebox.playlistImportDir, fileName)
-			objectName := fileName
-			fileRead, fileContents, _ := jukebox.readFileContents(fullPath)
-			if fileRead && fileContents != nil {
-				if jukebox.storageSystem.PutObject(jukebox.playlistContainer,
-					objectName,
-					fileContents,
-					nil) {
-					fmt.Println("put of playlist succeeded")
-					if !jukebox.storeSongPlaylist(objectName, fileContents) {
-						fmt.Println("storing of playlist to db failed")
-						jukebox.storageSystem.DeleteObject(jukebox.playlistContainer,
-							objectName)
-					} else {
-						fmt.Println("storing of playlist succeeded")
-						fileImportCount += 1
-					}
-				}
-			}
+	fileImportCount := 0
+	failedFiles := []string{}
+	for _, fileName := range fileNames {
+		fullPath := PathJoin(jukebox.playlistImportDir, fileName)
+		objectName := fileName
+		fileRead, fileContents, _ := jukebox.readFileContents(fullPath)
+		if !fileRead || fileContents == nil {
+			failedFiles = append(failedFiles, fileName)
+			continue
 		}
-
-		if fileImportCount > 0 {
-			fmt.Printf("%d playlists imported\n", fileImportCount)
-			jukebox.UploadMetadataDb()
+		errPut := jukebox.storageSystem.PutObject(jukebox.ctx,
+			jukebox.playlistContainer,
+			objectName,
+			fileContents,
+			nil)
+		if errPut == nil {
+			fmt.Println("put of playlist succeeded")
+			if !jukebox.storeSongPlaylist(objectName, fileContents) {
+				fmt.Println("storing of playlist to db failed")
+				jukebox.storageSystem.DeleteObject(jukebox.ctx,
+					jukebox.playlistContainer,
+					objectName)
+				failedFiles = append(failedFiles, fileName)
+			} else {
+				fmt.Println("storing of playlist succeeded")
+				fileImportCount += 1
+			}
 		} else {
-			fmt.Println("no files imported")
+			fmt.Printf("error: unable to upload playlist '%s'\n", objectName)
+			fmt.Printf("error: %v\n", errPut)
+			failedFiles = append(failedFiles, fileName)
 		}
 	}
+	return fileImportCount, failedFiles
 }
 
 func (jukebox *Jukebox) ShowPlaylists() {
-	if jukebox.jukeboxDb != nil {
-		jukebox.jukeboxDb.showPlaylists()
+	if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+		jukeboxDb.showPlaylists()
 	}
 }
 
@@ -1089,8 +2254,17 @@ func (jukebox *Jukebox) ShowAlbum(albumUid string) {
 	album := jukebox.getAlbum(albumUid)
 	if album != nil {
 		fmt.Printf("%s %s (%s)\n", album.Album, album.Year, album.Artist)
+		totalSeconds := 0
 		for _, track := range album.Tracks {
-			fmt.Printf("%d %s (%s)\n", track.Number, track.Title, track.Length)
+			length := track.Length
+			if song := jukebox.songWithBaseName(songBaseName(track.Object)); song != nil && song.DurationMs > 0 {
+				totalSeconds += int(song.DurationMs / 1000)
+				length = formatSongPosition(int(song.DurationMs / 1000))
+			}
+			fmt.Printf("%d %s (%s)\n", track.Number, track.Title, length)
+		}
+		if totalSeconds > 0 {
+			fmt.Printf("total time: %s\n", formatDuration(totalSeconds))
 		}
 	} else {
 		fmt.Printf("error: unable to retrieve album '%s'\n", albumUid)
@@ -1100,9 +2274,11 @@ func (jukebox *Jukebox) ShowAlbum(albumUid string) {
 func (jukebox *Jukebox) retrievePlaylist(playlist string) *Playlist {
 	objectName := fmt.Sprintf("%s.json", EncodeValue(playlist))
 	downloadFile := objectName
-	if jukebox.storageSystem.GetObject(jukebox.playlistContainer,
+	bytesRetrieved, errGet := jukebox.storageSystem.GetObject(jukebox.ctx,
+		jukebox.playlistContainer,
 		objectName,
-		downloadFile) > 0 {
+		downloadFile)
+	if errGet == nil && bytesRetrieved > 0 {
 
 		fileContents, err := FileReadAllText(downloadFile)
 		if err != nil {
@@ -1118,6 +2294,9 @@ func (jukebox *Jukebox) retrievePlaylist(playlist string) *Playlist {
 		}
 	} else {
 		fmt.Printf("error: unable to retrieve playlist from object storage\n")
+		if errGet != nil {
+			fmt.Printf("error: %v\n", errGet)
+		}
 	}
 	return nil
 }
@@ -1125,53 +2304,94 @@ func (jukebox *Jukebox) retrievePlaylist(playlist string) *Playlist {
 func (jukebox *Jukebox) ShowPlaylist(playlistName string) {
 	playlist := jukebox.retrievePlaylist(playlistName)
 	if playlist != nil {
+		totalSeconds := 0
 		for _, song := range playlist.Songs {
-			fmt.Printf("%s - %s (%s)\n", song.Artist, song.Song, song.Album)
+			length := ""
+			if dbSong := jukebox.playlistSong(song); dbSong != nil && dbSong.DurationMs > 0 {
+				totalSeconds += int(dbSong.DurationMs / 1000)
+				length = " " + formatSongPosition(int(dbSong.DurationMs/1000))
+			}
+			fmt.Printf("%s - %s (%s)%s\n", song.Artist, song.Song, song.Album, length)
+		}
+		if totalSeconds > 0 {
+			fmt.Printf("total time: %s\n", formatDuration(totalSeconds))
 		}
 	} else {
 		fmt.Printf("error: unable to retrieve playlist '%s'\n", playlistName)
 	}
 }
 
-func (jukebox *Jukebox) PlayPlaylist(playlistName string) {
-	playlist := jukebox.retrievePlaylist(playlistName)
-	if playlist != nil {
-		var songList []*SongMetadata
-		var extList []string
-		extList = append(extList, ".flac")
-		extList = append(extList, ".m4a")
-		extList = append(extList, ".mp3")
+// PlaylistNames returns the names of the playlists in the library.
+func (jukebox *Jukebox) PlaylistNames() []string {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return []string{}
+	}
+	return jukeboxDb.retrievePlaylistNames()
+}
 
-		for _, song := range playlist.Songs {
-			baseObjectName := EncodeArtistAlbumSong(song.Artist, song.Album, song.Song)
+// playlistSongs returns the songs of the playlist that are in the
+// library. returns nil if the playlist can't be retrieved.
+func (jukebox *Jukebox) playlistSongs(playlistName string) []*SongMetadata {
+	playlist := jukebox.retrievePlaylist(playlistName)
+	if playlist == nil {
+		fmt.Printf("error: unable to retrieve playlist '%s'\n", playlistName)
+		return nil
+	}
 
-			songFound := false
+	songList := []*SongMetadata{}
+	for _, song := range playlist.Songs {
+		dbSong := jukebox.playlistSong(song)
+		if dbSong != nil {
+			songList = append(songList, dbSong)
+		} else {
+			fmt.Printf("No song file for %s\n", EncodeArtistAlbumSong(song.Artist, song.Album, song.Song))
+		}
+	}
+	return songList
+}
 
-			for _, ext := range extList {
-				objectName := baseObjectName + ext
-				dbSong := jukebox.jukeboxDb.retrieveSong(objectName)
-				if dbSong != nil {
-					songFound = true
-					songList = append(songList, dbSong)
-					break
-				}
-			}
+// playlistSong returns the library song for a song in a playlist, or nil
+// if it isn't in the library.
+func (jukebox *Jukebox) playlistSong(song PlaylistSong) *SongMetadata {
+	return jukebox.songWithBaseName(EncodeArtistAlbumSong(song.Artist, song.Album, song.Song))
+}
 
-			if !songFound {
-				fmt.Printf("No song file for %s\n", baseObjectName)
-			}
+// songWithBaseName returns the library song whose uid is baseObjectName
+// plus one of the audio file extensions, or nil if there isn't one.
+func (jukebox *Jukebox) songWithBaseName(baseObjectName string) *SongMetadata {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil || len(baseObjectName) == 0 {
+		return nil
+	}
+	for _, ext := range []string{".flac", ".m4a", ".mp3"} {
+		dbSong := jukeboxDb.retrieveSong(baseObjectName + ext)
+		if dbSong != nil {
+			return dbSong
 		}
-		jukebox.playSongList(songList, false)
-	} else {
-		fmt.Printf("error: unable to retrieve playlist '%s'\n", playlistName)
 	}
+	return nil
+}
+
+// PlayPlaylist plays the songs of a playlist. returns false if they
+// couldn't be played.
+func (jukebox *Jukebox) PlayPlaylist(playlistName string) bool {
+	songList := jukebox.playlistSongs(playlistName)
+	if songList == nil {
+		return false
+	}
+	playSource := NewPlaySource(SourcePlaylist)
+	playSource.Playlist = playlistName
+	return jukebox.playSongList(songList, false, playSource)
 }
 
 func (jukebox *Jukebox) getAlbum(albumUid string) *Album {
 	downloadFile := albumUid
-	if jukebox.storageSystem.GetObject(jukebox.albumContainer,
+	bytesRetrieved, errGet := jukebox.storageSystem.GetObject(jukebox.ctx,
+		jukebox.albumContainer,
 		albumUid,
-		downloadFile) > 0 {
+		downloadFile)
+	if errGet == nil && bytesRetrieved > 0 {
 
 		fileContents, err := FileReadAllText(downloadFile)
 		if err != nil {
@@ -1189,11 +2409,20 @@ func (jukebox *Jukebox) getAlbum(albumUid string) *Album {
 		}
 	} else {
 		fmt.Printf("error: unable to retrieve album json object\n")
+		if errGet != nil {
+			fmt.Printf("error: %v\n", errGet)
+		}
 	}
 	return nil
 }
 
-func (jukebox *Jukebox) PlayAlbum(artist string, albumName string) {
+// PlayAlbum plays the songs of an album in track order. returns false if
+// they couldn't be played.
+func (jukebox *Jukebox) PlayAlbum(artist string, albumName string) bool {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
+	}
 	objectName := fmt.Sprintf("%s.json", EncodeArtistAlbum(artist, albumName))
 	album := jukebox.getAlbum(objectName)
 	if album != nil {
@@ -1215,7 +2444,7 @@ func (jukebox *Jukebox) PlayAlbum(artist string, albumName string) {
 
 				for _, ext := range extList {
 					objectName := baseObjectName + ext
-					dbSong := jukebox.jukeboxDb.retrieveSong(objectName)
+					dbSong := jukeboxDb.retrieveSong(objectName)
 					if dbSong != nil {
 						songFound = true
 						songList = append(songList, dbSong)
@@ -1228,19 +2457,30 @@ func (jukebox *Jukebox) PlayAlbum(artist string, albumName string) {
 				}
 			}
 			if len(songList) > 0 {
-				jukebox.playSongList(songList, false)
+				playSource := NewPlaySource(SourceAlbum)
+				playSource.Artist = artist
+				playSource.Album = albumName
+				return jukebox.playSongList(songList, false, playSource)
 			}
 		}
 	}
+	return false
 }
 
 func (jukebox *Jukebox) DeleteSong(songUid string, uploadMetadata bool) bool {
 	isDeleted := false
 	if len(songUid) > 0 {
-		dbDeleted := jukebox.jukeboxDb.deleteSong(songUid)
+		jukeboxDb := jukebox.metadataDb()
+		dbDeleted := jukeboxDb != nil && jukeboxDb.deleteSong(songUid)
 		container := jukebox.containerForSong(songUid)
 		if len(container) > 0 {
-			ssDeleted := jukebox.storageSystem.DeleteObject(container, songUid)
+			errDelete := jukebox.storageSystem.DeleteObject(jukebox.ctx, container, songUid)
+			// an object that's already gone is as good as deleted
+			ssDeleted := errDelete == nil || errors.Is(errDelete, ErrNotFound)
+			if !ssDeleted {
+				fmt.Printf("error: unable to delete song object '%s'\n", songUid)
+				fmt.Printf("error: %v\n", errDelete)
+			}
 			if dbDeleted && uploadMetadata {
 				jukebox.UploadMetadataDb()
 			}
@@ -1251,10 +2491,23 @@ func (jukebox *Jukebox) DeleteSong(songUid string, uploadMetadata bool) bool {
 	return isDeleted
 }
 
+// deleteLibrarySong deletes a song while the jukebox carries on running.
+// the song is taken out of the queue as well.
+func (jukebox *Jukebox) deleteLibrarySong(songUid string) bool {
+	if position := jukebox.queue.IndexOf(songUid); position >= 0 {
+		jukebox.queue.Remove(position)
+	}
+	return jukebox.DeleteSong(songUid, true)
+}
+
 func (jukebox *Jukebox) DeleteArtist(artist string) bool {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
+	}
 	isDeleted := false
 	if len(artist) > 0 {
-		songList := jukebox.jukeboxDb.retrieveSongs(artist, "")
+		songList := jukeboxDb.retrieveSongs(artist, "")
 		if songList != nil {
 			if len(songList) == 0 {
 				fmt.Println("no artist songs in jukebox")
@@ -1277,26 +2530,32 @@ func (jukebox *Jukebox) DeleteArtist(artist string) bool {
 }
 
 func (jukebox *Jukebox) DeleteAlbum(album string) bool {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
+	}
 	posDoubleDash := strings.Index(album, "--")
 	if posDoubleDash > -1 {
 		artist := album[0:posDoubleDash]
 		albumName := album[posDoubleDash+2:]
-		listAlbumSongs := jukebox.jukeboxDb.retrieveSongs(artist, albumName)
+		listAlbumSongs := jukeboxDb.retrieveSongs(artist, albumName)
 		if listAlbumSongs != nil && len(listAlbumSongs) > 0 {
 			numSongsDeleted := 0
 			for _, song := range listAlbumSongs {
 				fmt.Printf("%s %s\n", song.Fm.ContainerName, song.Fm.ObjectName)
 				// delete each song audio file
-				if jukebox.storageSystem.DeleteObject(jukebox.containerPrefix+song.Fm.ContainerName,
-					song.Fm.ObjectName) {
+				errDelete := jukebox.storageSystem.DeleteObject(jukebox.ctx,
+					jukebox.containerPrefix+song.Fm.ContainerName,
+					song.Fm.ObjectName)
+				if errDelete == nil || errors.Is(errDelete, ErrNotFound) {
 					numSongsDeleted += 1
 					// delete song metadata
-					jukebox.jukeboxDb.deleteSong(song.Fm.ObjectName)
+					jukeboxDb.deleteSong(song.Fm.ObjectName)
 				} else {
 					fmt.Printf("error: unable to delete song %s\n", song.Fm.ObjectName)
+					fmt.Printf("error: %v\n", errDelete)
 				}
 			}
-			//TODO: delete song metadata if we got 404?
 			if numSongsDeleted > 0 {
 				// upload metadata db
 				jukebox.UploadMetadataDb()
@@ -1313,17 +2572,23 @@ func (jukebox *Jukebox) DeleteAlbum(album string) bool {
 }
 
 func (jukebox *Jukebox) DeletePlaylist(playlistName string) bool {
+	jukeboxDb := jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return false
+	}
 	isDeleted := false
-	objectName := jukebox.jukeboxDb.getPlaylist(playlistName)
+	objectName := jukeboxDb.getPlaylist(playlistName)
 	if objectName != nil && len(*objectName) > 0 {
 		objectNameValue := *objectName
-		dbDeleted := jukebox.jukeboxDb.deletePlaylist(playlistName)
+		dbDeleted := jukeboxDb.deletePlaylist(playlistName)
 		if dbDeleted {
 			fmt.Printf("container='%s', object='%s'\n", jukebox.playlistContainer, objectNameValue)
-			if jukebox.storageSystem.DeleteObject(jukebox.playlistContainer, objectNameValue) {
+			errDelete := jukebox.storageSystem.DeleteObject(jukebox.ctx, jukebox.playlistContainer, objectNameValue)
+			if errDelete == nil || errors.Is(errDelete, ErrNotFound) {
 				isDeleted = true
 			} else {
 				fmt.Println("error: object delete failed")
+				fmt.Printf("error: %v\n", errDelete)
 			}
 		} else {
 			fmt.Println("error: database delete failed")
@@ -1341,8 +2606,7 @@ func (jukebox *Jukebox) DeletePlaylist(playlistName string) bool {
 }
 
 func (jukebox *Jukebox) ImportAlbumArt() {
-	if jukebox.jukeboxDb != nil && jukebox.jukeboxDb.isOpen() {
-		fileImportCount := 0
+	if jukebox.metadataDb() != nil {
 		dirListing, err := ListFilesInDirectory(jukebox.albumArtImportDir)
 		if err != nil {
 			return
@@ -1353,49 +2617,76 @@ func (jukebox *Jukebox) ImportAlbumArt() {
 			}
 		}
 
-		haveContainer := false
-
-		if !jukebox.storageSystem.HasContainer(jukebox.albumArtContainer) {
-			haveContainer = jukebox.storageSystem.CreateContainer(jukebox.albumArtContainer)
+		fileImportCount, _ := jukebox.importAlbumArtFiles(dirListing)
+		if fileImportCount > 0 {
+			fmt.Printf("%d album art files imported\n", fileImportCount)
 		} else {
-			haveContainer = true
+			fmt.Println("no files imported")
 		}
+	}
+}
 
-		if !haveContainer {
-			fmt.Println("error: unable to create container for album art. unable to import")
-			return
-		}
+// importAlbumArtFiles uploads the given files from the album art import
+// directory and returns the number that were uploaded along with the files
+// that failed to upload.
+func (jukebox *Jukebox) importAlbumArtFiles(fileNames []string) (int, []string) {
+	if jukebox.metadataDb() == nil {
+		return 0, fileNames
+	}
+	if !jukebox.haveOrCreateContainer(jukebox.albumArtContainer) {
+		fmt.Println("error: unable to create container for album art. unable to import")
+		return 0, fileNames
+	}
 
-		for _, fileName := range dirListing {
-			fullPath := PathJoin(jukebox.albumArtImportDir, fileName)
-			objectName := fileName
-			fileRead, fileContents, _ := jukebox.readFileContents(fullPath)
-			if fileRead && fileContents != nil {
-				if jukebox.storageSystem.PutObject(jukebox.albumArtContainer,
-					objectName,
-					fileContents,
-					nil) {
-					fileImportCount += 1
-				}
-			}
+	fileImportCount := 0
+	failedFiles := []string{}
+	for _, fileName := range fileNames {
+		fullPath := PathJoin(jukebox.albumArtImportDir, fileName)
+		objectName := fileName
+		fileRead, fileContents, _ := jukebox.readFileContents(fullPath)
+		if !fileRead || fileContents == nil {
+			failedFiles = append(failedFiles, fileName)
+			continue
 		}
-
-		if fileImportCount > 0 {
-			fmt.Printf("%d album art files imported\n", fileImportCount)
+		errPut := jukebox.storageSystem.PutObject(jukebox.ctx,
+			jukebox.albumArtContainer,
+			objectName,
+			fileContents,
+			nil)
+		if errPut == nil {
+			fileImportCount += 1
 		} else {
-			fmt.Println("no files imported")
+			fmt.Printf("error: unable to upload album art '%s'\n", objectName)
+			fmt.Printf("error: %v\n", errPut)
+			failedFiles = append(failedFiles, fileName)
 		}
 	}
+	return fileImportCount, failedFiles
+}
+
+// WatchImportDirs imports songs, playlists and album art as files are
+// dropped into the import directories, until Ctrl-C is pressed.
+func (jukebox *Jukebox) WatchImportDirs() {
+	if jukebox.metadataDb() == nil {
+		return
+	}
+	watchIntervalSeconds := defaultWatchIntervalSeconds
+	if jukebox.jukeboxOptions != nil {
+		watchIntervalSeconds = jukebox.jukeboxOptions.WatchIntervalSeconds
+	}
+	jukebox.installSignalHandlers()
+	NewImportWatcher(jukebox, time.Duration(watchIntervalSeconds)*time.Second).Run()
 }
 
-func InitializeStorageSystem(storageSys StorageSystem, containerPrefix string) bool {
+func InitializeStorageSystem(ctx context.Context, storageSys StorageSystemV2, containerPrefix string) bool {
 	// create the containers that will hold songs
 	artistSongChars := "0123456789abcdefghijklmnopqrstuvwxyz"
 
 	for _, ch := range artistSongChars {
 		containerName := containerPrefix + fmt.Sprintf("%c%s", ch, songContainerSuffix)
-		if !storageSys.CreateContainer(containerName) {
+		if err := storageSys.CreateContainer(ctx, containerName); err != nil {
 			fmt.Printf("error: unable to create container '%s'\n", containerName)
+			fmt.Printf("error: %v\n", err)
 			return false
 		}
 	}
@@ -1409,8 +2700,9 @@ func InitializeStorageSystem(storageSys StorageSystem, containerPrefix string) b
 
 	for _, containerName := range containerNames {
 		cnrName := containerPrefix + containerName
-		if !storageSys.CreateContainer(cnrName) {
+		if err := storageSys.CreateContainer(ctx, cnrName); err != nil {
 			fmt.Printf("error: unable to create container '%s'\n", cnrName)
+			fmt.Printf("error: %v\n", err)
 			return false
 		}
 	}
@@ -3,6 +3,7 @@ package jukebox
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -47,7 +48,7 @@ func (jukeboxDB *JukeboxDB) open() bool {
 				fmt.Println("error: unable to create all tables")
 			}
 		} else {
-			openSuccess = true
+			openSuccess = jukeboxDB.addMissingSongColumns()
 		}
 	}
 	return openSuccess
@@ -87,6 +88,26 @@ func (jukeboxDB *JukeboxDB) exit() {
 	jukeboxDB.close()
 }
 
+// copyTo writes a consistent copy of the database to filePath, so that
+// the database can be uploaded while it stays open.
+func (jukeboxDB *JukeboxDB) copyTo(filePath string) bool {
+	if jukeboxDB.dbConnection == nil {
+		return false
+	}
+	// VACUUM INTO won't overwrite an existing file
+	if FileExists(filePath) && !DeleteFile(filePath) {
+		fmt.Printf("error: unable to delete %s\n", filePath)
+		return false
+	}
+	_, err := jukeboxDB.dbConnection.Exec("VACUUM INTO ?", filePath)
+	if err != nil {
+		fmt.Printf("error: unable to copy database to %s\n", filePath)
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	return true
+}
+
 func (jukeboxDB *JukeboxDB) createTable(sqlStatement string) bool {
 	if jukeboxDB.dbConnection != nil {
 		stmt, err := jukeboxDB.dbConnection.Prepare(sqlStatement)
@@ -148,7 +169,15 @@ func (jukeboxDB *JukeboxDB) createTables() bool {
 			"encrypted INTEGER," +
 			"container_name TEXT NOT NULL," +
 			"object_name TEXT NOT NULL," +
-			"album_uid TEXT REFERENCES album(album_uid))"
+			"album_uid TEXT REFERENCES album(album_uid)," +
+			"track_number INTEGER," +
+			"disc_number INTEGER," +
+			"year INTEGER," +
+			"genre TEXT," +
+			"duration_ms INTEGER," +
+			"bitrate INTEGER," +
+			"sample_rate INTEGER," +
+			"channels INTEGER)"
 
 		createPlaylistTable := "CREATE TABLE playlist (" +
 			"playlist_uid TEXT UNIQUE NOT NULL," +
@@ -171,6 +200,72 @@ func (jukeboxDB *JukeboxDB) createTables() bool {
 	return false
 }
 
+// songAddedColumns are the song columns that were added after the song
+// table was first created. databases from before then get them added.
+var songAddedColumns = []string{
+	"track_number INTEGER",
+	"disc_number INTEGER",
+	"year INTEGER",
+	"genre TEXT",
+	"duration_ms INTEGER",
+	"bitrate INTEGER",
+	"sample_rate INTEGER",
+	"channels INTEGER",
+}
+
+func (jukeboxDB *JukeboxDB) addMissingSongColumns() bool {
+	columns := jukeboxDB.tableColumns("song")
+	if columns == nil {
+		return false
+	}
+	for _, columnDefinition := range songAddedColumns {
+		columnName := strings.Fields(columnDefinition)[0]
+		if !columns[columnName] {
+			sqlStatement := "ALTER TABLE song ADD COLUMN " + columnDefinition
+			_, err := jukeboxDB.dbConnection.Exec(sqlStatement)
+			if err != nil {
+				fmt.Printf("error: unable to add column '%s'\n", columnName)
+				fmt.Printf("error: %v\n", err)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// tableColumns returns the names of a table's columns, or nil if they
+// can't be read.
+func (jukeboxDB *JukeboxDB) tableColumns(tableName string) map[string]bool {
+	if jukeboxDB.dbConnection == nil {
+		return nil
+	}
+	rows, err := jukeboxDB.dbConnection.Query("PRAGMA table_info(" + tableName + ")")
+	if err != nil {
+		fmt.Printf("error: unable to read columns of table '%s'\n", tableName)
+		fmt.Printf("error: %v\n", err)
+		return nil
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var columnId int
+		var columnName string
+		var columnType string
+		var notNull int
+		var defaultValue *string
+		var primaryKey int
+		err = rows.Scan(&columnId, &columnName, &columnType, &notNull, &defaultValue, &primaryKey)
+		if err != nil {
+			fmt.Printf("error: unable to scan columns of table '%s'\n", tableName)
+			fmt.Printf("error: %v\n", err)
+			return nil
+		}
+		columns[columnName] = true
+	}
+	return columns
+}
+
 func (jukeboxDB *JukeboxDB) haveTables() bool {
 	haveTablesInDb := false
 	if jukeboxDB.dbConnection != nil {
@@ -235,11 +330,21 @@ func (jukeboxDB *JukeboxDB) songsForQueryResults(rows *sql.Rows) []*SongMetadata
 		var containerName string
 		var objectName string
 		var albumUid *string
+		var trackNumber *int
+		var discNumber *int
+		var year *int
+		var genre *string
+		var durationMs *int64
+		var bitrate *int
+		var sampleRate *int
+		var channels *int
 
 		err := rows.Scan(&fileUid, &fileTime, &oFileSize, &sFileSize,
 			&padCount, &artistName, &artistUid, &songName,
 			&md5Hash, &compressed, &encrypted, &containerName,
-			&objectName, &albumUid)
+			&objectName, &albumUid, &trackNumber, &discNumber,
+			&year, &genre, &durationMs, &bitrate, &sampleRate,
+			&channels)
 
 		if err != nil {
 			fmt.Printf("error: scan of row values failed\n")
@@ -267,6 +372,31 @@ func (jukeboxDB *JukeboxDB) songsForQueryResults(rows *sql.Rows) []*SongMetadata
 		} else {
 			song.AlbumUid = ""
 		}
+		// songs stored before these columns were added don't have them
+		if trackNumber != nil {
+			song.TrackNumber = *trackNumber
+		}
+		if discNumber != nil {
+			song.DiscNumber = *discNumber
+		}
+		if year != nil {
+			song.Year = *year
+		}
+		if genre != nil {
+			song.Genre = *genre
+		}
+		if durationMs != nil {
+			song.DurationMs = *durationMs
+		}
+		if bitrate != nil {
+			song.Bitrate = *bitrate
+		}
+		if sampleRate != nil {
+			song.SampleRate = *sampleRate
+		}
+		if channels != nil {
+			song.Channels = *channels
+		}
 
 		resultSongs = append(resultSongs, song)
 	}
@@ -274,9 +404,7 @@ func (jukeboxDB *JukeboxDB) songsForQueryResults(rows *sql.Rows) []*SongMetadata
 	return resultSongs
 }
 
-func (jukeboxDB *JukeboxDB) retrieveSong(fileName string) *SongMetadata {
-	if jukeboxDB.dbConnection != nil {
-		sqlQuery := `
+const songQueryByUidSql = `
             SELECT song_uid,
             file_time,
             origin_file_size,
@@ -290,12 +418,23 @@ func (jukeboxDB *JukeboxDB) retrieveSong(fileName string) *SongMetadata {
             encrypted,
             container_name,
             object_name,
-            album_uid
+            album_uid,
+            track_number,
+            disc_number,
+            year,
+            genre,
+            duration_ms,
+            bitrate,
+            sample_rate,
+            channels
             FROM song WHERE song_uid = ?
         `
-		stmt, err := jukeboxDB.dbConnection.Prepare(sqlQuery)
+
+func (jukeboxDB *JukeboxDB) retrieveSong(fileName string) *SongMetadata {
+	if jukeboxDB.dbConnection != nil {
+		stmt, err := jukeboxDB.dbConnection.Prepare(songQueryByUidSql)
 		if err != nil {
-			fmt.Printf("error: unable to prepare statement '%s'\n", sqlQuery)
+			fmt.Printf("error: unable to prepare statement '%s'\n", songQueryByUidSql)
 			fmt.Printf("error: %v\n", err)
 			return nil
 		}
@@ -369,37 +508,87 @@ func (jukeboxDB *JukeboxDB) deletePlaylist(plName string) bool {
 	return deleteSuccess
 }
 
+// songInsertSql and songUpdateSql take the values from songInsertArgs
+// and songUpdateArgs.
+const songInsertSql = "INSERT INTO song (song_uid, file_time, origin_file_size, " +
+	"stored_file_size, pad_char_count, artist_name, artist_uid, " +
+	"song_name, md5_hash, compressed, encrypted, container_name, " +
+	"object_name, album_uid, track_number, disc_number, year, genre, " +
+	"duration_ms, bitrate, sample_rate, channels) " +
+	"VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
+
+const songUpdateSql = `
+        UPDATE song SET file_time=?,
+           origin_file_size=?,
+           stored_file_size=?,
+           pad_char_count=?,
+           artist_name=?,
+           artist_uid=?,
+           song_name=?,
+           md5_hash=?,
+           compressed=?,
+           encrypted=?,
+           container_name=?,
+           object_name=?,
+           album_uid=?,
+           track_number=?,
+           disc_number=?,
+           year=?,
+           genre=?,
+           duration_ms=?,
+           bitrate=?,
+           sample_rate=?,
+           channels=? WHERE song_uid = ?
+    `
+
+func songInsertArgs(song *SongMetadata) []interface{} {
+	return []interface{}{song.Fm.FileUid,
+		song.Fm.FileTime,
+		song.Fm.OriginFileSize,
+		song.Fm.StoredFileSize,
+		song.Fm.PadCharCount,
+		song.ArtistName,
+		"",
+		song.SongName,
+		song.Fm.Md5Hash,
+		song.Fm.Compressed,
+		song.Fm.Encrypted,
+		song.Fm.ContainerName,
+		song.Fm.ObjectName,
+		song.AlbumUid,
+		song.TrackNumber,
+		song.DiscNumber,
+		song.Year,
+		song.Genre,
+		song.DurationMs,
+		song.Bitrate,
+		song.SampleRate,
+		song.Channels}
+}
+
+// songUpdateArgs are the same values as the insert, with the uid moved to
+// the end for the WHERE clause.
+func songUpdateArgs(song *SongMetadata) []interface{} {
+	return append(songInsertArgs(song)[1:], song.Fm.FileUid)
+}
+
 func (jukeboxDB *JukeboxDB) insertSong(song *SongMetadata) bool {
 	insertSuccess := false
 
 	if jukeboxDB.dbConnection != nil && song != nil {
-		sqlQuery := "INSERT INTO song VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?)"
 		tx, errTx := jukeboxDB.dbConnection.Begin()
 		if errTx != nil {
 			return false
 		}
-		stmt, err := tx.Prepare(sqlQuery)
+		stmt, err := tx.Prepare(songInsertSql)
 		if err != nil {
-			fmt.Printf("error: unable to prepare statement '%s'\n", sqlQuery)
+			fmt.Printf("error: unable to prepare statement '%s'\n", songInsertSql)
 			fmt.Printf("error: %v\n", err)
 			return false
 		}
 		defer stmt.Close()
 
-		stmt.Exec(song.Fm.FileUid,
-			song.Fm.FileTime,
-			song.Fm.OriginFileSize,
-			song.Fm.StoredFileSize,
-			song.Fm.PadCharCount,
-			song.ArtistName,
-			"",
-			song.SongName,
-			song.Fm.Md5Hash,
-			song.Fm.Compressed,
-			song.Fm.Encrypted,
-			song.Fm.ContainerName,
-			song.Fm.ObjectName,
-			song.AlbumUid)
+		stmt.Exec(songInsertArgs(song)...)
 		tx.Commit()
 		insertSuccess = true
 	}
@@ -411,48 +600,20 @@ func (jukeboxDB *JukeboxDB) updateSong(song *SongMetadata) bool {
 	updateSuccess := false
 
 	if jukeboxDB.dbConnection != nil && song != nil && len(song.Fm.FileUid) > 0 {
-		sqlQuery := `
-                UPDATE song SET file_time=?,
-                   origin_file_size=?,
-                   stored_file_size=?,
-                   pad_char_count=?,
-                   artist_name=?,
-                   artist_uid=?,
-                   song_name=?,
-                   md5_hash=?,
-                   compressed=?,
-                   encrypted=?,
-                   container_name=?,
-                   object_name=?,
-                   album_uid=? WHERE song_uid = ?
-            `
 		tx, errTx := jukeboxDB.dbConnection.Begin()
 		if errTx != nil {
 			return false
 		}
-		stmt, err := tx.Prepare(sqlQuery)
+		stmt, err := tx.Prepare(songUpdateSql)
 		if err != nil {
-			fmt.Printf("error: unable to prepare statement '%s'\n", sqlQuery)
+			fmt.Printf("error: unable to prepare statement '%s'\n", songUpdateSql)
 			fmt.Printf("error: %v\n", err)
 			return false
 		}
 
 		defer stmt.Close()
 
-		stmt.Exec(song.Fm.FileTime,
-			song.Fm.OriginFileSize,
-			song.Fm.StoredFileSize,
-			song.Fm.PadCharCount,
-			song.ArtistName,
-			"",
-			song.SongName,
-			song.Fm.Md5Hash,
-			song.Fm.Compressed,
-			song.Fm.Encrypted,
-			song.Fm.ContainerName,
-			song.Fm.ObjectName,
-			song.AlbumUid,
-			song.Fm.FileUid)
+		stmt.Exec(songUpdateArgs(song)...)
 		tx.Commit()
 		updateSuccess = true
 	}
@@ -477,6 +638,66 @@ func (jukeboxDB *JukeboxDB) storeSongMetadata(song *SongMetadata) bool {
 	}
 }
 
+// storeSongsMetadata inserts or updates songs in a single transaction.
+// each song is stored (or not) on its own, and the result says which ones
+// made it so that the caller can clean up after the others.
+func (jukeboxDB *JukeboxDB) storeSongsMetadata(songs []*SongMetadata) []bool {
+	stored := make([]bool, len(songs))
+	if jukeboxDB.dbConnection == nil || len(songs) == 0 {
+		return stored
+	}
+
+	tx, errTx := jukeboxDB.dbConnection.Begin()
+	if errTx != nil {
+		fmt.Printf("error: unable to begin transaction\n")
+		fmt.Printf("error: %v\n", errTx)
+		return stored
+	}
+
+	statements := []*sql.Stmt{}
+	for _, sqlQuery := range []string{songQueryByUidSql, songInsertSql, songUpdateSql} {
+		stmt, err := tx.Prepare(sqlQuery)
+		if err != nil {
+			fmt.Printf("error: unable to prepare statement '%s'\n", sqlQuery)
+			fmt.Printf("error: %v\n", err)
+			tx.Rollback()
+			return stored
+		}
+		defer stmt.Close()
+		statements = append(statements, stmt)
+	}
+	queryStmt, insertStmt, updateStmt := statements[0], statements[1], statements[2]
+
+	for i, song := range songs {
+		if song == nil || len(song.Fm.FileUid) == 0 {
+			continue
+		}
+		rows, err := queryStmt.Query(song.Fm.FileUid)
+		if err == nil {
+			dbSongs := jukeboxDB.songsForQueryResults(rows)
+			rows.Close()
+			if len(dbSongs) == 0 {
+				_, err = insertStmt.Exec(songInsertArgs(song)...)
+			} else if !song.Equals(dbSongs[0]) {
+				_, err = updateStmt.Exec(songUpdateArgs(song)...)
+			}
+		}
+		if err != nil {
+			fmt.Printf("error: unable to store metadata for '%s'\n", song.Fm.FileUid)
+			fmt.Printf("error: %v\n", err)
+		} else {
+			stored[i] = true
+		}
+	}
+
+	if errCommit := tx.Commit(); errCommit != nil {
+		fmt.Printf("error: unable to commit song metadata\n")
+		fmt.Printf("error: %v\n", errCommit)
+		return make([]bool, len(songs))
+	}
+	return stored
+}
+
 func (jukeboxDB *JukeboxDB) sqlWhereClause() string {
 	whereClause := " WHERE encrypted = 0"
 	return whereClause
@@ -500,7 +721,15 @@ func (jukeboxDB *JukeboxDB) retrieveSongs(artist string,
             encrypted,
             container_name,
             object_name,
-            album_uid FROM song
+            album_uid,
+            track_number,
+            disc_number,
+            year,
+            genre,
+            duration_ms,
+            bitrate,
+            sample_rate,
+            channels FROM song
         `
 
 		sqlQuery += jukeboxDB.sqlWhereClause()
@@ -560,7 +789,15 @@ func (jukeboxDB *JukeboxDB) songsForArtist(artistName string) []*SongMetadata {
             encrypted,
             container_name,
             object_name,
-            album_uid FROM song
+            album_uid,
+            track_number,
+            disc_number,
+            year,
+            genre,
+            duration_ms,
+            bitrate,
+            sample_rate,
+            channels FROM song
         `
 		sqlQuery += jukeboxDB.sqlWhereClause()
 		sqlQuery += " AND artist = ?"
@@ -731,6 +968,31 @@ func (jukeboxDB *JukeboxDB) showPlaylists() {
 	}
 }
 
+// retrievePlaylistNames returns the names of the playlists ordered by
+// their uid.
+func (jukeboxDB *JukeboxDB) retrievePlaylistNames() []string {
+	playlistNames := []string{}
+	if jukeboxDB.dbConnection != nil {
+		sqlQuery := "SELECT playlist_name " +
+			"FROM playlist " +
+			"ORDER BY playlist_uid"
+		rows, err := jukeboxDB.dbConnection.Query(sqlQuery)
+		if err != nil {
+			fmt.Printf("error: unable to query playlists\n")
+			fmt.Printf("error: %v\n", err)
+			return playlistNames
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var plName string
+			if rows.Scan(&plName) == nil {
+				playlistNames = append(playlistNames, plName)
+			}
+		}
+	}
+	return playlistNames
+}
+
 func (jukeboxDB *JukeboxDB) deleteSong(songUid string) bool {
 	wasDeleted := false
 	if jukeboxDB.dbConnection != nil {
@@ -1,6 +1,7 @@
 package jukebox
 
 import (
+	"database/sql"
 	"testing"
 )
 
@@ -11,6 +12,54 @@ func Test_isOpen(t *testing.T) {
 }
 
 func Test_open(t *testing.T) {
+	th := NewTestHelper(t)
+	dbFilePath := PathJoin(t.TempDir(), "jukebox_db.sqlite3")
+
+	// a song table from before the tag columns were added
+	db, err := sql.Open("sqlite3", dbFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("CREATE TABLE song (song_uid TEXT UNIQUE NOT NULL, file_time TEXT, " +
+		"origin_file_size INTEGER, stored_file_size INTEGER, pad_char_count INTEGER, " +
+		"artist_name TEXT, artist_uid TEXT, song_name TEXT NOT NULL, md5_hash TEXT NOT NULL, " +
+		"compressed INTEGER, encrypted INTEGER, container_name TEXT NOT NULL, " +
+		"object_name TEXT NOT NULL, album_uid TEXT)")
+	if err == nil {
+		_, err = db.Exec("INSERT INTO song VALUES ('The-Who--Whos-Next--My-Wife.mp3', '', 3, 3, 0, " +
+			"'The Who', '', 'My Wife', '', 0, 0, 'w-artist-songs', 'The-Who--Whos-Next--My-Wife.mp3', NULL)")
+	}
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jukeboxDB := NewJukeboxDB(dbFilePath, false)
+	th.Require(jukeboxDB.open(), "db with old song table must open")
+	defer jukeboxDB.close()
+	columns := jukeboxDB.tableColumns("song")
+	for _, columnName := range []string{"track_number", "disc_number", "year", "genre",
+		"duration_ms", "bitrate", "sample_rate", "channels"} {
+		th.Require(columns[columnName], "missing song column must be added: "+columnName)
+	}
+
+	song := jukeboxDB.retrieveSong("The-Who--Whos-Next--My-Wife.mp3")
+	th.Require(song != nil, "song stored before migration must be retrieved")
+	if song != nil {
+		th.Require(song.TrackNumber == 0 && song.Year == 0, "song stored before migration has no numbers")
+		th.RequireStringEquals(song.Genre, "", "song stored before migration has no genre")
+
+		song.TrackNumber = 4
+		song.Year = 1971
+		song.Genre = "Rock"
+		song.DurationMs = 214000
+		song.Bitrate = 320
+		song.SampleRate = 44100
+		song.Channels = 2
+		th.Require(jukeboxDB.storeSongMetadata(song), "song must be updated")
+		updatedSong := jukeboxDB.retrieveSong(song.Fm.FileUid)
+		th.Require(updatedSong != nil && updatedSong.Equals(song), "updated song must have tag values")
+	}
 }
 
 func Test_close(t *testing.T) {
@@ -55,6 +104,50 @@ func Test_updateSong(t *testing.T) {
 func Test_db_storeSongMetadata(t *testing.T) {
 }
 
+func Test_storeSongsMetadata(t *testing.T) {
+	th := NewTestHelper(t)
+	jukeboxDB := NewJukeboxDB(PathJoin(t.TempDir(), "jukebox_db.sqlite3"), false)
+	th.Require(jukeboxDB.open(), "db must open")
+	defer jukeboxDB.close()
+
+	// make the insert of one song fail on its own
+	_, err := jukeboxDB.dbConnection.Exec("CREATE TRIGGER reject_song BEFORE INSERT ON song " +
+		"WHEN NEW.song_uid LIKE 'Rejected%' BEGIN SELECT RAISE(ABORT, 'rejected'); END")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	songs := []*SongMetadata{}
+	for _, fileUid := range []string{"The-Who--Whos-Next--My-Wife.mp3",
+		"Rejected--Album--Song.mp3",
+		"The-Who--Whos-Next--Bargain.mp3"} {
+		song := NewSongMetadata()
+		song.Fm = NewFileMetadata()
+		song.Fm.FileUid = fileUid
+		song.Fm.ObjectName = fileUid
+		song.Fm.ContainerName = "w-artist-songs"
+		song.ArtistName = artistFromFileName(fileUid)
+		song.SongName = songFromFileName(fileUid)
+		songs = append(songs, song)
+	}
+	songs = append(songs, nil)
+
+	stored := jukeboxDB.storeSongsMetadata(songs)
+	th.Require(len(stored) == len(songs), "result for each song")
+	th.Require(stored[0] && stored[2], "songs must be stored")
+	th.RequireFalse(stored[1], "rejected song must not be stored")
+	th.RequireFalse(stored[3], "nil song must not be stored")
+	th.Require(len(jukeboxDB.retrieveSongs("", "")) == 2, "other songs in the batch must be committed")
+
+	songs[0].Year = 1971
+	stored = jukeboxDB.storeSongsMetadata(songs[:1])
+	th.Require(stored[0], "existing song must be updated")
+	song := jukeboxDB.retrieveSong(songs[0].Fm.FileUid)
+	th.Require(song != nil && song.Year == 1971, "updated song must have new values")
+
+	th.Require(len(jukeboxDB.storeSongsMetadata(nil)) == 0, "empty batch")
+}
+
 func Test_sqlWhereClause(t *testing.T) {
 }
 
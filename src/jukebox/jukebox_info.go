@@ -0,0 +1,109 @@
+package jukebox
+
+import "fmt"
+
+// SongInfo describes a song to clients of the jukebox (e.g., the HTTP
+// API).
+type SongInfo struct {
+	Uid             string `json:"uid"`
+	Artist          string `json:"artist"`
+	Album           string `json:"album"`
+	Song            string `json:"song"`
+	DurationSeconds int    `json:"duration-seconds,omitempty"`
+}
+
+func NewSongInfo(song *SongMetadata) *SongInfo {
+	var info SongInfo
+	if song.Fm != nil {
+		info.Uid = song.Fm.FileUid
+		info.Album = albumFromFileName(song.Fm.FileUid)
+	}
+	info.Artist = song.ArtistName
+	info.Song = song.SongName
+	info.DurationSeconds = int(song.DurationMs / 1000)
+	return &info
+}
+
+// PlayerStatus is a snapshot of what the jukebox is playing.
+type PlayerStatus struct {
+	Song             *SongInfo `json:"song"`
+	Index            int       `json:"index"`
+	NumberSongs      int       `json:"number-songs"`
+	Paused           bool      `json:"paused"`
+	PositionSeconds  int       `json:"position-seconds"`
+	DurationSeconds  int       `json:"duration-seconds,omitempty"`
+	SleepSeconds     int       `json:"sleep-seconds,omitempty"`
+	RemainingSeconds int       `json:"remaining-seconds,omitempty"`
+}
+
+func NewPlayerStatus() *PlayerStatus {
+	var status PlayerStatus
+	status.Song = nil
+	status.Index = -1
+	status.NumberSongs = 0
+	status.Paused = false
+	status.PositionSeconds = 0
+	return &status
+}
+
+// Show prints the status the way that DisplayInfo shows the current song.
+func (status *PlayerStatus) Show() {
+	if status.Song == nil {
+		fmt.Println("no song playing")
+		return
+	}
+	position := status.positionText(" / ")
+	if status.Paused {
+		fmt.Printf("paused: %s (%s)\n", status.Song.Uid, position)
+	} else {
+		fmt.Printf("now playing: %s (%s)\n", status.Song.Uid, position)
+	}
+	fmt.Printf("song %d of %d\n", status.Index+1, status.NumberSongs)
+	if status.RemainingSeconds > 0 {
+		fmt.Printf("time remaining: %s\n", formatDuration(status.RemainingSeconds))
+	}
+	if status.SleepSeconds > 0 {
+		fmt.Printf("sleep timer: %s\n", formatSongPosition(status.SleepSeconds))
+	}
+}
+
+// StatusLine returns the status as a single line, for showing in place
+// while playing.
+func (status *PlayerStatus) StatusLine() string {
+	if status.Song == nil {
+		return "no song playing"
+	}
+	state := "playing"
+	if status.Paused {
+		state = "paused"
+	}
+	line := fmt.Sprintf("%s %d/%d: %s (%s)", state, status.Index+1, status.NumberSongs,
+		status.Song.Uid, status.positionText("/"))
+	if status.SleepSeconds > 0 {
+		line += fmt.Sprintf(" sleep %s", formatSongPosition(status.SleepSeconds))
+	}
+	return line
+}
+
+// positionText returns the position in the song, followed by the song's
+// length if it's known.
+func (status *PlayerStatus) positionText(separator string) string {
+	position := formatSongPosition(status.PositionSeconds)
+	if status.DurationSeconds > 0 {
+		position += separator + formatSongPosition(status.DurationSeconds)
+	}
+	return position
+}
+
+// LibraryArtist is an artist in the jukebox library.
+type LibraryArtist struct {
+	Artist string `json:"artist"`
+	Songs  int    `json:"songs"`
+}
+
+// LibraryAlbum is an album in the jukebox library.
+type LibraryAlbum struct {
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Songs  int    `json:"songs"`
+}
@@ -0,0 +1,58 @@
+package jukebox
+
+import "testing"
+
+func TestNewSongInfo(t *testing.T) {
+	th := NewTestHelper(t)
+	song := NewSongMetadata()
+	song.Fm = NewFileMetadata()
+	song.Fm.FileUid = "The-Who--Whos-Next--My-Wife.mp3"
+	song.ArtistName = "The Who"
+	song.SongName = "My Wife"
+	song.DurationMs = 214900
+
+	info := NewSongInfo(song)
+	th.RequireStringEquals(info.Uid, "The-Who--Whos-Next--My-Wife.mp3", "uid must be file uid")
+	th.RequireStringEquals(info.Artist, "The Who", "artist must be artist name")
+	th.RequireStringEquals(info.Album, "Whos Next", "album must come from file uid")
+	th.RequireStringEquals(info.Song, "My Wife", "song must be song name")
+	th.Require(info.DurationSeconds == 214, "duration must be whole seconds")
+}
+
+func TestNewPlayerStatus(t *testing.T) {
+	th := NewTestHelper(t)
+	status := NewPlayerStatus()
+	th.Require(status.Song == nil, "new status must not have a song")
+	th.Require(status.Index == -1, "new status must not have a song index")
+	th.RequireFalse(status.Paused, "new status must not be paused")
+}
+
+func TestPlayerStatusLine(t *testing.T) {
+	th := NewTestHelper(t)
+	status := NewPlayerStatus()
+	th.RequireStringEquals(status.StatusLine(), "no song playing", "status line without song")
+
+	status.Song = &SongInfo{Uid: "The-Who--Whos-Next--My-Wife.mp3"}
+	status.Index = 2
+	status.NumberSongs = 10
+	status.PositionSeconds = 83
+	th.RequireStringEquals(status.StatusLine(), "playing 3/10: The-Who--Whos-Next--My-Wife.mp3 (1:23)",
+		"status line while playing")
+
+	status.Paused = true
+	status.SleepSeconds = 600
+	th.RequireStringEquals(status.StatusLine(), "paused 3/10: The-Who--Whos-Next--My-Wife.mp3 (1:23) sleep 10:00",
+		"status line while paused with sleep timer")
+}
+
+func TestPlayerStatusLineWithDuration(t *testing.T) {
+	th := NewTestHelper(t)
+	status := NewPlayerStatus()
+	status.Song = &SongInfo{Uid: "The-Who--Whos-Next--My-Wife.mp3"}
+	status.Index = 0
+	status.NumberSongs = 2
+	status.PositionSeconds = 83
+	status.DurationSeconds = 214
+	th.RequireStringEquals(status.StatusLine(), "playing 1/2: The-Who--Whos-Next--My-Wife.mp3 (1:23/3:34)",
+		"status line with song length")
+}
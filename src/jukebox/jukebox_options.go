@@ -2,13 +2,35 @@ package jukebox
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"time"
 )
 
 type JukeboxOptions struct {
 	DebugMode                bool
 	CheckDataIntegrity       bool
 	FileCacheCount           int
+	DownloadWorkers          int
+	ImportWorkers            int
+	ImportBatchSize          int
+	AfterImport              string
+	WatchIntervalSeconds     int
+	SongCacheMaxBytes        int64
+	StreamSongs              bool
+	AudioPlayer              string
+	AudioPlayerCommand       string
+	HttpAddress              string
+	ControlSocket            string
+	RepeatMode               string
+	ShuffleMode              string
+	ShuffleSeed              int64
+	ArtistGap                int
 	NumberSongs              int
+	StopAfterMinutes         int
+	StopAt                   string
+	PreviewSeconds           int
+	PreviewOffset            int
 	SuppressMetadataDownload bool
 }
 
@@ -17,7 +39,26 @@ func NewJukeboxOptions() *JukeboxOptions {
 	o.DebugMode = false
 	o.CheckDataIntegrity = false
 	o.FileCacheCount = 3
+	o.DownloadWorkers = 2
+	o.ImportWorkers = 4
+	o.ImportBatchSize = 50
+	o.AfterImport = AfterImportKeep
+	o.WatchIntervalSeconds = defaultWatchIntervalSeconds
+	o.SongCacheMaxBytes = defaultSongCacheMaxBytes
+	o.StreamSongs = false
+	o.AudioPlayer = DefaultAudioPlayerName()
+	o.AudioPlayerCommand = ""
+	o.HttpAddress = defaultHttpAddress
+	o.ControlSocket = defaultControlSocket
+	o.RepeatMode = RepeatAll
+	o.ShuffleMode = ShuffleRandom
+	o.ShuffleSeed = 0
+	o.ArtistGap = defaultArtistGap
 	o.NumberSongs = 0
+	o.StopAfterMinutes = 0
+	o.StopAt = ""
+	o.PreviewSeconds = 0
+	o.PreviewOffset = 0
 	o.SuppressMetadataDownload = false
 	return &o
 }
@@ -35,16 +76,147 @@ func (o *JukeboxOptions) Show() {
 	printBoolValue("DebugMode", o.DebugMode)
 	printBoolValue("CheckDataIntegrity", o.CheckDataIntegrity)
 	fmt.Printf("FileCacheCount = %d\n", o.FileCacheCount)
+	fmt.Printf("DownloadWorkers = %d\n", o.DownloadWorkers)
+	fmt.Printf("ImportWorkers = %d\n", o.ImportWorkers)
+	fmt.Printf("ImportBatchSize = %d\n", o.ImportBatchSize)
+	fmt.Printf("AfterImport = %s\n", o.AfterImport)
+	fmt.Printf("WatchIntervalSeconds = %d\n", o.WatchIntervalSeconds)
+	fmt.Printf("SongCacheMaxBytes = %d\n", o.SongCacheMaxBytes)
+	printBoolValue("StreamSongs", o.StreamSongs)
+	fmt.Printf("AudioPlayer = %s\n", o.AudioPlayer)
+	fmt.Printf("AudioPlayerCommand = %s\n", o.AudioPlayerCommand)
+	fmt.Printf("HttpAddress = %s\n", o.HttpAddress)
+	fmt.Printf("ControlSocket = %s\n", o.ControlSocket)
+	fmt.Printf("RepeatMode = %s\n", o.RepeatMode)
+	fmt.Printf("ShuffleMode = %s\n", o.ShuffleMode)
+	fmt.Printf("ShuffleSeed = %d\n", o.ShuffleSeed)
+	fmt.Printf("ArtistGap = %d\n", o.ArtistGap)
 	fmt.Printf("NumberSongs = %d\n", o.NumberSongs)
+	fmt.Printf("StopAfterMinutes = %d\n", o.StopAfterMinutes)
+	fmt.Printf("StopAt = %s\n", o.StopAt)
+	fmt.Printf("PreviewSeconds = %d\n", o.PreviewSeconds)
+	fmt.Printf("PreviewOffset = %d\n", o.PreviewOffset)
 	printBoolValue("SuppressMetadataDownload", o.SuppressMetadataDownload)
 	fmt.Println("========= End JukeboxOptions =========")
 }
 
+// nextTimeOfDay returns the next time (after now) that the clock reads
+// timeOfDay, given as HH:MM.
+func nextTimeOfDay(timeOfDay string, now time.Time) (time.Time, error) {
+	clockTime, err := time.Parse("15:04", timeOfDay)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nextTime := time.Date(now.Year(), now.Month(), now.Day(),
+		clockTime.Hour(), clockTime.Minute(), 0, 0, now.Location())
+	if !nextTime.After(now) {
+		nextTime = nextTime.AddDate(0, 0, 1)
+	}
+	return nextTime, nil
+}
+
+func stringInList(value string, list []string) bool {
+	for _, listValue := range list {
+		if listValue == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (o *JukeboxOptions) ValidateOptions() bool {
 	if o.FileCacheCount < 0 {
 		fmt.Println("error: file cache count must be non-negative integer value")
 		return false
 	}
 
+	if o.SongCacheMaxBytes < 0 {
+		fmt.Println("error: song cache size must be non-negative integer value")
+		return false
+	}
+
+	if !stringInList(o.AudioPlayer, AudioPlayerNames()) {
+		fmt.Printf("error: unknown audio player '%s'\n", o.AudioPlayer)
+		fmt.Printf("supported players: %s\n", strings.Join(AudioPlayerNames(), ", "))
+		return false
+	}
+
+	if o.AudioPlayer == audioPlayerCommand && len(o.AudioPlayerCommand) == 0 {
+		fmt.Println("error: audio player command must be given for command player")
+		return false
+	}
+
+	if len(o.HttpAddress) > 0 {
+		if _, _, err := net.SplitHostPort(o.HttpAddress); err != nil {
+			fmt.Printf("error: invalid http address '%s'\n", o.HttpAddress)
+			return false
+		}
+	}
+
+	if !stringInList(o.RepeatMode, RepeatModes()) {
+		fmt.Printf("error: unknown repeat mode '%s'\n", o.RepeatMode)
+		fmt.Printf("supported repeat modes: %s\n", strings.Join(RepeatModes(), ", "))
+		return false
+	}
+
+	if !stringInList(o.ShuffleMode, ShuffleModes()) {
+		fmt.Printf("error: unknown shuffle mode '%s'\n", o.ShuffleMode)
+		fmt.Printf("supported shuffle modes: %s\n", strings.Join(ShuffleModes(), ", "))
+		return false
+	}
+
+	if o.NumberSongs < 0 {
+		fmt.Println("error: number of songs must be non-negative integer value")
+		return false
+	}
+
+	if o.StopAfterMinutes < 0 {
+		fmt.Println("error: stop after minutes must be non-negative integer value")
+		return false
+	}
+
+	if len(o.StopAt) > 0 {
+		if _, err := nextTimeOfDay(o.StopAt, time.Now()); err != nil {
+			fmt.Printf("error: stop time '%s' must be given as HH:MM\n", o.StopAt)
+			return false
+		}
+	}
+
+	if o.PreviewSeconds < 0 || o.PreviewOffset < 0 {
+		fmt.Println("error: preview seconds and offset must be non-negative integer values")
+		return false
+	}
+
+	if o.ArtistGap < 0 {
+		fmt.Println("error: artist gap must be non-negative integer value")
+		return false
+	}
+
+	if o.DownloadWorkers < 1 {
+		fmt.Println("error: download workers must be a positive integer value")
+		return false
+	}
+
+	if o.ImportWorkers < 1 {
+		fmt.Println("error: import workers must be a positive integer value")
+		return false
+	}
+
+	if o.ImportBatchSize < 1 {
+		fmt.Println("error: import batch size must be a positive integer value")
+		return false
+	}
+
+	if !stringInList(o.AfterImport, AfterImportActions()) {
+		fmt.Printf("error: unknown after import action '%s'\n", o.AfterImport)
+		fmt.Printf("supported after import actions: %s\n", strings.Join(AfterImportActions(), ", "))
+		return false
+	}
+
+	if o.WatchIntervalSeconds < 1 {
+		fmt.Println("error: watch interval must be a positive integer value")
+		return false
+	}
+
 	return true
 }
@@ -0,0 +1,64 @@
+package jukebox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextTimeOfDay(t *testing.T) {
+	th := NewTestHelper(t)
+	now := time.Date(2024, time.March, 9, 22, 30, 0, 0, time.Local)
+
+	stopTime, err := nextTimeOfDay("23:15", now)
+	th.Require(err == nil, "valid time must parse")
+	th.Require(stopTime.Equal(time.Date(2024, time.March, 9, 23, 15, 0, 0, time.Local)),
+		"later time must be today")
+
+	stopTime, err = nextTimeOfDay("07:00", now)
+	th.Require(err == nil, "valid time must parse")
+	th.Require(stopTime.Equal(time.Date(2024, time.March, 10, 7, 0, 0, 0, time.Local)),
+		"earlier time must be tomorrow")
+
+	stopTime, _ = nextTimeOfDay("22:30", now)
+	th.Require(stopTime.After(now), "current time must be tomorrow")
+
+	_, err = nextTimeOfDay("10pm", now)
+	th.Require(err != nil, "invalid time must fail")
+}
+
+func TestValidateOptionsLimits(t *testing.T) {
+	th := NewTestHelper(t)
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+	th.Require(options.ValidateOptions(), "default options must be valid")
+
+	options.StopAt = "25:00"
+	th.RequireFalse(options.ValidateOptions(), "invalid stop time must be rejected")
+	options.StopAt = "06:45"
+	th.Require(options.ValidateOptions(), "valid stop time must be accepted")
+
+	options.NumberSongs = -1
+	th.RequireFalse(options.ValidateOptions(), "negative number of songs must be rejected")
+	options.NumberSongs = 0
+	options.StopAfterMinutes = -5
+	th.RequireFalse(options.ValidateOptions(), "negative minutes must be rejected")
+}
+
+func TestValidateOptionsImport(t *testing.T) {
+	th := NewTestHelper(t)
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+
+	options.ImportWorkers = 0
+	th.RequireFalse(options.ValidateOptions(), "import workers must be positive")
+	options.ImportWorkers = 8
+	options.ImportBatchSize = 0
+	th.RequireFalse(options.ValidateOptions(), "import batch size must be positive")
+	options.ImportBatchSize = 1
+	th.Require(options.ValidateOptions(), "positive import values must be accepted")
+
+	options.AfterImport = "copy"
+	th.RequireFalse(options.ValidateOptions(), "unknown after import action must be rejected")
+	options.AfterImport = AfterImportMove
+	th.Require(options.ValidateOptions(), "known after import action must be accepted")
+}
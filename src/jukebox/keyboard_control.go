@@ -0,0 +1,152 @@
+package jukebox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	keyPauseResume = ' '
+	keyNext        = 'n'
+	keyPrevious    = 'p'
+	keyInfo        = 'i'
+	keyVolumeUp    = '+'
+	keyVolumeDown  = '-'
+	keyQuit        = 'q'
+
+	keyVolumeStep        = 5
+	keyReadTimeoutTenths = 2
+	statusLineInterval   = 1 * time.Second
+
+	// moves to the start of the line and clears it
+	clearLine = "\r\033[K"
+)
+
+// KeyboardControl lets the jukebox be controlled with single key presses
+// while it plays in a terminal, and keeps a status line up to date.
+type KeyboardControl struct {
+	jukebox     *Jukebox
+	input       *os.File
+	output      io.Writer
+	state       *terminalState
+	stopChannel chan bool
+	waitGroup   sync.WaitGroup
+}
+
+func NewKeyboardControl(jukebox *Jukebox, input *os.File, output io.Writer) *KeyboardControl {
+	var keyboardControl KeyboardControl
+	keyboardControl.jukebox = jukebox
+	keyboardControl.input = input
+	keyboardControl.output = output
+	keyboardControl.state = nil
+	keyboardControl.stopChannel = make(chan bool)
+	return &keyboardControl
+}
+
+// Start begins reading keys. returns false (and does nothing) when the
+// input isn't a terminal that the jukebox is in the foreground of.
+func (keyboardControl *KeyboardControl) Start() bool {
+	fd := keyboardControl.input.Fd()
+	if !isTerminal(fd) || !isForegroundTerminal(fd) {
+		return false
+	}
+	state, err := makeKeyReader(fd, keyReadTimeoutTenths)
+	if err != nil {
+		fmt.Println("error: unable to read keys from terminal")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	keyboardControl.state = state
+	fmt.Fprintln(keyboardControl.output,
+		"keys: space pause/resume, n next, p previous, i info, +/- volume, q quit")
+
+	keyboardControl.waitGroup.Add(1)
+	go keyboardControl.readKeys()
+	return true
+}
+
+// Stop stops reading keys and puts the terminal back the way it was.
+func (keyboardControl *KeyboardControl) Stop() {
+	if keyboardControl.state == nil {
+		return
+	}
+	close(keyboardControl.stopChannel)
+	keyboardControl.waitGroup.Wait()
+	restoreTerminal(keyboardControl.input.Fd(), keyboardControl.state)
+	keyboardControl.state = nil
+	fmt.Fprint(keyboardControl.output, clearLine)
+}
+
+func (keyboardControl *KeyboardControl) readKeys() {
+	defer keyboardControl.waitGroup.Done()
+	key := make([]byte, 1)
+	var lastStatusTime time.Time
+	for {
+		select {
+		case <-keyboardControl.stopChannel:
+			return
+		default:
+		}
+
+		// reads give up after a short wait so that stopping is noticed
+		bytesRead, err := keyboardControl.input.Read(key)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return
+		}
+		if bytesRead == 1 {
+			keyboardControl.handleKey(key[0])
+			lastStatusTime = time.Time{}
+		}
+		if time.Since(lastStatusTime) >= statusLineInterval {
+			keyboardControl.showStatusLine()
+			lastStatusTime = time.Now()
+		}
+	}
+}
+
+func (keyboardControl *KeyboardControl) showStatusLine() {
+	if keyboardControl.jukebox.isExitRequested() {
+		return
+	}
+	fmt.Fprintf(keyboardControl.output, "%s%s", clearLine,
+		keyboardControl.jukebox.Status().StatusLine())
+}
+
+// handleKey carries out what the key is for. returns false if the key
+// isn't one of the control keys.
+func (keyboardControl *KeyboardControl) handleKey(key byte) bool {
+	jukebox := keyboardControl.jukebox
+	// anything printed goes on a line of its own, in place of the status
+	// line (which is shown again after the key)
+	fmt.Fprint(keyboardControl.output, clearLine)
+	switch key {
+	case keyPauseResume:
+		jukebox.TogglePausePlay()
+	case keyNext:
+		jukebox.AdvanceToNextSong()
+	case keyPrevious:
+		if !jukebox.PreviousSong() {
+			fmt.Println("no previous song")
+		}
+	case keyInfo:
+		jukebox.DisplayInfo()
+	case keyVolumeUp, '=':
+		// '=' is '+' without shift
+		if jukebox.ChangeVolume(keyVolumeStep) {
+			fmt.Printf("volume %d%%\n", jukebox.Volume())
+		}
+	case keyVolumeDown:
+		if jukebox.ChangeVolume(-keyVolumeStep) {
+			fmt.Printf("volume %d%%\n", jukebox.Volume())
+		}
+	case keyQuit:
+		jukebox.StopPlay()
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package jukebox
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestKeyboardControlNotTerminal(t *testing.T) {
+	th := NewTestHelper(t)
+	input, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+
+	var output strings.Builder
+	keyboardControl := NewKeyboardControl(nil, input, &output)
+	th.RequireFalse(keyboardControl.Start(), "keys must not be read when input isn't a terminal")
+	keyboardControl.Stop()
+	th.RequireStringEquals(output.String(), "", "nothing must be shown when input isn't a terminal")
+}
+
+func TestKeyboardControlKeys(t *testing.T) {
+	th := NewTestHelper(t)
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+	jukebox := newTestJukebox(t, options)
+	jukebox.queue.Reset(newTestQueueSongs("a.mp3", "b.mp3", "c.mp3"))
+
+	var output strings.Builder
+	keyboardControl := NewKeyboardControl(jukebox, os.Stdin, &output)
+
+	th.Require(keyboardControl.handleKey('n'), "n must be a control key")
+	th.Require(jukebox.Status().Index == 1, "n must move to next song")
+	th.Require(keyboardControl.handleKey('p'), "p must be a control key")
+	th.Require(jukebox.Status().Index == 0, "p must move to previous song")
+
+	th.Require(keyboardControl.handleKey(' '), "space must be a control key")
+	th.Require(jukebox.Status().Paused, "space must pause")
+	th.Require(keyboardControl.handleKey(' '), "space must be a control key")
+	th.RequireFalse(jukebox.Status().Paused, "space must resume")
+
+	th.Require(keyboardControl.handleKey('i'), "i must be a control key")
+	th.Require(keyboardControl.handleKey('+'), "+ must be a control key")
+	th.Require(jukebox.Volume() == defaultVolume, "volume must not change without a player that can change it")
+	th.RequireFalse(keyboardControl.handleKey('x'), "x must not be a control key")
+	th.RequireFalse(jukebox.isExitRequested(), "play must go on until q")
+
+	th.Require(keyboardControl.handleKey('q'), "q must be a control key")
+	th.Require(jukebox.isExitRequested(), "q must stop play")
+}
@@ -0,0 +1,253 @@
+package jukebox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+const (
+	mp3FrameHeaderSize = 4
+
+	// how far past the ID3v2 tag to look for the first frame
+	mp3MaxSyncSearch = 64 * 1024
+
+	mpegVersion1   = 3
+	mpegVersion2   = 2
+	mpegVersion2_5 = 0
+
+	mpegLayer1 = 3
+	mpegLayer2 = 2
+	mpegLayer3 = 1
+
+	mpegChannelModeMono = 3
+
+	xingFramesFlag = 0x01
+	xingBytesFlag  = 0x02
+)
+
+// mpegBitrates are the bitrates (kbit/s) that frame headers refer to by
+// index, for MPEG-1 layers 1-3 and MPEG-2/2.5 layer 1 and layers 2-3.
+var mpegBitrates = [5][16]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+// mpegSampleRates are the sample rates of MPEG-1, MPEG-2 and MPEG-2.5.
+var mpegSampleRates = map[int][3]int{
+	mpegVersion1:   {44100, 48000, 32000},
+	mpegVersion2:   {22050, 24000, 16000},
+	mpegVersion2_5: {11025, 12000, 8000},
+}
+
+// mp3Frame is what an MPEG audio frame header says about the frame.
+type mp3Frame struct {
+	version         int
+	layer           int
+	bitrate         int
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	frameSize       int
+}
+
+// parseMp3FrameHeader returns the frame that a 4 byte header describes, or
+// nil if it isn't a valid header.
+func parseMp3FrameHeader(header []byte) *mp3Frame {
+	if len(header) < mp3FrameHeaderSize || header[0] != 0xff || header[1]&0xe0 != 0xe0 {
+		return nil
+	}
+	version := int(header[1]>>3) & 0x03
+	layer := int(header[1]>>1) & 0x03
+	bitrateIndex := int(header[2]>>4) & 0x0f
+	sampleRateIndex := int(header[2]>>2) & 0x03
+	padding := int(header[2]>>1) & 0x01
+	channelMode := int(header[3]>>6) & 0x03
+	// version 1 and layer 0 are reserved, and "free" bitrates can't be
+	// used to find the next frame
+	if version == 1 || layer == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return nil
+	}
+
+	var frame mp3Frame
+	frame.version = version
+	frame.layer = layer
+	frame.sampleRate = mpegSampleRates[version][sampleRateIndex]
+	frame.channels = 2
+	if channelMode == mpegChannelModeMono {
+		frame.channels = 1
+	}
+
+	switch {
+	case version == mpegVersion1 && layer == mpegLayer1:
+		frame.bitrate = mpegBitrates[0][bitrateIndex]
+	case version == mpegVersion1 && layer == mpegLayer2:
+		frame.bitrate = mpegBitrates[1][bitrateIndex]
+	case version == mpegVersion1:
+		frame.bitrate = mpegBitrates[2][bitrateIndex]
+	case layer == mpegLayer1:
+		frame.bitrate = mpegBitrates[3][bitrateIndex]
+	default:
+		frame.bitrate = mpegBitrates[4][bitrateIndex]
+	}
+
+	switch {
+	case layer == mpegLayer1:
+		frame.samplesPerFrame = 384
+		frame.frameSize = (12*frame.bitrate*1000/frame.sampleRate + padding) * 4
+	case layer == mpegLayer3 && version != mpegVersion1:
+		frame.samplesPerFrame = 576
+		frame.frameSize = 72*frame.bitrate*1000/frame.sampleRate + padding
+	default:
+		frame.samplesPerFrame = 1152
+		frame.frameSize = 144*frame.bitrate*1000/frame.sampleRate + padding
+	}
+	return &frame
+}
+
+// sideInfoSize returns the size of the layer 3 side information that
+// comes after the frame header, which is where a Xing header goes.
+func (frame *mp3Frame) sideInfoSize() int {
+	if frame.version == mpegVersion1 {
+		if frame.channels == 1 {
+			return 17
+		}
+		return 32
+	}
+	if frame.channels == 1 {
+		return 9
+	}
+	return 17
+}
+
+// readMp3Properties finds the first frame after the ID3v2 tag. the length
+// comes from the Xing (or Info) or VBRI header in that frame if there is
+// one, otherwise all the frames are counted.
+func readMp3Properties(file io.ReaderAt, fileSize int64) *AudioProperties {
+	audioEnd := fileSize
+	id3v1Header := make([]byte, 3)
+	if fileSize >= id3v1TagSize {
+		if _, err := file.ReadAt(id3v1Header, fileSize-id3v1TagSize); err == nil &&
+			bytes.Equal(id3v1Header, []byte("TAG")) {
+			audioEnd -= id3v1TagSize
+		}
+	}
+
+	frameOffset, frame := findFirstMp3Frame(file, id3v2TagSize(file), audioEnd)
+	if frame == nil {
+		return nil
+	}
+	properties := NewAudioProperties()
+	properties.SampleRate = frame.sampleRate
+	properties.Channels = frame.channels
+
+	firstFrame := make([]byte, frame.frameSize)
+	if _, err := file.ReadAt(firstFrame, frameOffset); err != nil && err != io.EOF {
+		return nil
+	}
+	if frames, audioBytes, found := readMp3VbrHeader(firstFrame, frame); found && frames > 0 {
+		properties.DurationMs = int64(frames) * int64(frame.samplesPerFrame) * 1000 / int64(frame.sampleRate)
+		if audioBytes == 0 {
+			audioBytes = audioEnd - frameOffset
+		}
+		properties.setBitrate(audioBytes)
+		return properties
+	}
+
+	// no VBR header, so add up the frames
+	reader := bufio.NewReader(io.NewSectionReader(file, frameOffset, audioEnd-frameOffset))
+	header := make([]byte, mp3FrameHeaderSize)
+	var totalSamples int64
+	var audioBytes int64
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+		nextFrame := parseMp3FrameHeader(header)
+		if nextFrame == nil || nextFrame.sampleRate != frame.sampleRate {
+			break
+		}
+		if _, err := reader.Discard(nextFrame.frameSize - mp3FrameHeaderSize); err != nil {
+			break
+		}
+		totalSamples += int64(nextFrame.samplesPerFrame)
+		audioBytes += int64(nextFrame.frameSize)
+	}
+	if totalSamples == 0 {
+		return nil
+	}
+	properties.DurationMs = totalSamples * 1000 / int64(frame.sampleRate)
+	properties.setBitrate(audioBytes)
+	return properties
+}
+
+// findFirstMp3Frame looks for a frame header that's followed by another
+// frame header (or the end of the audio), so that data that happens to
+// look like a header isn't taken for the first frame.
+func findFirstMp3Frame(file io.ReaderAt, start int64, end int64) (int64, *mp3Frame) {
+	searchSize := end - start
+	if searchSize > mp3MaxSyncSearch {
+		searchSize = mp3MaxSyncSearch
+	}
+	if searchSize < mp3FrameHeaderSize {
+		return 0, nil
+	}
+	data := make([]byte, searchSize)
+	if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+		return 0, nil
+	}
+
+	nextHeader := make([]byte, mp3FrameHeaderSize)
+	for pos := 0; pos+mp3FrameHeaderSize <= len(data); pos++ {
+		frame := parseMp3FrameHeader(data[pos:])
+		if frame == nil {
+			continue
+		}
+		nextOffset := start + int64(pos) + int64(frame.frameSize)
+		if nextOffset == end {
+			return start + int64(pos), frame
+		}
+		if _, err := file.ReadAt(nextHeader, nextOffset); err == nil && parseMp3FrameHeader(nextHeader) != nil {
+			return start + int64(pos), frame
+		}
+	}
+	return 0, nil
+}
+
+// readMp3VbrHeader reads the number of frames and bytes from a Xing (or
+// Info, which is what some encoders call it in constant bitrate files) or
+// VBRI header in the first frame.
+func readMp3VbrHeader(firstFrame []byte, frame *mp3Frame) (int, int64, bool) {
+	xingOffset := mp3FrameHeaderSize + frame.sideInfoSize()
+	if len(firstFrame) >= xingOffset+8 {
+		xing := firstFrame[xingOffset:]
+		if bytes.HasPrefix(xing, []byte("Xing")) || bytes.HasPrefix(xing, []byte("Info")) {
+			flags := binary.BigEndian.Uint32(xing[4:8])
+			xing = xing[8:]
+			frames := 0
+			var audioBytes int64
+			if flags&xingFramesFlag != 0 && len(xing) >= 4 {
+				frames = int(binary.BigEndian.Uint32(xing[0:4]))
+				xing = xing[4:]
+			}
+			if flags&xingBytesFlag != 0 && len(xing) >= 4 {
+				audioBytes = int64(binary.BigEndian.Uint32(xing[0:4]))
+			}
+			return frames, audioBytes, true
+		}
+	}
+
+	// VBRI always comes 32 bytes after the header
+	vbriOffset := mp3FrameHeaderSize + 32
+	if len(firstFrame) >= vbriOffset+18 && bytes.HasPrefix(firstFrame[vbriOffset:], []byte("VBRI")) {
+		vbri := firstFrame[vbriOffset:]
+		audioBytes := int64(binary.BigEndian.Uint32(vbri[10:14]))
+		frames := int(binary.BigEndian.Uint32(vbri[14:18]))
+		return frames, audioBytes, true
+	}
+	return 0, 0, false
+}
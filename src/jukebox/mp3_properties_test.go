@@ -0,0 +1,91 @@
+package jukebox
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// readFixtureMp3Properties reads the audio properties of an mp3 file in
+// testdata/tags.
+func readFixtureMp3Properties(t *testing.T, fileName string) *AudioProperties {
+	contents, err := os.ReadFile(tagFixturePath(fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readMp3Properties(bytes.NewReader(contents), int64(len(contents)))
+}
+
+func TestParseMp3FrameHeader(t *testing.T) {
+	th := NewTestHelper(t)
+	frame := parseMp3FrameHeader([]byte{0xff, 0xfb, 0x92, 0x44})
+	th.Require(frame != nil, "MPEG-1 layer 3 header")
+	if frame != nil {
+		th.Require(frame.bitrate == 128 && frame.sampleRate == 44100, "bitrate and sample rate")
+		th.Require(frame.channels == 2, "joint stereo")
+		th.Require(frame.samplesPerFrame == 1152, "samples per frame")
+		th.Require(frame.frameSize == 418, "frame size with padding")
+	}
+
+	frame = parseMp3FrameHeader([]byte{0xff, 0xf3, 0x80, 0xc4})
+	th.Require(frame != nil, "MPEG-2 layer 3 header")
+	if frame != nil {
+		th.Require(frame.bitrate == 64 && frame.sampleRate == 22050, "bitrate and sample rate")
+		th.Require(frame.channels == 1, "mono")
+		th.Require(frame.samplesPerFrame == 576, "samples per frame")
+		th.Require(frame.frameSize == 208, "frame size")
+	}
+
+	th.Require(parseMp3FrameHeader([]byte{0xff, 0xfb, 0xf0, 0x44}) == nil, "bad bitrate")
+	th.Require(parseMp3FrameHeader([]byte{0xff, 0xfb, 0x9c, 0x44}) == nil, "bad sample rate")
+	th.Require(parseMp3FrameHeader([]byte{0xff, 0xe9, 0x90, 0x44}) == nil, "reserved version")
+	th.Require(parseMp3FrameHeader([]byte{'I', 'D', '3', 0x03}) == nil, "not a frame")
+}
+
+func TestReadMp3PropertiesXing(t *testing.T) {
+	th := NewTestHelper(t)
+	properties := readFixtureMp3Properties(t, "xing.mp3")
+	th.Require(properties != nil, "mp3 with Xing header")
+	if properties != nil {
+		th.Require(properties.DurationMs == 26122, "duration from number of frames")
+		th.Require(properties.Bitrate == 128, "bitrate from number of bytes")
+		th.Require(properties.SampleRate == 44100, "sample rate")
+		th.Require(properties.Channels == 2, "channels")
+	}
+}
+
+func TestReadMp3PropertiesVbri(t *testing.T) {
+	th := NewTestHelper(t)
+	properties := readFixtureMp3Properties(t, "vbri.mp3")
+	th.Require(properties != nil, "mp3 with VBRI header")
+	if properties != nil {
+		th.Require(properties.DurationMs == 52244, "duration from number of frames")
+		th.Require(properties.Bitrate == 459, "bitrate from number of bytes")
+	}
+}
+
+func TestReadMp3PropertiesFrameScan(t *testing.T) {
+	th := NewTestHelper(t)
+	properties := readFixtureMp3Properties(t, "cbr.mp3")
+	th.Require(properties != nil, "mp3 without VBR header")
+	if properties != nil {
+		th.Require(properties.DurationMs == 2612, "duration from counting frames")
+		th.Require(properties.Bitrate == 128, "bitrate")
+		th.Require(properties.SampleRate == 44100, "sample rate")
+	}
+
+	properties = readFixtureMp3Properties(t, "mpeg2_mono.mp3")
+	th.Require(properties != nil, "MPEG-2 mp3")
+	if properties != nil {
+		th.Require(properties.DurationMs == 1306, "duration from counting frames")
+		th.Require(properties.Bitrate == 64, "bitrate")
+		th.Require(properties.SampleRate == 22050, "sample rate")
+		th.Require(properties.Channels == 1, "channels")
+	}
+}
+
+func TestReadMp3PropertiesNoFrames(t *testing.T) {
+	th := NewTestHelper(t)
+	th.Require(readFixtureMp3Properties(t, "untagged.mp3") == nil, "data that only looks like frames")
+	th.Require(readMp3Properties(bytes.NewReader([]byte("abc")), 3) == nil, "tiny file")
+}
@@ -0,0 +1,250 @@
+package jukebox
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	mp4AtomHeaderSize   = 8
+	mp4MetaVersionSize  = 4
+	mp4DataPrefixSize   = 8
+	mp4MaxItemListBytes = 16 * 1024 * 1024
+
+	mp4MovieHeaderV0Size = 20
+	mp4MovieHeaderV1Size = 32
+
+	// the stsd header and an audio sample entry up to its sample rate
+	mp4SampleDescriptionSize = 8 + 8 + 28
+)
+
+// mp4ItemFields are the fields that the text items in an MP4 item list
+// ("ilst") are for.
+var mp4ItemFields = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "album-artist",
+	"\xa9alb": "album",
+	"\xa9day": "year",
+	"\xa9gen": "genre",
+}
+
+// mp4Atom is where an atom's data is in the file.
+type mp4Atom struct {
+	atomType   string
+	dataOffset int64
+	dataSize   int64
+}
+
+// readMp4Tags reads the items in moov/udta/meta/ilst. only the atoms on
+// that path are read, so the audio data isn't.
+func readMp4Tags(file io.ReaderAt, fileSize int64) *AudioTags {
+	atom := findMp4Path(file, fileSize, "moov", "udta", "meta", "ilst")
+	if atom == nil || atom.dataSize > mp4MaxItemListBytes {
+		return nil
+	}
+
+	itemList := make([]byte, atom.dataSize)
+	if _, err := file.ReadAt(itemList, atom.dataOffset); err != nil {
+		return nil
+	}
+	tags := NewAudioTags()
+	for len(itemList) >= mp4AtomHeaderSize {
+		itemSize := int(binary.BigEndian.Uint32(itemList[0:4]))
+		if itemSize < mp4AtomHeaderSize || itemSize > len(itemList) {
+			break
+		}
+		itemType := string(itemList[4:8])
+		value := mp4ItemData(itemList[mp4AtomHeaderSize:itemSize])
+		itemList = itemList[itemSize:]
+		if value == nil {
+			continue
+		}
+
+		if field, isText := mp4ItemFields[itemType]; isText {
+			tags.setField(field, string(value))
+			continue
+		}
+		switch itemType {
+		case "trkn", "disk":
+			// 2 bytes of padding, the number, then the total
+			if len(value) >= 4 {
+				number := int(binary.BigEndian.Uint16(value[2:4]))
+				if itemType == "trkn" && tags.TrackNumber == 0 {
+					tags.TrackNumber = number
+				} else if itemType == "disk" && tags.DiscNumber == 0 {
+					tags.DiscNumber = number
+				}
+			}
+		case "gnre":
+			// the ID3v1 genre number plus 1
+			if len(value) >= 2 {
+				tags.setField("genre", id3GenreName(int(binary.BigEndian.Uint16(value[0:2]))-1))
+			}
+		}
+	}
+	return tags
+}
+
+// findMp4Path returns the atom at the end of a path of atom types (e.g.,
+// "moov", "mvhd"), or nil if there isn't one.
+func findMp4Path(file io.ReaderAt, fileSize int64, atomTypes ...string) *mp4Atom {
+	atom := &mp4Atom{atomType: "", dataOffset: 0, dataSize: fileSize}
+	for _, atomType := range atomTypes {
+		atom = findMp4Atom(file, atom, atomType)
+		if atom == nil {
+			return nil
+		}
+	}
+	return atom
+}
+
+// findMp4Atom returns the first child of parent of type atomType, or nil
+// if there isn't one.
+func findMp4Atom(file io.ReaderAt, parent *mp4Atom, atomType string) *mp4Atom {
+	for _, atom := range mp4ChildAtoms(file, parent) {
+		if atom.atomType == atomType {
+			return atom
+		}
+	}
+	return nil
+}
+
+// mp4ChildAtoms returns the atoms inside parent, stopping at the first one
+// that doesn't fit.
+func mp4ChildAtoms(file io.ReaderAt, parent *mp4Atom) []*mp4Atom {
+	atoms := []*mp4Atom{}
+	offset := parent.dataOffset
+	if parent.atomType == "meta" {
+		// meta has a version and flags before its children
+		offset += mp4MetaVersionSize
+	}
+	end := parent.dataOffset + parent.dataSize
+	header := make([]byte, mp4AtomHeaderSize)
+	for offset+mp4AtomHeaderSize <= end {
+		if _, err := file.ReadAt(header, offset); err != nil {
+			return atoms
+		}
+		atomSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(mp4AtomHeaderSize)
+		switch atomSize {
+		case 0:
+			// the atom goes to the end
+			atomSize = end - offset
+		case 1:
+			// the size is in the 8 bytes after the type
+			largeSize := make([]byte, 8)
+			if _, err := file.ReadAt(largeSize, offset+mp4AtomHeaderSize); err != nil {
+				return atoms
+			}
+			atomSize = int64(binary.BigEndian.Uint64(largeSize))
+			headerSize += 8
+		}
+		if atomSize < headerSize || offset+atomSize > end {
+			return atoms
+		}
+		atoms = append(atoms, &mp4Atom{
+			atomType:   string(header[4:8]),
+			dataOffset: offset + headerSize,
+			dataSize:   atomSize - headerSize,
+		})
+		offset += atomSize
+	}
+	return atoms
+}
+
+// readMp4Properties reads the duration from the movie header (mvhd) and
+// the sample rate and channels from the sample description (stsd) of the
+// first sound track.
+func readMp4Properties(file io.ReaderAt, fileSize int64) *AudioProperties {
+	mvhd := findMp4Path(file, fileSize, "moov", "mvhd")
+	if mvhd == nil || mvhd.dataSize < mp4MovieHeaderV0Size {
+		return nil
+	}
+	header := make([]byte, mp4MovieHeaderV1Size)
+	if mvhd.dataSize < mp4MovieHeaderV1Size {
+		header = header[:mp4MovieHeaderV0Size]
+	}
+	if _, err := file.ReadAt(header, mvhd.dataOffset); err != nil {
+		return nil
+	}
+
+	// version 1 headers have 64 bit times and duration
+	var timeScale int64
+	var duration int64
+	if header[0] == 1 {
+		if len(header) < mp4MovieHeaderV1Size {
+			return nil
+		}
+		timeScale = int64(binary.BigEndian.Uint32(header[20:24]))
+		duration = int64(binary.BigEndian.Uint64(header[24:32]))
+	} else {
+		timeScale = int64(binary.BigEndian.Uint32(header[12:16]))
+		duration = int64(binary.BigEndian.Uint32(header[16:20]))
+	}
+	if timeScale == 0 {
+		return nil
+	}
+	properties := NewAudioProperties()
+	properties.DurationMs = duration * 1000 / timeScale
+
+	moov := findMp4Path(file, fileSize, "moov")
+	for _, trak := range mp4ChildAtoms(file, moov) {
+		if trak.atomType != "trak" {
+			continue
+		}
+		stbl := trak
+		for _, atomType := range []string{"mdia", "minf", "stbl"} {
+			if stbl != nil {
+				stbl = findMp4Atom(file, stbl, atomType)
+			}
+		}
+		if stbl != nil && readMp4SampleDescription(file, stbl, properties) {
+			break
+		}
+	}
+
+	if mdat := findMp4Path(file, fileSize, "mdat"); mdat != nil {
+		properties.setBitrate(mdat.dataSize)
+	} else {
+		properties.setBitrate(fileSize)
+	}
+	return properties
+}
+
+// readMp4SampleDescription reads the channels and sample rate from the
+// first entry of a sound track's stsd atom. returns false if the track
+// isn't a sound track.
+func readMp4SampleDescription(file io.ReaderAt, stbl *mp4Atom, properties *AudioProperties) bool {
+	stsd := findMp4Atom(file, stbl, "stsd")
+	if stsd == nil || stsd.dataSize < mp4SampleDescriptionSize {
+		return false
+	}
+	// the stsd version, flags and entry count come before the first entry
+	entry := make([]byte, mp4SampleDescriptionSize)
+	if _, err := file.ReadAt(entry, stsd.dataOffset); err != nil {
+		return false
+	}
+	entryType := string(entry[12:16])
+	if entryType != "mp4a" && entryType != "alac" && entryType != "fLaC" && entryType != ".mp3" {
+		return false
+	}
+	sampleEntry := entry[16:]
+	properties.Channels = int(binary.BigEndian.Uint16(sampleEntry[16:18]))
+	// the sample rate is 16.16 fixed point
+	properties.SampleRate = int(binary.BigEndian.Uint16(sampleEntry[24:26]))
+	return true
+}
+
+// mp4ItemData returns the value in an item's "data" atom, which comes
+// after 4 bytes of type and 4 bytes of locale.
+func mp4ItemData(item []byte) []byte {
+	if len(item) < mp4AtomHeaderSize+mp4DataPrefixSize || string(item[4:8]) != "data" {
+		return nil
+	}
+	dataSize := int(binary.BigEndian.Uint32(item[0:4]))
+	if dataSize < mp4AtomHeaderSize+mp4DataPrefixSize || dataSize > len(item) {
+		return nil
+	}
+	return item[mp4AtomHeaderSize+mp4DataPrefixSize : dataSize]
+}
@@ -0,0 +1,55 @@
+package jukebox
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReadMp4Tags(t *testing.T) {
+	th := NewTestHelper(t)
+	contents, err := os.ReadFile(tagFixturePath("atoms.m4a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := readMp4Tags(bytes.NewReader(contents), int64(len(contents)))
+	th.Require(tags != nil, "m4a file must have tags")
+	if tags != nil {
+		th.RequireStringEquals(tags.Artist, "Queen", "artist")
+		th.RequireStringEquals(tags.AlbumArtist, "Queen", "album artist")
+		th.RequireStringEquals(tags.Album, "A Night at the Opera", "album")
+		th.RequireStringEquals(tags.Title, "Bohemian Rhapsody", "title")
+		th.Require(tags.TrackNumber == 11, "track number")
+		th.Require(tags.DiscNumber == 1, "disc number")
+		th.Require(tags.Year == 1975, "year from timestamp")
+		th.RequireStringEquals(tags.Genre, "Rock", "numbered genre")
+	}
+}
+
+func TestReadMp4TagsNoItems(t *testing.T) {
+	th := NewTestHelper(t)
+	noMoov := []byte("\x00\x00\x00\x10ftypM4A \x00\x00\x02\x00")
+	th.Require(readMp4Tags(bytes.NewReader(noMoov), int64(len(noMoov))) == nil, "file without moov atom")
+
+	// an atom that says it's bigger than the file
+	badSize := []byte("\x00\x00\x01\x00moov")
+	th.Require(readMp4Tags(bytes.NewReader(badSize), int64(len(badSize))) == nil, "atom past end of file")
+}
+
+func TestReadMp4Properties(t *testing.T) {
+	th := NewTestHelper(t)
+	contents, err := os.ReadFile(tagFixturePath("atoms.m4a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	properties := readMp4Properties(bytes.NewReader(contents), int64(len(contents)))
+	th.Require(properties != nil, "m4a file must have properties")
+	if properties != nil {
+		th.Require(properties.DurationMs == 354320, "duration from movie header")
+		th.Require(properties.SampleRate == 48000, "sample rate from sound track")
+		th.Require(properties.Channels == 2, "channels from sound track")
+		th.Require(properties.Bitrate == 0, "mdat is too small for a bitrate")
+	}
+}
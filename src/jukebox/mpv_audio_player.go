@@ -0,0 +1,165 @@
+package jukebox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var errMpvNotPlaying = errors.New("mpv is not playing")
+
+// MpvAudioPlayer runs mpv with a JSON IPC socket so that pausing,
+// seeking, volume changes and skipping to the next song happen inside
+// the running player instead of by killing and restarting it.
+type MpvAudioPlayer struct {
+	command    *CommandAudioPlayer
+	socketPath string
+	mutex      sync.Mutex
+	client     *MpvIpcClient
+}
+
+func defaultMpvSocketPath() string {
+	return PathJoin(os.TempDir(), fmt.Sprintf("jukebox-mpv-%d.sock", os.Getpid()))
+}
+
+func NewMpvAudioPlayer(exeFileName string, socketPath string) *MpvAudioPlayer {
+	var player MpvAudioPlayer
+	player.socketPath = socketPath
+	player.command = NewCommandAudioPlayer(exeFileName,
+		[]string{"--no-video",
+			"--really-quiet",
+			"--input-ipc-server=" + socketPath,
+			"--start={offset}",
+			"{file}"},
+		true)
+	player.command.SetDurationArgs([]string{"--length={duration}"})
+	return &player
+}
+
+func (player *MpvAudioPlayer) SetPlayDuration(seconds int) bool {
+	return player.command.SetPlayDuration(seconds)
+}
+
+func (player *MpvAudioPlayer) ReadsStdin() bool {
+	return true
+}
+
+func (player *MpvAudioPlayer) SupportsStartOffset() bool {
+	return true
+}
+
+func (player *MpvAudioPlayer) Play(filePath string) error {
+	return player.PlayFrom(filePath, 0)
+}
+
+func (player *MpvAudioPlayer) PlayFrom(filePath string, offsetSeconds int) error {
+	err := player.command.PlayFrom(filePath, offsetSeconds)
+	player.closeClient()
+	return err
+}
+
+func (player *MpvAudioPlayer) PlayStream(input io.Reader) error {
+	err := player.command.PlayStream(input)
+	player.closeClient()
+	return err
+}
+
+func (player *MpvAudioPlayer) closeClient() {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	if player.client != nil {
+		player.client.Close()
+		player.client = nil
+	}
+}
+
+// ipcClient connects to mpv if it's running. mpv creates its socket
+// shortly after it starts, so the connection is retried for a while.
+func (player *MpvAudioPlayer) ipcClient() (*MpvIpcClient, error) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	if player.client != nil {
+		return player.client, nil
+	}
+
+	deadline := time.Now().Add(mpvIpcTimeout)
+	for {
+		if !player.command.IsPlaying() {
+			return nil, errMpvNotPlaying
+		}
+		client, err := NewMpvIpcClient(player.socketPath)
+		if err == nil {
+			player.client = client
+			return client, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Stop asks mpv to end the song, and only kills it if that fails.
+func (player *MpvAudioPlayer) Stop() error {
+	client, err := player.ipcClient()
+	if err == nil {
+		err = client.Next()
+	}
+	if err != nil {
+		return player.command.Stop()
+	}
+	return nil
+}
+
+func (player *MpvAudioPlayer) Pause() error {
+	client, err := player.ipcClient()
+	if err != nil {
+		return err
+	}
+	return client.SetPause(true)
+}
+
+func (player *MpvAudioPlayer) Resume() error {
+	client, err := player.ipcClient()
+	if err != nil {
+		return err
+	}
+	return client.SetPause(false)
+}
+
+func (player *MpvAudioPlayer) Seek(offsetSeconds int) error {
+	client, err := player.ipcClient()
+	if err != nil {
+		return err
+	}
+	return client.Seek(float64(offsetSeconds))
+}
+
+func (player *MpvAudioPlayer) SetVolume(percent int) error {
+	client, err := player.ipcClient()
+	if err != nil {
+		return err
+	}
+	return client.SetVolume(percent)
+}
+
+// Position returns the seconds played and the length of the song.
+func (player *MpvAudioPlayer) Position() (float64, float64, error) {
+	client, err := player.ipcClient()
+	if err != nil {
+		return 0, 0, err
+	}
+	timePos, err := client.TimePos()
+	if err != nil {
+		return 0, 0, err
+	}
+	duration, err := client.Duration()
+	if err != nil {
+		return 0, 0, err
+	}
+	return timePos, duration, nil
+}
@@ -0,0 +1,118 @@
+package jukebox
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// newTestMpvPlayer returns an mpv player that runs a stand-in for mpv
+// (it just sleeps) and talks to a fake IPC server. stopping a song in
+// the fake server ends the stand-in, like mpv does.
+func newTestMpvPlayer(t *testing.T) (*MpvAudioPlayer, *fakeMpvServer) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell and unix sockets")
+	}
+	testDir := t.TempDir()
+	exePath := PathJoin(testDir, "mpv")
+	FileWriteAllText(exePath, "#!/bin/sh\nexec sleep 30\n")
+	os.Chmod(exePath, 0755)
+
+	player := NewMpvAudioPlayer(exePath, PathJoin(testDir, "mpv.sock"))
+	fake := newFakeMpvServer(t, player.socketPath)
+	fake.onCommand = func(command []interface{}) {
+		if command[0] == "stop" {
+			player.command.Stop()
+		}
+	}
+	return player, fake
+}
+
+func waitForPlaying(t *testing.T, player *MpvAudioPlayer) {
+	for i := 0; i < 500; i++ {
+		if player.command.IsPlaying() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("player wasn't started")
+}
+
+func TestMpvAudioPlayer(t *testing.T) {
+	th := NewTestHelper(t)
+	player, fake := newTestMpvPlayer(t)
+
+	th.Require(player.Pause() == errMpvNotPlaying, "player that isn't playing can't be paused")
+	args := player.command.commandArgs("song.mp3", 30)
+	th.Require(len(args) == 5, "mpv must be given IPC socket")
+	th.RequireStringEquals(args[2], "--input-ipc-server="+player.socketPath, "mpv must use IPC socket")
+	th.RequireStringEquals(args[3], "--start=30", "mpv must start at offset")
+
+	playDone := make(chan error)
+	go func() {
+		playDone <- player.Play("song.mp3")
+	}()
+	waitForPlaying(t, player)
+
+	th.Require(player.Pause() == nil, "Pause must succeed")
+	th.Require(fake.property("pause") == true, "mpv must be paused")
+	th.Require(player.Resume() == nil, "Resume must succeed")
+	th.Require(fake.property("pause") == false, "mpv must be resumed")
+	th.Require(player.SetVolume(70) == nil, "SetVolume must succeed")
+	th.Require(fake.property("volume") == 70.0, "mpv volume must be set")
+	th.Require(player.Seek(42) == nil, "Seek must succeed")
+	timePos, duration, err := player.Position()
+	th.Require(err == nil && timePos == 42 && duration == 185, "Position must come from mpv")
+
+	th.Require(player.Stop() == nil, "Stop must succeed")
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop must end the song")
+	}
+}
+
+func TestJukeboxWithMpvAudioPlayer(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, NewJukeboxOptions())
+	importTestSongs(t, jukebox, map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3": "my wife audio",
+	})
+	player, fake := newTestMpvPlayer(t)
+	jukebox.setAudioPlayer(player)
+
+	song := jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--My-Wife.mp3")
+	jukebox.queue.Reset([]*SongMetadata{song})
+	th.Require(jukebox.downloadSong(song), "downloadSong must succeed")
+
+	playDone := make(chan bool)
+	go func() {
+		jukebox.playSong(song)
+		playDone <- true
+	}()
+	waitForPlaying(t, player)
+
+	// pausing happens in mpv, so the player keeps running
+	jukebox.TogglePausePlay()
+	th.Require(jukebox.isPausedNow(), "jukebox must be paused")
+	th.Require(fake.property("pause") == true, "mpv must be paused")
+	th.Require(player.command.IsPlaying(), "mpv must not be stopped by pause")
+	th.Require(jukebox.currentSongPosition() == 12, "position must come from mpv")
+
+	th.Require(jukebox.SetVolume(55), "SetVolume must succeed")
+	th.Require(fake.property("volume") == 55.0, "mpv volume must be set")
+	th.Require(jukebox.SeekTo(100), "SeekTo must succeed")
+	th.Require(jukebox.currentSongPosition() == 100, "position must follow seek")
+
+	// advancing while paused resumes mpv before ending the song
+	jukebox.AdvanceToNextSong()
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AdvanceToNextSong must end the song")
+	}
+	th.RequireFalse(jukebox.isPausedNow(), "jukebox must not stay paused")
+	th.Require(fake.property("pause") == false, "mpv must be resumed")
+	th.RequireFalse(FileExists(jukebox.songPathInPlaylist(song)), "played song must be deleted")
+}
@@ -0,0 +1,137 @@
+package jukebox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const mpvIpcTimeout = 2 * time.Second
+
+// MpvIpcClient sends commands to a running mpv over its JSON IPC socket
+// (see mpv's --input-ipc-server option). each command is a JSON object
+// on its own line; replies are matched to commands by request_id and
+// anything else mpv sends (e.g., events) is skipped.
+type MpvIpcClient struct {
+	conn          net.Conn
+	reader        *bufio.Reader
+	mutex         sync.Mutex
+	nextRequestId int
+}
+
+type mpvIpcRequest struct {
+	Command   []interface{} `json:"command"`
+	RequestId int           `json:"request_id"`
+}
+
+type mpvIpcResponse struct {
+	Data      json.RawMessage `json:"data"`
+	Error     string          `json:"error"`
+	RequestId *int            `json:"request_id"`
+	Event     string          `json:"event"`
+}
+
+func NewMpvIpcClient(socketPath string) (*MpvIpcClient, error) {
+	conn, err := net.DialTimeout("unix", socketPath, mpvIpcTimeout)
+	if err != nil {
+		return nil, err
+	}
+	var client MpvIpcClient
+	client.conn = conn
+	client.reader = bufio.NewReader(conn)
+	client.nextRequestId = 1
+	return &client, nil
+}
+
+func (client *MpvIpcClient) Close() error {
+	return client.conn.Close()
+}
+
+// Command runs an mpv command (e.g., "seek", 30, "absolute") and returns
+// the data of the reply.
+func (client *MpvIpcClient) Command(args ...interface{}) (json.RawMessage, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	request := mpvIpcRequest{Command: args, RequestId: client.nextRequestId}
+	client.nextRequestId += 1
+
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	client.conn.SetDeadline(time.Now().Add(mpvIpcTimeout))
+	defer client.conn.SetDeadline(time.Time{})
+
+	_, err = client.conn.Write(append(requestBytes, '\n'))
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		line, err := client.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		var response mpvIpcResponse
+		if json.Unmarshal(line, &response) != nil {
+			continue
+		}
+		if response.RequestId == nil || *response.RequestId != request.RequestId {
+			// an event, or the reply to a command that timed out
+			continue
+		}
+		if response.Error != "success" {
+			return nil, fmt.Errorf("mpv command %v failed: %s", args, response.Error)
+		}
+		return response.Data, nil
+	}
+}
+
+func (client *MpvIpcClient) SetProperty(name string, value interface{}) error {
+	_, err := client.Command("set_property", name, value)
+	return err
+}
+
+func (client *MpvIpcClient) GetFloatProperty(name string) (float64, error) {
+	data, err := client.Command("get_property", name)
+	if err != nil {
+		return 0, err
+	}
+	var value float64
+	if len(data) == 0 || json.Unmarshal(data, &value) != nil {
+		return 0, errors.New("mpv property '" + name + "' is not a number")
+	}
+	return value, nil
+}
+
+func (client *MpvIpcClient) SetPause(paused bool) error {
+	return client.SetProperty("pause", paused)
+}
+
+func (client *MpvIpcClient) Seek(seconds float64) error {
+	_, err := client.Command("seek", seconds, "absolute")
+	return err
+}
+
+func (client *MpvIpcClient) SetVolume(percent int) error {
+	return client.SetProperty("volume", percent)
+}
+
+// Next ends the current song.
+func (client *MpvIpcClient) Next() error {
+	_, err := client.Command("stop")
+	return err
+}
+
+func (client *MpvIpcClient) TimePos() (float64, error) {
+	return client.GetFloatProperty("time-pos")
+}
+
+func (client *MpvIpcClient) Duration() (float64, error) {
+	return client.GetFloatProperty("duration")
+}
@@ -0,0 +1,143 @@
+package jukebox
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeMpvServer speaks enough of mpv's JSON IPC protocol for tests. it
+// keeps the properties that are set, and sends an event ahead of every
+// reply like mpv does when something changes.
+type fakeMpvServer struct {
+	listener   net.Listener
+	socketPath string
+	mutex      sync.Mutex
+	properties map[string]interface{}
+	commands   [][]interface{}
+	// onCommand (when set) is called for every command received
+	onCommand func(command []interface{})
+}
+
+func newFakeMpvServer(t *testing.T, socketPath string) *fakeMpvServer {
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("unable to listen on %s: %v", socketPath, err)
+	}
+	fake := &fakeMpvServer{
+		listener:   listener,
+		socketPath: socketPath,
+		properties: map[string]interface{}{
+			"pause":    false,
+			"volume":   100.0,
+			"time-pos": 12.5,
+			"duration": 185.0,
+		},
+	}
+	go fake.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return fake
+}
+
+func (fake *fakeMpvServer) serve() {
+	for {
+		conn, err := fake.listener.Accept()
+		if err != nil {
+			return
+		}
+		go fake.handle(conn)
+	}
+}
+
+func (fake *fakeMpvServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var request mpvIpcRequest
+		if json.Unmarshal(line, &request) != nil || len(request.Command) == 0 {
+			continue
+		}
+
+		fake.mutex.Lock()
+		fake.commands = append(fake.commands, request.Command)
+		onCommand := fake.onCommand
+		response := map[string]interface{}{"request_id": request.RequestId, "error": "success"}
+		switch request.Command[0] {
+		case "get_property":
+			value, ok := fake.properties[request.Command[1].(string)]
+			if ok {
+				response["data"] = value
+			} else {
+				response["error"] = "property not found"
+			}
+		case "set_property":
+			fake.properties[request.Command[1].(string)] = request.Command[2]
+		case "seek":
+			fake.properties["time-pos"] = request.Command[1]
+		case "stop":
+		default:
+			response["error"] = "invalid parameter"
+		}
+		fake.mutex.Unlock()
+
+		if onCommand != nil {
+			onCommand(request.Command)
+		}
+		event, _ := json.Marshal(map[string]interface{}{"event": "property-change"})
+		reply, _ := json.Marshal(response)
+		conn.Write(append(append(event, '\n'), append(reply, '\n')...))
+	}
+}
+
+func (fake *fakeMpvServer) property(name string) interface{} {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	return fake.properties[name]
+}
+
+func (fake *fakeMpvServer) commandNames() []string {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	var names []string
+	for _, command := range fake.commands {
+		names = append(names, command[0].(string))
+	}
+	return names
+}
+
+func TestMpvIpcClient(t *testing.T) {
+	th := NewTestHelper(t)
+	fake := newFakeMpvServer(t, PathJoin(t.TempDir(), "mpv.sock"))
+
+	client, err := NewMpvIpcClient(fake.socketPath)
+	th.Require(err == nil, "client must connect")
+	defer client.Close()
+
+	th.Require(client.SetPause(true) == nil, "SetPause must succeed")
+	th.Require(fake.property("pause") == true, "pause must be set in mpv")
+	th.Require(client.SetVolume(40) == nil, "SetVolume must succeed")
+	th.Require(fake.property("volume") == 40.0, "volume must be set in mpv")
+	th.Require(client.Seek(90) == nil, "Seek must succeed")
+
+	timePos, err := client.TimePos()
+	th.Require(err == nil && timePos == 90, "time-pos must be read back")
+	duration, err := client.Duration()
+	th.Require(err == nil && duration == 185, "duration must be read back")
+
+	th.Require(client.Next() == nil, "Next must succeed")
+	names := fake.commandNames()
+	th.RequireStringEquals(names[len(names)-1], "stop", "Next must stop the current song")
+
+	_, err = client.GetFloatProperty("no-such-property")
+	th.Require(err != nil, "failed command must report an error")
+	_, err = client.Command("no-such-command")
+	th.Require(err != nil, "unknown command must report an error")
+}
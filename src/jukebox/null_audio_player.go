@@ -0,0 +1,104 @@
+package jukebox
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const nullPlayerTick = 10 * time.Millisecond
+
+// NullAudioPlayer doesn't play anything. it simulates playing a song by
+// sleeping for the play length, which is useful when there's no audio
+// player available and in tests.
+type NullAudioPlayer struct {
+	playLength    time.Duration
+	mutex         sync.Mutex
+	remaining     time.Duration
+	paused        bool
+	stopRequested bool
+	songsPlayed   int
+	startOffsets  []int
+}
+
+func NewNullAudioPlayer(playLengthSeconds int) *NullAudioPlayer {
+	var player NullAudioPlayer
+	player.playLength = time.Duration(playLengthSeconds) * time.Second
+	return &player
+}
+
+func (player *NullAudioPlayer) ReadsStdin() bool {
+	return false
+}
+
+func (player *NullAudioPlayer) Play(filePath string) error {
+	return player.PlayFrom(filePath, 0)
+}
+
+func (player *NullAudioPlayer) SupportsStartOffset() bool {
+	return true
+}
+
+func (player *NullAudioPlayer) PlayFrom(filePath string, offsetSeconds int) error {
+	player.mutex.Lock()
+	player.remaining = player.playLength - time.Duration(offsetSeconds)*time.Second
+	player.startOffsets = append(player.startOffsets, offsetSeconds)
+	player.paused = false
+	player.mutex.Unlock()
+
+	for {
+		player.mutex.Lock()
+		if player.stopRequested || player.remaining <= 0 {
+			// a stop that came in before the song started stops it too
+			player.stopRequested = false
+			player.songsPlayed += 1
+			player.mutex.Unlock()
+			return nil
+		}
+		if !player.paused {
+			player.remaining -= nullPlayerTick
+		}
+		player.mutex.Unlock()
+		time.Sleep(nullPlayerTick)
+	}
+}
+
+func (player *NullAudioPlayer) PlayStream(input io.Reader) error {
+	return fmt.Errorf("%w: playing from stdin", ErrPlayerUnsupported)
+}
+
+func (player *NullAudioPlayer) Stop() error {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.stopRequested = true
+	return nil
+}
+
+func (player *NullAudioPlayer) Pause() error {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.paused = true
+	return nil
+}
+
+func (player *NullAudioPlayer) Resume() error {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.paused = false
+	return nil
+}
+
+// StartOffsets returns the offset that each song was started at.
+func (player *NullAudioPlayer) StartOffsets() []int {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return append([]int{}, player.startOffsets...)
+}
+
+// SongsPlayed returns the number of times Play has finished.
+func (player *NullAudioPlayer) SongsPlayed() int {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	return player.songsPlayed
+}
@@ -0,0 +1,51 @@
+package jukebox
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNullAudioPlayer(t *testing.T) {
+	th := NewTestHelper(t)
+	player := NewNullAudioPlayer(0)
+	th.Require(player.Play("song.mp3") == nil, "Play must succeed")
+	th.Require(player.SongsPlayed() == 1, "song must be counted as played")
+	th.Require(player.PlayFrom("song.mp3", 30) == nil, "PlayFrom must succeed")
+	startOffsets := player.StartOffsets()
+	th.Require(len(startOffsets) == 2 && startOffsets[1] == 30, "start offsets must be recorded")
+
+	player = NewNullAudioPlayer(60)
+	playDone := make(chan error)
+	go func() {
+		playDone <- player.Play("song.mp3")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	player.Stop()
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop must end Play")
+	}
+	th.Require(player.PlayStream(nil) != nil, "null player must not stream")
+
+	// a stop between songs must stop the next song
+	player.Stop()
+	go func() {
+		playDone <- player.Play("song.mp3")
+	}()
+	select {
+	case <-playDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop before Play must end Play")
+	}
+	go func() {
+		playDone <- player.Play("song.mp3")
+	}()
+	select {
+	case <-playDone:
+		t.Fatal("stop must only end one song")
+	case <-time.After(100 * time.Millisecond):
+	}
+	player.Stop()
+	<-playDone
+}
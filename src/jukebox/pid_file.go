@@ -0,0 +1,51 @@
+package jukebox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// WritePidFile records the current process as the running jukebox.
+func WritePidFile(pidFilePath string) bool {
+	return FileWriteAllText(pidFilePath, fmt.Sprintf("%d\n", os.Getpid()))
+}
+
+// readPidFile returns the pid in the file, or 0 if there's no valid pid.
+func readPidFile(pidFilePath string) int {
+	pidText, err := FileReadAllText(pidFilePath)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(pidText))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// processIsRunning reports whether there's a process with the pid.
+func processIsRunning(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	// a process owned by someone else can't be signaled, but it exists
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// RunningJukeboxPid returns the pid of the jukebox that wrote the pid
+// file, or 0 if no jukebox is running. a pid file left behind by a
+// jukebox that's no longer running is deleted.
+func RunningJukeboxPid(pidFilePath string) int {
+	if !FileExists(pidFilePath) {
+		return 0
+	}
+	pid := readPidFile(pidFilePath)
+	if pid > 0 && processIsRunning(pid) {
+		return pid
+	}
+	fmt.Printf("removing stale pid file '%s'\n", pidFilePath)
+	DeleteFile(pidFilePath)
+	return 0
+}
@@ -0,0 +1,25 @@
+package jukebox
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRunningJukeboxPid(t *testing.T) {
+	th := NewTestHelper(t)
+	pidFilePath := PathJoin(t.TempDir(), jukeboxPidFileName)
+
+	th.Require(RunningJukeboxPid(pidFilePath) == 0, "missing pid file must mean no jukebox")
+
+	th.Require(WritePidFile(pidFilePath), "WritePidFile must succeed")
+	th.Require(RunningJukeboxPid(pidFilePath) == os.Getpid(), "running process must be found")
+
+	// pids are positive and well below this, so nothing is running with it
+	FileWriteAllText(pidFilePath, "2147483646\n")
+	th.Require(RunningJukeboxPid(pidFilePath) == 0, "stale pid must mean no jukebox")
+	th.RequireFalse(FileExists(pidFilePath), "stale pid file must be removed")
+
+	FileWriteAllText(pidFilePath, "not a pid\n")
+	th.Require(RunningJukeboxPid(pidFilePath) == 0, "invalid pid file must mean no jukebox")
+	th.RequireFalse(FileExists(pidFilePath), "invalid pid file must be removed")
+}
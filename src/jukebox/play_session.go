@@ -0,0 +1,119 @@
+package jukebox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SourceSongs    = "songs"
+	SourceAlbum    = "album"
+	SourcePlaylist = "playlist"
+
+	sessionFileName = "jukebox_session.json"
+)
+
+// PlaySource is what the songs being played were chosen from.
+type PlaySource struct {
+	Type        string `json:"type"`
+	Artist      string `json:"artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	Playlist    string `json:"playlist,omitempty"`
+	ShuffleMode string `json:"shuffle-mode,omitempty"`
+}
+
+func NewPlaySource(sourceType string) *PlaySource {
+	var source PlaySource
+	source.Type = sourceType
+	return &source
+}
+
+// Describe returns the source as it's shown to the user.
+func (source *PlaySource) Describe() string {
+	description := source.Type
+	switch source.Type {
+	case SourcePlaylist:
+		description = fmt.Sprintf("playlist '%s'", source.Playlist)
+	case SourceAlbum:
+		description = fmt.Sprintf("album '%s' by '%s'", source.Album, source.Artist)
+	default:
+		if len(source.Artist) > 0 {
+			description += fmt.Sprintf(" by '%s'", source.Artist)
+		}
+		if len(source.Album) > 0 {
+			description += fmt.Sprintf(" from '%s'", source.Album)
+		}
+	}
+	if len(source.ShuffleMode) > 0 {
+		description = fmt.Sprintf("%s shuffle of %s", source.ShuffleMode, description)
+	}
+	return description
+}
+
+// PlaySession is what's needed to carry on playing where play stopped:
+// the songs in the queue (in play order), which of them is being played
+// and how far into it play has gotten.
+type PlaySession struct {
+	Source        PlaySource `json:"source"`
+	Songs         []string   `json:"songs"`
+	Index         int        `json:"index"`
+	OffsetSeconds int        `json:"offset-seconds"`
+	RepeatMode    string     `json:"repeat-mode"`
+	SavedAt       time.Time  `json:"saved-at"`
+}
+
+func NewPlaySession() *PlaySession {
+	var session PlaySession
+	session.Songs = []string{}
+	session.Index = 0
+	session.OffsetSeconds = 0
+	session.RepeatMode = RepeatAll
+	return &session
+}
+
+// Save writes the session to the file. the file is replaced in one step
+// so that a jukebox killed while saving leaves the last session intact.
+func (session *PlaySession) Save(sessionFilePath string) bool {
+	sessionJson, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		fmt.Println("error: unable to encode play session")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	tempFilePath := sessionFilePath + ".tmp"
+	if !FileWriteAllBytes(tempFilePath, sessionJson) {
+		fmt.Printf("error: unable to write play session to '%s'\n", tempFilePath)
+		return false
+	}
+	if !RenameFile(tempFilePath, sessionFilePath) {
+		fmt.Printf("error: unable to save play session to '%s'\n", sessionFilePath)
+		return false
+	}
+	return true
+}
+
+// LoadPlaySession reads the session saved in the file. returns nil if
+// there's no valid session.
+func LoadPlaySession(sessionFilePath string) *PlaySession {
+	if !FileExists(sessionFilePath) {
+		return nil
+	}
+	sessionJson, err := FileReadAllBytes(sessionFilePath)
+	if err != nil {
+		fmt.Printf("error: unable to read play session from '%s'\n", sessionFilePath)
+		fmt.Printf("error: %v\n", err)
+		return nil
+	}
+	session := NewPlaySession()
+	if err := json.Unmarshal(sessionJson, session); err != nil {
+		fmt.Printf("error: invalid play session in '%s'\n", sessionFilePath)
+		fmt.Printf("error: %v\n", err)
+		return nil
+	}
+	if session.Index < 0 || session.Index >= len(session.Songs) {
+		session.Index = 0
+		session.OffsetSeconds = 0
+	}
+	return session
+}
@@ -0,0 +1,50 @@
+package jukebox
+
+import (
+	"testing"
+)
+
+func TestPlaySessionSaveLoad(t *testing.T) {
+	th := NewTestHelper(t)
+	sessionFilePath := PathJoin(t.TempDir(), sessionFileName)
+	th.Require(LoadPlaySession(sessionFilePath) == nil, "missing session must not load")
+
+	session := NewPlaySession()
+	session.Source = *NewPlaySource(SourceAlbum)
+	session.Source.Artist = "The Who"
+	session.Source.Album = "Whos Next"
+	session.Songs = []string{"a.mp3", "b.mp3", "c.mp3"}
+	session.Index = 2
+	session.OffsetSeconds = 75
+	session.RepeatMode = RepeatNone
+	th.Require(session.Save(sessionFilePath), "session must save")
+	th.RequireFalse(FileExists(sessionFilePath+".tmp"), "temp file must not be left")
+
+	loaded := LoadPlaySession(sessionFilePath)
+	th.Require(loaded != nil, "saved session must load")
+	th.Require(len(loaded.Songs) == 3 && loaded.Index == 2, "songs and index must load")
+	th.Require(loaded.OffsetSeconds == 75, "offset must load")
+	th.RequireStringEquals(loaded.RepeatMode, RepeatNone, "repeat mode must load")
+	th.RequireStringEquals(loaded.Source.Describe(), "album 'Whos Next' by 'The Who'", "source must load")
+
+	session.Index = 3
+	session.Save(sessionFilePath)
+	loaded = LoadPlaySession(sessionFilePath)
+	th.Require(loaded.Index == 0 && loaded.OffsetSeconds == 0, "bad index must start from first song")
+
+	FileWriteAllText(sessionFilePath, "not json")
+	th.Require(LoadPlaySession(sessionFilePath) == nil, "invalid session must not load")
+}
+
+func TestPlaySourceDescribe(t *testing.T) {
+	th := NewTestHelper(t)
+	source := NewPlaySource(SourceSongs)
+	th.RequireStringEquals(source.Describe(), "songs", "plain songs")
+	source.Artist = "The Kinks"
+	source.ShuffleMode = ShuffleSmart
+	th.RequireStringEquals(source.Describe(), "smart shuffle of songs by 'The Kinks'", "shuffled songs by artist")
+
+	source = NewPlaySource(SourcePlaylist)
+	source.Playlist = "road trip"
+	th.RequireStringEquals(source.Describe(), "playlist 'road trip'", "playlist")
+}
@@ -0,0 +1,304 @@
+package jukebox
+
+import "sync"
+
+const (
+	RepeatAll  = "all"
+	RepeatOne  = "one"
+	RepeatNone = "none"
+)
+
+func RepeatModes() []string {
+	return []string{RepeatAll, RepeatOne, RepeatNone}
+}
+
+// Queue is the order that songs are played in. it holds the song being
+// played, the songs that are up next, and the history of songs already
+// played (which is what going back to the previous song uses). with
+// repeat all, the history is played again once the queue runs out, and
+// with repeat one, the current song is played again until it's skipped.
+//
+// positions given to the queue operations are positions among the
+// upcoming songs, where 0 is the song that plays next.
+type Queue struct {
+	mutex      sync.Mutex
+	history    []*SongMetadata
+	current    *SongMetadata
+	upcoming   []*SongMetadata
+	repeatMode string
+	generation int
+	onChange   func()
+}
+
+func NewQueue() *Queue {
+	var queue Queue
+	queue.history = []*SongMetadata{}
+	queue.current = nil
+	queue.upcoming = []*SongMetadata{}
+	queue.repeatMode = RepeatAll
+	queue.generation = 0
+	queue.onChange = nil
+	return &queue
+}
+
+// SetChangeListener sets the function that's called after the upcoming
+// songs are changed.
+func (queue *Queue) SetChangeListener(onChange func()) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.onChange = onChange
+}
+
+func (queue *Queue) SetRepeatMode(repeatMode string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.repeatMode = repeatMode
+}
+
+func (queue *Queue) RepeatMode() string {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.repeatMode
+}
+
+// wraps reports whether the history plays again once the queue runs out.
+// skipping a song with repeat one moves on as repeat all would.
+func (queue *Queue) wraps() bool {
+	return queue.repeatMode != RepeatNone
+}
+
+// changed is called (without the lock held) after an operation changes
+// the upcoming songs.
+func (queue *Queue) changed() {
+	queue.mutex.Lock()
+	onChange := queue.onChange
+	queue.mutex.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// Reset replaces everything in the queue. the first song becomes the
+// current song.
+func (queue *Queue) Reset(songs []*SongMetadata) {
+	queue.mutex.Lock()
+	queue.history = []*SongMetadata{}
+	queue.current = nil
+	queue.upcoming = []*SongMetadata{}
+	if len(songs) > 0 {
+		queue.current = songs[0]
+		queue.upcoming = append(queue.upcoming, songs[1:]...)
+	}
+	queue.generation += 1
+	queue.mutex.Unlock()
+	queue.changed()
+}
+
+// Restore replaces everything in the queue, with the song at index as the
+// current song and the songs before it as the history.
+func (queue *Queue) Restore(songs []*SongMetadata, index int) {
+	if index < 0 || index >= len(songs) {
+		queue.Reset(songs)
+		return
+	}
+	queue.mutex.Lock()
+	queue.history = append([]*SongMetadata{}, songs[:index]...)
+	queue.current = songs[index]
+	queue.upcoming = append([]*SongMetadata{}, songs[index+1:]...)
+	queue.generation += 1
+	queue.mutex.Unlock()
+	queue.changed()
+}
+
+// Current returns the current song along with the generation of the
+// queue, which changes whenever the current song does.
+func (queue *Queue) Current() (*SongMetadata, int) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.current, queue.generation
+}
+
+func (queue *Queue) Generation() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.generation
+}
+
+// Position returns the position of the current song among all of the
+// songs in the queue, and the number of songs.
+func (queue *Queue) Position() (int, int) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if queue.current == nil {
+		return -1, len(queue.history) + len(queue.upcoming)
+	}
+	return len(queue.history), len(queue.history) + 1 + len(queue.upcoming)
+}
+
+// Len returns the number of songs in the queue (including the history).
+func (queue *Queue) Len() int {
+	_, numberSongs := queue.Position()
+	return numberSongs
+}
+
+// SongFinished is called when the current song has played to its end.
+// unless the current song changed since generation, it moves on to the
+// song that plays next (which for repeat one is the same song). returns
+// nil when there's nothing left to play.
+func (queue *Queue) SongFinished(generation int) *SongMetadata {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if generation != queue.generation || queue.repeatMode == RepeatOne {
+		return queue.current
+	}
+	return queue.advance()
+}
+
+// Advance moves on to the next song and returns it. returns nil when
+// there's nothing left to play.
+func (queue *Queue) Advance() *SongMetadata {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.advance()
+}
+
+func (queue *Queue) advance() *SongMetadata {
+	if queue.current != nil {
+		queue.history = append(queue.history, queue.current)
+		queue.current = nil
+	}
+	if len(queue.upcoming) == 0 && queue.wraps() {
+		queue.upcoming = queue.history
+		queue.history = []*SongMetadata{}
+	}
+	if len(queue.upcoming) > 0 {
+		queue.current = queue.upcoming[0]
+		queue.upcoming = queue.upcoming[1:]
+	}
+	queue.generation += 1
+	return queue.current
+}
+
+// Previous goes back to the last song played. the current song becomes
+// the next song. returns false if there's no history.
+func (queue *Queue) Previous() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if len(queue.history) == 0 {
+		return false
+	}
+	if queue.current != nil {
+		queue.upcoming = append([]*SongMetadata{queue.current}, queue.upcoming...)
+	}
+	lastIndex := len(queue.history) - 1
+	queue.current = queue.history[lastIndex]
+	queue.history = queue.history[:lastIndex]
+	queue.generation += 1
+	return true
+}
+
+// Append adds songs to the end of the queue.
+func (queue *Queue) Append(songs ...*SongMetadata) {
+	queue.mutex.Lock()
+	queue.upcoming = append(queue.upcoming, songs...)
+	queue.mutex.Unlock()
+	queue.changed()
+}
+
+// InsertNext adds a song to play after the current one.
+func (queue *Queue) InsertNext(song *SongMetadata) {
+	queue.mutex.Lock()
+	queue.upcoming = append([]*SongMetadata{song}, queue.upcoming...)
+	queue.mutex.Unlock()
+	queue.changed()
+}
+
+// Remove takes the song at position out of the queue. returns false if
+// there's no song at position.
+func (queue *Queue) Remove(position int) bool {
+	queue.mutex.Lock()
+	if position < 0 || position >= len(queue.upcoming) {
+		queue.mutex.Unlock()
+		return false
+	}
+	upcoming := append([]*SongMetadata{}, queue.upcoming[:position]...)
+	queue.upcoming = append(upcoming, queue.upcoming[position+1:]...)
+	queue.mutex.Unlock()
+	queue.changed()
+	return true
+}
+
+// Move moves the song at position from to position to. returns false if
+// either position is out of range.
+func (queue *Queue) Move(from int, to int) bool {
+	queue.mutex.Lock()
+	numberUpcoming := len(queue.upcoming)
+	if from < 0 || from >= numberUpcoming || to < 0 || to >= numberUpcoming {
+		queue.mutex.Unlock()
+		return false
+	}
+	song := queue.upcoming[from]
+	upcoming := append([]*SongMetadata{}, queue.upcoming[:from]...)
+	upcoming = append(upcoming, queue.upcoming[from+1:]...)
+	upcoming = append(upcoming[:to], append([]*SongMetadata{song}, upcoming[to:]...)...)
+	queue.upcoming = upcoming
+	queue.mutex.Unlock()
+	queue.changed()
+	return true
+}
+
+// Clear removes the upcoming songs and the history. the current song
+// keeps playing.
+func (queue *Queue) Clear() {
+	queue.mutex.Lock()
+	queue.history = []*SongMetadata{}
+	queue.upcoming = []*SongMetadata{}
+	queue.mutex.Unlock()
+	queue.changed()
+}
+
+// IndexOf returns the position of the first upcoming song with the uid,
+// or -1 if it isn't in the queue.
+func (queue *Queue) IndexOf(songUid string) int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for i, song := range queue.upcoming {
+		if song.Fm != nil && song.Fm.FileUid == songUid {
+			return i
+		}
+	}
+	return -1
+}
+
+// Upcoming returns (up to limit of) the songs that will be played after
+// the current one, in order. unless repeat is off, that includes the
+// history.
+// a negative limit returns all of them.
+func (queue *Queue) Upcoming(limit int) []*SongMetadata {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	songs := append([]*SongMetadata{}, queue.upcoming...)
+	if queue.wraps() {
+		songs = append(songs, queue.history...)
+	}
+	if limit >= 0 && len(songs) > limit {
+		songs = songs[:limit]
+	}
+	return songs
+}
+
+// Songs returns every song in the queue: the history, the current song
+// and the upcoming songs.
+func (queue *Queue) Songs() []*SongMetadata {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	songs := append([]*SongMetadata{}, queue.history...)
+	if queue.current != nil {
+		songs = append(songs, queue.current)
+	}
+	return append(songs, queue.upcoming...)
+}
@@ -0,0 +1,116 @@
+package jukebox
+
+import "testing"
+
+func newTestQueueSongs(uids ...string) []*SongMetadata {
+	songs := []*SongMetadata{}
+	for _, uid := range uids {
+		song := NewSongMetadata()
+		song.Fm = NewFileMetadata()
+		song.Fm.FileUid = uid
+		songs = append(songs, song)
+	}
+	return songs
+}
+
+func queueUids(songs []*SongMetadata) string {
+	uids := ""
+	for _, song := range songs {
+		uids += song.Fm.FileUid
+	}
+	return uids
+}
+
+func TestQueueAdvanceAndPrevious(t *testing.T) {
+	th := NewTestHelper(t)
+	queue := NewQueue()
+	changes := 0
+	queue.SetChangeListener(func() { changes += 1 })
+
+	song, generation := queue.Current()
+	th.Require(song == nil, "new queue must be empty")
+	th.RequireFalse(queue.Previous(), "previous must fail without history")
+
+	queue.Reset(newTestQueueSongs("a", "b", "c"))
+	th.Require(changes == 1, "reset must notify listener")
+	song, _ = queue.Current()
+	th.RequireStringEquals(song.Fm.FileUid, "a", "first song must be current")
+	th.Require(queue.Generation() != generation, "reset must change generation")
+
+	index, total := queue.Position()
+	th.Require(index == 0 && total == 3, "position must be first of three")
+
+	th.RequireStringEquals(queue.Advance().Fm.FileUid, "b", "advance must move to next song")
+	th.RequireStringEquals(queueUids(queue.Upcoming(-1)), "ca", "repeat must include history")
+	th.Require(queue.Previous(), "previous must succeed")
+	song, _ = queue.Current()
+	th.RequireStringEquals(song.Fm.FileUid, "a", "previous must go back")
+	th.RequireStringEquals(queueUids(queue.Upcoming(-1)), "bc", "previous song must come back up")
+
+	queue.Advance()
+	queue.Advance()
+	th.RequireStringEquals(queue.Advance().Fm.FileUid, "a", "repeat must start over")
+
+	queue.SetRepeatMode(RepeatNone)
+	queue.Advance()
+	queue.Advance()
+	th.Require(queue.Advance() == nil, "queue without repeat must run out")
+}
+
+func TestQueueEditing(t *testing.T) {
+	th := NewTestHelper(t)
+	queue := NewQueue()
+	queue.SetRepeatMode(RepeatNone)
+	songs := newTestQueueSongs("a", "b", "c", "d", "e")
+	queue.Reset(songs[:3])
+	changes := 0
+	queue.SetChangeListener(func() { changes += 1 })
+
+	queue.Append(songs[3])
+	queue.InsertNext(songs[4])
+	th.RequireStringEquals(queueUids(queue.Upcoming(-1)), "ebcd", "songs must be added")
+	th.Require(queue.IndexOf("c") == 2, "IndexOf must find song")
+	th.Require(queue.IndexOf("a") == -1, "IndexOf must not find current song")
+
+	th.Require(queue.Move(3, 0), "move must succeed")
+	th.RequireStringEquals(queueUids(queue.Upcoming(-1)), "debc", "song must move to front")
+	th.Require(queue.Move(0, 3), "move must succeed")
+	th.RequireStringEquals(queueUids(queue.Upcoming(-1)), "ebcd", "song must move to end")
+	th.RequireFalse(queue.Move(0, 4), "move past the end must fail")
+
+	th.Require(queue.Remove(1), "remove must succeed")
+	th.RequireStringEquals(queueUids(queue.Upcoming(2)), "ec", "song must be removed")
+	th.RequireFalse(queue.Remove(3), "remove past the end must fail")
+	th.Require(changes == 5, "each change must notify listener")
+
+	queue.Advance()
+	queue.Clear()
+	th.Require(queue.Len() == 1, "clear must only keep current song")
+	th.RequireStringEquals(queueUids(queue.Songs()), "e", "current song must be kept")
+}
+
+func TestQueueRepeatModes(t *testing.T) {
+	th := NewTestHelper(t)
+	queue := NewQueue()
+	queue.Reset(newTestQueueSongs("a", "b"))
+
+	queue.SetRepeatMode(RepeatOne)
+	_, generation := queue.Current()
+	th.RequireStringEquals(queue.SongFinished(generation).Fm.FileUid, "a", "repeat one must play song again")
+	th.RequireStringEquals(queue.Advance().Fm.FileUid, "b", "skip with repeat one must move on")
+	th.RequireStringEquals(queue.Advance().Fm.FileUid, "a", "skip with repeat one must wrap around")
+
+	queue.SetRepeatMode(RepeatAll)
+	_, generation = queue.Current()
+	queue.Advance()
+	th.RequireStringEquals(queue.SongFinished(generation).Fm.FileUid, "b",
+		"finished song must not advance queue that already moved")
+	_, generation = queue.Current()
+	th.RequireStringEquals(queue.SongFinished(generation).Fm.FileUid, "a", "repeat all must wrap around")
+
+	queue.SetRepeatMode(RepeatNone)
+	_, generation = queue.Current()
+	th.RequireStringEquals(queue.SongFinished(generation).Fm.FileUid, "b", "finished song must advance queue")
+	_, generation = queue.Current()
+	th.Require(queue.SongFinished(generation) == nil, "no repeat must stop at the end")
+}
@@ -1,25 +1,31 @@
 package jukebox
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"io"
 	"os"
+	"strings"
+	"time"
 )
 
 type S3StorageSystem struct {
-	debugMode      bool
-	awsAccessKey   string
-	awsSecretKey   string
-	endpointUrl    string
-	region         string
-	listContainers []string
-	s3Client       *s3.S3
-	s3Session      *session.Session
+	debugMode    bool
+	awsAccessKey string
+	awsSecretKey string
+	endpointUrl  string
+	region       string
+	s3Client     *s3.S3
+	s3Session    *session.Session
 }
 
 func NewS3StorageSystem(accessKey string,
@@ -29,14 +35,13 @@ func NewS3StorageSystem(accessKey string,
 	debugMode bool) *S3StorageSystem {
 
 	ss := S3StorageSystem{
-		debugMode:      debugMode,
-		awsAccessKey:   accessKey,
-		awsSecretKey:   secretKey,
-		endpointUrl:    theEndpointUrl,
-		region:         theRegion,
-		listContainers: nil,
-		s3Client:       nil,
-		s3Session:      nil,
+		debugMode:    debugMode,
+		awsAccessKey: accessKey,
+		awsSecretKey: secretKey,
+		endpointUrl:  theEndpointUrl,
+		region:       theRegion,
+		s3Client:     nil,
+		s3Session:    nil,
 	}
 
 	if debugMode {
@@ -46,16 +51,72 @@ func NewS3StorageSystem(accessKey string,
 	return &ss
 }
 
-func (ss *S3StorageSystem) Enter() bool {
-	connected := false
+// s3Error maps the errors returned by the AWS SDK onto the storage errors
+func s3Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		switch aerr.Code() {
+		case request.CanceledErrorCode:
+			return fmt.Errorf("%w: %v", context.Canceled, err)
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		case s3.ErrCodeNoSuchBucket:
+			return fmt.Errorf("%w: %v", ErrContainerMissing, err)
+		case "InvalidRange":
+			return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		case "AccessDenied", "Forbidden", "InvalidAccessKeyId",
+			"SignatureDoesNotMatch", "ExpiredToken":
+			return fmt.Errorf("%w: %v", ErrAuth, err)
+		case request.ErrCodeRequestError, request.ErrCodeResponseTimeout,
+			"RequestTimeout", "SlowDown", "InternalError", "ServiceUnavailable":
+			return fmt.Errorf("%w: %v", ErrTransient, err)
+		}
+
+		if reqErr, ok := err.(awserr.RequestFailure); ok {
+			statusCode := reqErr.StatusCode()
+			if statusCode == 401 || statusCode == 403 {
+				return fmt.Errorf("%w: %v", ErrAuth, err)
+			} else if statusCode == 404 {
+				return fmt.Errorf("%w: %v", ErrNotFound, err)
+			} else if statusCode == 429 || statusCode >= 500 {
+				return fmt.Errorf("%w: %v", ErrTransient, err)
+			}
+		}
+
+		// uploads and downloads can wrap the error that really matters
+		if aerr.OrigErr() != nil && aerr.OrigErr() != err {
+			if mapped := s3Error(aerr.OrigErr()); mapped != aerr.OrigErr() {
+				return mapped
+			}
+		}
+	}
+	return err
+}
+
+func (ss *S3StorageSystem) haveSession() error {
+	if ss.s3Client == nil || ss.s3Session == nil {
+		return errors.New("no existing S3 session")
+	}
+	return nil
+}
+
+func (ss *S3StorageSystem) Enter(ctx context.Context) error {
 	if ss.debugMode {
 		fmt.Println("attempting to connect to S3")
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	credentials := credentials.NewStaticCredentials(ss.awsAccessKey, ss.awsSecretKey, "")
 	if credentials == nil {
-		fmt.Printf("error: unable to create credentials\n")
-		return false
+		return errors.New("unable to create credentials")
 	}
 
 	s3Config := aws.Config{
@@ -71,25 +132,15 @@ func (ss *S3StorageSystem) Enter() bool {
 	})
 	if err != nil {
 		fmt.Println("unable to establish S3 session")
-	} else {
-		s3Client := s3.New(s3Session)
-
-		fmt.Println("established S3 session")
-		ss.s3Client = s3Client
-		ss.s3Session = s3Session
-		connected = true
+		return s3Error(err)
 	}
 
-	if connected {
-		listBuckets, err := ss.ListAccountContainers()
-		if err == nil {
-			ss.listContainers = *listBuckets
-		} else {
-			fmt.Printf("ss.ListAccountContainers returned error: %v\n", err)
-		}
-	}
+	s3Client := s3.New(s3Session)
 
-	return connected
+	fmt.Println("established S3 session")
+	ss.s3Client = s3Client
+	ss.s3Session = s3Session
+	return nil
 }
 
 func (ss *S3StorageSystem) Exit() {
@@ -100,284 +151,257 @@ func (ss *S3StorageSystem) Exit() {
 		ss.s3Session = nil
 		ss.s3Client = nil
 	}
-	ss.listContainers = nil
 }
 
-func (ss *S3StorageSystem) ListAccountContainers() (*[]string, error) {
+func (ss *S3StorageSystem) ListAccountContainers(ctx context.Context) (*[]string, error) {
 	if ss.debugMode {
 		fmt.Println("ListAccountContainers")
 	}
 
-	if ss.s3Client != nil {
-		result, err := ss.s3Client.ListBuckets(&s3.ListBucketsInput{})
-		if err != nil {
-			fmt.Printf("ListBuckets failed\n")
-			return nil, err
-		} else {
-			var listContainers []string
-			for _, bucket := range result.Buckets {
-				listContainers = append(listContainers, *bucket.Name)
-			}
-			return &listContainers, nil
-		}
+	if err := ss.haveSession(); err != nil {
+		return nil, err
+	}
+
+	result, err := ss.s3Client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		fmt.Printf("ListBuckets failed\n")
+		return nil, s3Error(err)
 	} else {
-		return nil, errors.New("no existing S3 session")
+		var listContainers []string
+		for _, bucket := range result.Buckets {
+			listContainers = append(listContainers, *bucket.Name)
+		}
+		return &listContainers, nil
 	}
 }
 
-func (ss *S3StorageSystem) CreateContainer(containerName string) bool {
+func (ss *S3StorageSystem) CreateContainer(ctx context.Context, containerName string) error {
 	if ss.debugMode {
 		fmt.Printf("CreateContainer: '%s'\n", containerName)
 	}
 
-	containerCreated := false
+	if err := ss.haveSession(); err != nil {
+		return err
+	}
+	if len(containerName) == 0 {
+		return fmt.Errorf("%w: container name is missing", ErrInvalidArgument)
+	}
 
-	if ss.s3Client != nil {
-		bucket := aws.String(containerName)
-		_, err := ss.s3Client.CreateBucket(&s3.CreateBucketInput{
-			Bucket: bucket,
-		})
-		if err == nil {
-			containerCreated = true
-			ss.AddContainer(containerName)
-		} else {
-			fmt.Printf("error: CreateBucket failed - %v\n", err)
+	_, err := ss.s3Client.CreateBucketWithContext(ctx, &s3.CreateBucketInput{
+		Bucket: aws.String(containerName),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok &&
+			aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+			// created earlier by us (or another of our clients)
+			return nil
 		}
+		fmt.Printf("error: CreateBucket failed - %v\n", err)
+		return s3Error(err)
 	}
 
-	return containerCreated
+	return nil
 }
 
-func (ss *S3StorageSystem) DeleteContainer(containerName string) bool {
+func (ss *S3StorageSystem) DeleteContainer(ctx context.Context, containerName string) error {
 	if ss.debugMode {
 		fmt.Printf("DeleteContainer: '%s'\n", containerName)
 	}
 
-	containerDeleted := false
+	if err := ss.haveSession(); err != nil {
+		return err
+	}
+	if len(containerName) == 0 {
+		return fmt.Errorf("%w: container name is missing", ErrInvalidArgument)
+	}
 
-	if ss.s3Client != nil {
-		bucket := aws.String(containerName)
-		_, err := ss.s3Client.DeleteBucket(&s3.DeleteBucketInput{
-			Bucket: bucket,
-		})
-		if err == nil {
-			containerDeleted = true
-			ss.DeleteContainer(containerName)
-		}
+	_, err := ss.s3Client.DeleteBucketWithContext(ctx, &s3.DeleteBucketInput{
+		Bucket: aws.String(containerName),
+	})
+	if err != nil {
+		fmt.Printf("error: DeleteBucket failed - %v\n", err)
+		return s3Error(err)
 	}
 
-	return containerDeleted
+	return nil
 }
 
-func (ss *S3StorageSystem) ListContainerContents(containerName string) ([]string, error) {
+func (ss *S3StorageSystem) ListContainerContents(ctx context.Context, containerName string) ([]string, error) {
 	if ss.debugMode {
 		fmt.Printf("ListContainerContents: '%s'\n", containerName)
 	}
 
-	if ss.s3Client != nil {
-		bucket := aws.String(containerName)
-		resp, err := ss.s3Client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: bucket})
-		if err == nil {
-			var listObjects []string
-			for _, item := range resp.Contents {
+	if err := ss.haveSession(); err != nil {
+		return nil, err
+	}
+
+	var listObjects []string
+	err := ss.s3Client.ListObjectsV2PagesWithContext(ctx,
+		&s3.ListObjectsV2Input{Bucket: aws.String(containerName)},
+		func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, item := range page.Contents {
 				if len(*item.Key) > 0 {
 					listObjects = append(listObjects, *item.Key)
 				}
 			}
-			return listObjects, nil
-		} else {
-			return nil, err
-		}
-	} else {
-		return nil, errors.New("no existing S3 session")
+			return true
+		})
+	if err != nil {
+		return nil, s3Error(err)
 	}
+	return listObjects, nil
 }
 
-func (ss *S3StorageSystem) GetObjectMetadata(containerName string,
-	objectName string, dictProps *PropertySet) bool {
+func (ss *S3StorageSystem) GetObjectMetadata(ctx context.Context,
+	containerName string,
+	objectName string,
+	dictProps *PropertySet) error {
 	if ss.debugMode {
 		fmt.Printf("GetObjectMetadata: container='%s', object='%s'\n",
 			containerName,
 			objectName)
 	}
 
-	gotMetadata := false
+	if err := ss.haveSession(); err != nil {
+		return err
+	}
+	if dictProps == nil || len(containerName) == 0 || len(objectName) == 0 {
+		return fmt.Errorf("%w: container, object and property set are required", ErrInvalidArgument)
+	}
 
 	// https://docs.aws.amazon.com/sdk-for-go/api/service/s3/#S3.HeadObject
-	if ss.s3Client != nil && len(containerName) > 0 && len(objectName) > 0 {
-		//TODO: implement GetObjectMetadata
-		/*
-			        request := s3.HeadObjectInput{
-			           Bucket: &containerName,
-			           Key: &objectName,
-			        }
-			        response, err := ss.s3Session.HeadObject(&request)
-			        if err != nil {
-			           return nil
-			        } else {
-			           dict_headers := make(map[string]interface{})
-			           //if response.ArchiveStatus != nil {
-			           //   dict_headers["ArchiveStatus"] = *response.ArchiveStatus
-			           //}
-			           //if response.BucketKeyEnabled != nil {
-			           //   enabled := *response.BucketKeyEnabled
-			           //   var enabled_value string
-				   //   if enabled {
-			           //      enabled_value = "true"
-			           //   } else {
-			           //      enabled_value = "false"
-			           //   }
-				   //   dict_headers["BucketKeyEnabled"] = enabled_value
-			           //}
-				   if response.CacheControl != nil {
-			              dict_headers["CacheControl"] = *response.CacheControl
-			           }
-				   if response.ChecksumCRC32 != nil {
-			              dict_headers["ChecksumCRC32"] = *response.ChecksumCRC32
-			           }
-				   if response.ChecksumCRC32C != nil {
-			              dict_headers["ChecksumCRC32C"] = *response.ChecksumCRC32C
-			           }
-				   if response.ChecksumSHA1 != nil {
-			              dict_headers["ChecksumSHA1"] = *response.ChecksumSHA1
-			           }
-				   if response.ChecksumSHA256 != nil {
-			              dict_headers["ChecksumSHA256"] = *response.ChecksumSHA256
-			           }
-				   if response.ContentDisposition != nil {
-			              dict_headers["ContentDisposition"] = *response.ContentDisposition
-			           }
-				   if response.ContentEncoding != nil {
-			              dict_headers["ContentEncoding"] = *response.ContentEncoding
-			           }
-				   if response.ContentLanguage != nil {
-			              dict_headers["ContentLanguage"] = *response.ContentLanguage
-			           }
-				   //if response.ContentLength != nil {
-			           //   //*int64
-			           //}
-				   if response.ContentType != nil {
-			              dict_headers["ContentType"] = *response.ContentType
-			           }
-				   //if response.DeleteMarker != nil {
-			           //   delete_marker := *response.DeleteMarker
-			           //   var delete_marker_value string
-			           //   if delete_marker {
-			           //      delete_marker_value = "true"
-			           //   } else {
-			           //      delete_marker_value = "false"
-			           //   }
-				   //   dict_headers["DeleteMarker"] = delete_marker_value
-			           //}
-				   if response.ETag != nil {
-			              dict_headers["ETag"] = *response.ETag
-			           }
-				   if response.Expiration != nil {
-			              dict_headers["Expiration"] = *response.Expiration
-			           }
-				   if response.Expires != nil {
-			              dict_headers["Expires"] = *response.Expires
-			           }
-				   if response.LastModified != nil {
-			              //*time.Time
-			           }
-				   if response.Metadata != nil {
-			              //map[string]*string
-			           }
-				   //if response.MissingMeta != nil {
-			           //   //*int64
-			           //}
-				   //if response.ObjectLockLegalHoldStatus != nil {
-			           //   dict_headers["ObjectLockLegalHoldStatus"] = *response.ObjectLockLegalHoldStatus
-			           //}
-				   //if response.ObjectLockMode != nil {
-			           //   dict_headers["ObjectLockMode"] = *response.ObjectLockMode
-			           //}
-				   if response.ObjectLockRetainUntilDate != nil {
-			              //*time.Time
-			           }
-				   //if response.PartsCount != nil {
-			           //   dict_headers["PartsCount"] = fmt.Sprintf("%d", *response.PartsCount)
-			           //}
-				   //if response.ReplicationStatus != nil {
-			           //   dict_headers["ReplicationStatus"] = *response.ReplicationStatus
-			           //}
-				   //if response.RequestCharged != nil {
-			           //   dict_headers["RequestCharged"] = *response.RequestCharged
-			           //}
-				   if response.Restore != nil {
-			              dict_headers["Restore"] = *response.Restore
-			           }
-				   if response.SSECustomerAlgorithm != nil {
-			              dict_headers["SSECustomerAlgorithm"] = *response.SSECustomerAlgorithm
-			           }
-				   if response.SSECustomerKeyMD5 != nil {
-			              dict_headers["SSECustomerKeyMD5"] = *response.SSECustomerKeyMD5
-			           }
-				   if response.SSEKMSKeyId != nil {
-			              dict_headers["SSEKMSKeyId"] = *response.SSEKMSKeyId
-			           }
-				   //if response.ServerSideEncryption != nil {
-			           //   dict_headers["ServerSideEncryption"] = *response.ServerSideEncryption
-			           //}
-				   //if response.StorageClass != nil {
-			           //   dict_headers["StorageClass"] = *response.StorageClass
-			           //}
-				   if response.VersionId != nil {
-			              dict_headers["VersionId"] = *response.VersionId
-			           }
-				   if response.WebsiteRedirectLocation != nil {
-			              dict_headers["WebsiteRedirectLocation"] = *response.WebsiteRedirectLocation
-			           }
-				   return &dict_headers
-			        }
-		*/
-	}
-	return gotMetadata
+	response, err := ss.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(containerName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		if ss.debugMode {
+			fmt.Printf("error: HeadObject failed - %v\n", err)
+		}
+		return s3Error(err)
+	}
+
+	if response.ETag != nil {
+		dictProps.Add("etag", NewStringPropertyValue(strings.Trim(*response.ETag, "\"")))
+	}
+	if response.ContentLength != nil {
+		dictProps.Add("content_length", NewLongPropertyValue(*response.ContentLength))
+	}
+	if response.ContentType != nil {
+		dictProps.Add("content_type", NewStringPropertyValue(*response.ContentType))
+	}
+	if response.LastModified != nil {
+		dictProps.Add("last_modified",
+			NewStringPropertyValue(response.LastModified.UTC().Format(time.RFC3339)))
+	}
+	for key, value := range response.Metadata {
+		if value != nil {
+			// http header canonicalization changes the case of the
+			// metadata keys, so put them back the way we stored them
+			dictProps.Add(strings.ToLower(key), NewStringPropertyValue(*value))
+		}
+	}
+
+	return nil
 }
 
-func (ss *S3StorageSystem) PutObject(containerName string,
+func (ss *S3StorageSystem) PutObject(ctx context.Context,
+	containerName string,
 	objectName string,
 	fileContents []byte,
-	headers *PropertySet) bool {
+	headers *PropertySet) error {
+	return ss.PutObjectFromReader(ctx,
+		containerName,
+		objectName,
+		bytes.NewReader(fileContents),
+		int64(len(fileContents)),
+		headers)
+}
 
-	objectAdded := false
+func (ss *S3StorageSystem) PutObjectFromReader(ctx context.Context,
+	containerName string,
+	objectName string,
+	reader io.Reader,
+	contentLength int64,
+	headers *PropertySet) error {
 
-	if ss.s3Client != nil && len(containerName) > 0 &&
-		len(objectName) > 0 && len(fileContents) > 0 {
-		//TODO: implement PutObject
+	if ss.debugMode {
+		fmt.Printf("PutObjectFromReader: container='%s', object='%s', length=%d\n",
+			containerName,
+			objectName,
+			contentLength)
+	}
+
+	if err := ss.haveSession(); err != nil {
+		return err
+	}
+	// zero-byte objects are fine in S3
+	if len(containerName) == 0 || len(objectName) == 0 || reader == nil || contentLength < 0 {
+		return fmt.Errorf("%w: container, object and content (of known length) are required", ErrInvalidArgument)
 	}
 
-	return objectAdded
+	// the uploader streams the reader in parts, so only a few parts
+	// are ever held in memory. when we know the length, make sure
+	// the part size is big enough to stay under the part limit.
+	uploader := s3manager.NewUploader(ss.s3Session, func(u *s3manager.Uploader) {
+		if contentLength > 0 {
+			minPartSize := contentLength/int64(s3manager.MaxUploadParts) + 1
+			if minPartSize > u.PartSize {
+				u.PartSize = minPartSize
+			}
+		}
+	})
+
+	_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:   aws.String(containerName),
+		Key:      aws.String(objectName),
+		Body:     reader,
+		Metadata: metadataFromPropertySet(headers),
+	})
+
+	if err != nil {
+		fmt.Printf("error: unable to put object %s/%s - %v\n", containerName, objectName, err)
+		return s3Error(err)
+	}
+
+	return nil
 }
 
-func (ss *S3StorageSystem) DeleteObject(containerName string, objectName string) bool {
+func (ss *S3StorageSystem) DeleteObject(ctx context.Context, containerName string, objectName string) error {
 	if ss.debugMode {
 		fmt.Printf("DeleteObject: container='%s', object='%s'\n",
 			containerName,
 			objectName)
 	}
 
-	objectDeleted := false
+	if err := ss.haveSession(); err != nil {
+		return err
+	}
+	if len(containerName) == 0 || len(objectName) == 0 {
+		return fmt.Errorf("%w: container and object are required", ErrInvalidArgument)
+	}
 
-	if ss.s3Client != nil && len(containerName) > 0 && len(objectName) > 0 {
-		bucket := aws.String(containerName)
-		item := aws.String(objectName)
-		_, err := ss.s3Client.DeleteObject(&s3.DeleteObjectInput{
-			Bucket: bucket,
-			Key:    item,
-		})
-		if err == nil {
-			objectDeleted = true
-		}
+	// S3 doesn't complain about deleting a key that doesn't exist, so
+	// check first to be able to report it
+	_, err := ss.s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(containerName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return s3Error(err)
 	}
 
-	return objectDeleted
+	_, err = ss.s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(containerName),
+		Key:    aws.String(objectName),
+	})
+	return s3Error(err)
 }
 
-func (ss *S3StorageSystem) GetObject(containerName string,
+func (ss *S3StorageSystem) GetObject(ctx context.Context,
+	containerName string,
 	objectName string,
-	localFilePath string) int64 {
+	localFilePath string) (int64, error) {
 
 	if ss.debugMode {
 		fmt.Printf("GetObject: container='%s', object='%s', localFilePath='%s'\n",
@@ -386,137 +410,193 @@ func (ss *S3StorageSystem) GetObject(containerName string,
 			localFilePath)
 	}
 
-	bytesRetrieved := int64(0)
+	if err := ss.haveSession(); err != nil {
+		return 0, err
+	}
+	if len(containerName) == 0 || len(objectName) == 0 || len(localFilePath) == 0 {
+		return 0, fmt.Errorf("%w: container, object and local file path are required", ErrInvalidArgument)
+	}
 
-	if ss.s3Client != nil && len(containerName) > 0 &&
-		len(objectName) > 0 && len(localFilePath) > 0 {
+	file, err := os.Create(localFilePath)
+	if err != nil {
+		return 0, fsError(err)
+	}
+	defer file.Close()
 
-		file, err := os.Create(localFilePath)
-		if err != nil {
-			return 0
-		}
-		defer file.Close()
-
-		downloader := s3manager.NewDownloader(ss.s3Session)
-
-		bucket := aws.String(containerName)
-		filename := aws.String(objectName)
-
-		_, err = downloader.Download(file,
-			&s3.GetObjectInput{
-				Bucket: bucket,
-				Key:    filename,
-			})
-		if err != nil {
-			fmt.Printf("error: unable to download %s/%s to %s\n", containerName, objectName, localFilePath)
-		} else {
-			bytesRetrieved = GetFileSize(localFilePath)
-		}
+	downloader := s3manager.NewDownloader(ss.s3Session)
+
+	bytesRetrieved, err := downloader.DownloadWithContext(ctx, file,
+		&s3.GetObjectInput{
+			Bucket: aws.String(containerName),
+			Key:    aws.String(objectName),
+		})
+	if err != nil {
+		fmt.Printf("error: unable to download %s/%s to %s\n", containerName, objectName, localFilePath)
+		return 0, s3Error(err)
 	}
 
-	return bytesRetrieved
+	return bytesRetrieved, nil
 }
 
-func (ss *S3StorageSystem) AddContainer(containerName string) {
-	if ss.listContainers != nil {
-		ss.listContainers = append(ss.listContainers, containerName)
-	}
+func (ss *S3StorageSystem) GetObjectToWriter(ctx context.Context,
+	containerName string,
+	objectName string,
+	writer io.Writer) (int64, error) {
+	return ss.GetObjectRange(ctx, containerName, objectName, 0, 0, writer)
 }
 
-func (ss *S3StorageSystem) AddFileFromPath(containerName string,
+func (ss *S3StorageSystem) GetObjectRange(ctx context.Context,
+	containerName string,
 	objectName string,
-	filePath string) bool {
+	offset int64,
+	length int64,
+	writer io.Writer) (int64, error) {
 
-	fileAdded := false
+	if ss.debugMode {
+		fmt.Printf("GetObjectRange: container='%s', object='%s', offset=%d, length=%d\n",
+			containerName,
+			objectName,
+			offset,
+			length)
+	}
 
-	if ss.s3Session != nil {
-		file, err := os.Open(filePath)
-		if err != nil {
-			fmt.Println("Unable to open file " + filePath)
-			return false
-		}
-		defer file.Close()
+	if err := ss.haveSession(); err != nil {
+		return 0, err
+	}
+	if len(containerName) == 0 || len(objectName) == 0 || writer == nil {
+		return 0, fmt.Errorf("%w: container, object and writer are required", ErrInvalidArgument)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("%w: negative offset %d", ErrInvalidArgument, offset)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(containerName),
+		Key:    aws.String(objectName),
+	}
+	if length > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+	}
 
-		uploader := s3manager.NewUploader(ss.s3Session)
+	response, err := ss.s3Client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		fmt.Printf("error: unable to get %s/%s - %v\n", containerName, objectName, err)
+		return 0, s3Error(err)
+	}
+	defer response.Body.Close()
 
-		bucket := aws.String(containerName)
-		filename := aws.String(objectName)
+	bytesRetrieved, err := io.Copy(writer, newContextReader(ctx, response.Body))
+	if err != nil {
+		fmt.Printf("error: unable to read %s/%s - %v\n", containerName, objectName, err)
+		return bytesRetrieved, s3Error(err)
+	}
 
-		_, err = uploader.Upload(&s3manager.UploadInput{
-			Bucket: bucket,
-			Key:    filename,
-			Body:   file,
-		})
+	return bytesRetrieved, nil
+}
 
-		if err != nil {
-			fmt.Printf("error: unable to add file from path: %s to %s/%s\n", filePath, containerName, objectName)
-		} else {
-			fileAdded = true
-		}
+func (ss *S3StorageSystem) AddFileFromPath(ctx context.Context,
+	containerName string,
+	objectName string,
+	filePath string) error {
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		fmt.Println("Unable to open file " + filePath)
+		return fsError(err)
 	}
+	defer file.Close()
 
-	return fileAdded
+	err = ss.PutObjectFromReader(ctx, containerName, objectName, file, GetFileSize(filePath), nil)
+	if err != nil {
+		fmt.Printf("error: unable to add file from path: %s to %s/%s\n", filePath, containerName, objectName)
+	}
+
+	return err
 }
 
-func (ss *S3StorageSystem) GetContainerNames() ([]string, error) {
-	if ss.listContainers != nil {
-		return ss.listContainers, nil
+func (ss *S3StorageSystem) GetContainerNames(ctx context.Context) ([]string, error) {
+	listBuckets, err := ss.ListAccountContainers(ctx)
+	if err != nil {
+		return nil, err
 	} else {
-		return nil, errors.New("no existing S3 session")
+		return *listBuckets, nil
 	}
 }
 
-func (ss *S3StorageSystem) HasContainer(containerName string) bool {
-	haveContainer := false
+func (ss *S3StorageSystem) HasContainer(ctx context.Context, containerName string) (bool, error) {
+	if err := ss.haveSession(); err != nil {
+		return false, err
+	}
+	if len(containerName) == 0 {
+		return false, fmt.Errorf("%w: container name is missing", ErrInvalidArgument)
+	}
 
-	if ss.listContainers != nil {
-		for _, existingContainerName := range ss.listContainers {
-			if containerName == existingContainerName {
-				haveContainer = true
-				break
-			}
+	// ask the server every time since other clients may have created
+	// or deleted the bucket since we connected
+	_, err := ss.s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(containerName),
+	})
+	if err != nil {
+		if ss.debugMode {
+			fmt.Printf("HeadBucket '%s' - %v\n", containerName, err)
 		}
-	} else {
-		fmt.Printf("ss.listContainers is nil\n")
+		err = s3Error(err)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrContainerMissing) {
+			return false, nil
+		}
+		return false, err
 	}
-	return haveContainer
+	return true, nil
 }
 
-func (ss *S3StorageSystem) RemoveContainer(containerName string) {
-	if ss.listContainers != nil {
-		foundIndex := -1
-		for index, existingContainerName := range ss.listContainers {
-			if existingContainerName == containerName {
-				foundIndex = index
-				break
-			}
-		}
+func (ss *S3StorageSystem) RetrieveFile(ctx context.Context,
+	fm *FileMetadata,
+	localDirectory string) (int64, error) {
 
-		if foundIndex > -1 {
-			// copy the last item in the slice to the index of the item we're removing
-			// and then make the slice a sub-slice of 1 element less
-			ss.listContainers[foundIndex] = ss.listContainers[len(ss.listContainers)-1]
-			ss.listContainers = ss.listContainers[:len(ss.listContainers)-1]
-		}
+	if len(localDirectory) == 0 {
+		return 0, fmt.Errorf("%w: local directory is missing", ErrInvalidArgument)
+	}
+	filePath := PathJoin(localDirectory, fm.FileUid)
+	if ss.debugMode {
+		fmt.Printf("retrieving container=%s\n", fm.ContainerName)
+		fmt.Printf("retrieving object=%s\n", fm.ObjectName)
 	}
+	return ss.GetObject(ctx, fm.ContainerName, fm.ObjectName, filePath)
 }
 
-func (ss *S3StorageSystem) RetrieveFile(fm *FileMetadata,
-	localDirectory string) int64 {
+func (ss *S3StorageSystem) StoreFile(ctx context.Context,
+	fm *FileMetadata,
+	fileContents []byte) error {
+	return ss.PutObject(ctx, fm.ContainerName, fm.ObjectName, fileContents, fm.ToPropertySet())
+}
 
-	if len(localDirectory) > 0 {
-		filePath := PathJoin(localDirectory, fm.FileUid)
-		if ss.debugMode {
-			fmt.Printf("retrieving container=%s\n", fm.ContainerName)
-			fmt.Printf("retrieving object=%s\n", fm.ObjectName)
-		}
-		return ss.GetObject(fm.ContainerName, fm.ObjectName, filePath)
-	} else {
-		return 0
+func metadataFromPropertySet(headers *PropertySet) map[string]*string {
+	if headers == nil || headers.Count() == 0 {
+		return nil
 	}
-}
 
-func (ss *S3StorageSystem) StoreFile(fm *FileMetadata,
-	fileContents []byte) bool {
-	return ss.PutObject(fm.ContainerName, fm.ObjectName, fileContents, nil)
+	metadata := make(map[string]*string)
+	for _, key := range headers.GetKeys() {
+		pv := headers.Get(key)
+		var value string
+		if pv.IsBool() {
+			if pv.GetBoolValue() {
+				value = psValueTrue
+			} else {
+				value = psValueFalse
+			}
+		} else if pv.IsString() {
+			value = pv.GetStringValue()
+		} else if pv.IsInt() {
+			value = fmt.Sprintf("%d", pv.GetIntValue())
+		} else if pv.IsLong() {
+			value = fmt.Sprintf("%d", pv.GetLongValue())
+		} else if pv.IsUlong() {
+			value = fmt.Sprintf("%d", pv.GetUlongValue())
+		}
+		metadata[key] = aws.String(value)
+	}
+	return metadata
 }
@@ -0,0 +1,521 @@
+package jukebox
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3Object and fakeS3Server implement just enough of the S3 REST
+// protocol (path-style requests) to exercise S3StorageSystem without
+// needing a real S3 endpoint or MinIO.
+type fakeS3Object struct {
+	data         []byte
+	metadata     map[string]string
+	lastModified time.Time
+}
+
+type fakeS3Server struct {
+	mutex   sync.Mutex
+	buckets map[string]map[string]*fakeS3Object
+	server  *httptest.Server
+	// onGetObject (when set) is called before an object GET is served
+	onGetObject func(r *http.Request)
+}
+
+func newFakeS3Server() *fakeS3Server {
+	fake := &fakeS3Server{
+		buckets: make(map[string]map[string]*fakeS3Object),
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	return fake
+}
+
+func (fake *fakeS3Server) Close() {
+	fake.server.Close()
+}
+
+func (fake *fakeS3Server) newStorageSystem(t *testing.T) *S3StorageSystem {
+	ss := NewS3StorageSystem("test-access-key", "test-secret-key", fake.server.URL, "us-east-1", false)
+	if err := ss.Enter(context.Background()); err != nil {
+		t.Fatalf("unable to enter S3 storage system: %v", err)
+	}
+	return ss
+}
+
+type fakeS3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func fakeS3WriteError(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		xml.NewEncoder(w).Encode(fakeS3Error{Code: code, Message: code})
+	}
+}
+
+func (fake *fakeS3Server) handle(w http.ResponseWriter, r *http.Request) {
+	if fake.onGetObject != nil && r.Method == http.MethodGet &&
+		strings.Count(strings.TrimPrefix(r.URL.Path, "/"), "/") > 0 {
+		fake.onGetObject(r)
+	}
+
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path == "" {
+		fake.listBuckets(w)
+		return
+	}
+
+	bucketName := path
+	key := ""
+	if pos := strings.Index(path, "/"); pos > -1 {
+		bucketName = path[:pos]
+		key = path[pos+1:]
+	}
+
+	if key == "" {
+		fake.handleBucket(w, r, bucketName)
+	} else {
+		fake.handleObject(w, r, bucketName, key)
+	}
+}
+
+func (fake *fakeS3Server) listBuckets(w http.ResponseWriter) {
+	type bucket struct {
+		Name string `xml:"Name"`
+	}
+	type result struct {
+		XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+		Buckets []bucket `xml:"Buckets>Bucket"`
+	}
+	var res result
+	for name := range fake.buckets {
+		res.Buckets = append(res.Buckets, bucket{Name: name})
+	}
+	sort.Slice(res.Buckets, func(i, j int) bool {
+		return res.Buckets[i].Name < res.Buckets[j].Name
+	})
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(res)
+}
+
+func (fake *fakeS3Server) handleBucket(w http.ResponseWriter, r *http.Request, bucketName string) {
+	objects, exists := fake.buckets[bucketName]
+	switch r.Method {
+	case http.MethodPut:
+		if exists {
+			fakeS3WriteError(w, r, http.StatusConflict, "BucketAlreadyOwnedByYou")
+			return
+		}
+		fake.buckets[bucketName] = make(map[string]*fakeS3Object)
+	case http.MethodDelete:
+		if !exists {
+			fakeS3WriteError(w, r, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		if len(objects) > 0 {
+			fakeS3WriteError(w, r, http.StatusConflict, "BucketNotEmpty")
+			return
+		}
+		delete(fake.buckets, bucketName)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		if !exists {
+			fakeS3WriteError(w, r, http.StatusNotFound, "NotFound")
+		}
+	case http.MethodGet:
+		if !exists {
+			fakeS3WriteError(w, r, http.StatusNotFound, "NoSuchBucket")
+			return
+		}
+		type content struct {
+			Key  string `xml:"Key"`
+			Size int64  `xml:"Size"`
+		}
+		type result struct {
+			XMLName  xml.Name  `xml:"ListBucketResult"`
+			Name     string    `xml:"Name"`
+			KeyCount int       `xml:"KeyCount"`
+			Contents []content `xml:"Contents"`
+		}
+		res := result{Name: bucketName}
+		for key, obj := range objects {
+			res.Contents = append(res.Contents, content{Key: key, Size: int64(len(obj.data))})
+		}
+		sort.Slice(res.Contents, func(i, j int) bool {
+			return res.Contents[i].Key < res.Contents[j].Key
+		})
+		res.KeyCount = len(res.Contents)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(res)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (fake *fakeS3Server) handleObject(w http.ResponseWriter, r *http.Request, bucketName string, key string) {
+	objects, exists := fake.buckets[bucketName]
+	if !exists {
+		fakeS3WriteError(w, r, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		obj := &fakeS3Object{
+			data:         data,
+			metadata:     make(map[string]string),
+			lastModified: time.Now().UTC().Truncate(time.Second),
+		}
+		for name, values := range r.Header {
+			lowerName := strings.ToLower(name)
+			if strings.HasPrefix(lowerName, "x-amz-meta-") && len(values) > 0 {
+				obj.metadata[strings.TrimPrefix(lowerName, "x-amz-meta-")] = values[0]
+			}
+		}
+		objects[key] = obj
+		w.Header().Set("ETag", fake.etag(obj))
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead, http.MethodGet:
+		obj, found := objects[key]
+		if !found {
+			fakeS3WriteError(w, r, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", fake.etag(obj))
+		w.Header().Set("Last-Modified", obj.lastModified.Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "binary/octet-stream")
+		for name, value := range obj.metadata {
+			w.Header().Set("x-amz-meta-"+name, value)
+		}
+		data := obj.data
+		status := http.StatusOK
+		if rangeHeader := r.Header.Get("Range"); len(rangeHeader) > 0 {
+			start, end, ok := fakeS3ParseRange(rangeHeader, int64(len(obj.data)))
+			if !ok {
+				fakeS3WriteError(w, r, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+				return
+			}
+			data = obj.data[start : end+1]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(obj.data)))
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (fake *fakeS3Server) etag(obj *fakeS3Object) string {
+	return fmt.Sprintf("\"%x\"", len(obj.data))
+}
+
+func fakeS3ParseRange(rangeHeader string, size int64) (int64, int64, bool) {
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if len(parts[1]) > 0 {
+		end, err = strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, start <= end
+}
+
+func (fake *fakeS3Server) createBucketDirectly(bucketName string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	fake.buckets[bucketName] = make(map[string]*fakeS3Object)
+}
+
+func (fake *fakeS3Server) deleteBucketDirectly(bucketName string) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+	delete(fake.buckets, bucketName)
+}
+
+func TestS3Containers(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	haveContainer, err := ss.HasContainer(ctx, "songs")
+	th.Require(err == nil && !haveContainer, "container must not exist before creation")
+	th.Require(ss.CreateContainer(ctx, "songs") == nil, "CreateContainer must succeed")
+	haveContainer, _ = ss.HasContainer(ctx, "songs")
+	th.Require(haveContainer, "container must exist after creation")
+	th.Require(ss.CreateContainer(ctx, "songs") == nil, "CreateContainer of our own existing bucket must succeed")
+
+	names, err := ss.GetContainerNames(ctx)
+	th.Require(err == nil, "GetContainerNames must not fail")
+	th.Require(len(names) == 1 && names[0] == "songs", "GetContainerNames must list created container")
+
+	th.Require(ss.DeleteContainer(ctx, "songs") == nil, "DeleteContainer must succeed")
+	haveContainer, _ = ss.HasContainer(ctx, "songs")
+	th.RequireFalse(haveContainer, "container must not exist after delete")
+	err = ss.DeleteContainer(ctx, "songs")
+	th.Require(errors.Is(err, ErrContainerMissing), "DeleteContainer of missing container must report it")
+}
+
+func TestS3ContainersChangedByOtherClient(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	fake.createBucketDirectly("playlists")
+	haveContainer, _ := ss.HasContainer(ctx, "playlists")
+	th.Require(haveContainer, "bucket created by other client must be seen")
+	names, _ := ss.GetContainerNames(ctx)
+	th.Require(len(names) == 1, "bucket created by other client must be listed")
+
+	fake.deleteBucketDirectly("playlists")
+	haveContainer, _ = ss.HasContainer(ctx, "playlists")
+	th.RequireFalse(haveContainer, "bucket deleted by other client must not be seen")
+	th.Require(ss.CreateContainer(ctx, "playlists") == nil, "bucket deleted by other client must be creatable")
+}
+
+func TestS3PutObject(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	err := ss.PutObject(ctx, "songs", "a.mp3", []byte("abc"), nil)
+	th.Require(errors.Is(err, ErrContainerMissing), "PutObject into missing container must fail")
+	th.Require(ss.CreateContainer(ctx, "songs") == nil, "CreateContainer must succeed")
+
+	headers := NewPropertySet()
+	headers.Add("file_uid", NewStringPropertyValue("The-Who--Whos-Next--My-Wife.mp3"))
+	headers.Add("stored_file_size", NewLongPropertyValue(11))
+	headers.Add("encrypted", NewBoolPropertyValue(false))
+	th.Require(ss.PutObject(ctx, "songs", "a.mp3", []byte("song-binary"), headers) == nil,
+		"PutObject must succeed")
+
+	contents, err := ss.ListContainerContents(ctx, "songs")
+	th.Require(err == nil && len(contents) == 1 && contents[0] == "a.mp3",
+		"uploaded object must be listed")
+
+	localFile := PathJoin(t.TempDir(), "a.mp3")
+	bytesRetrieved, err := ss.GetObject(ctx, "songs", "a.mp3", localFile)
+	th.Require(err == nil && bytesRetrieved == 11, "GetObject must retrieve all bytes")
+	text, _ := FileReadAllText(localFile)
+	th.RequireStringEquals(text, "song-binary", "retrieved object must match uploaded object")
+
+	th.Require(ss.DeleteObject(ctx, "songs", "a.mp3") == nil, "DeleteObject must succeed")
+	contents, _ = ss.ListContainerContents(ctx, "songs")
+	th.Require(len(contents) == 0, "deleted object must not be listed")
+	err = ss.DeleteObject(ctx, "songs", "a.mp3")
+	th.Require(errors.Is(err, ErrNotFound), "DeleteObject of missing object must report not found")
+}
+
+func TestS3GetObjectMetadata(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	ss.CreateContainer(ctx, "songs")
+
+	fm := NewFileMetadata()
+	fm.FileUid = "The-Who--Whos-Next--My-Wife.mp3"
+	fm.ContainerName = "songs"
+	fm.ObjectName = fm.FileUid
+	fm.StoredFileSize = 7
+	fm.Md5Hash = "0123456789abcdef"
+	th.Require(ss.StoreFile(ctx, fm, []byte("1234567")) == nil, "StoreFile must succeed")
+
+	props := NewPropertySet()
+	err := ss.GetObjectMetadata(ctx, "songs", "missing.mp3", props)
+	th.Require(errors.Is(err, ErrNotFound), "GetObjectMetadata of missing object must report not found")
+
+	th.Require(ss.GetObjectMetadata(ctx, "songs", fm.ObjectName, props) == nil, "GetObjectMetadata must succeed")
+	th.Require(props.GetLongValue("content_length") == 7, "content length must be provided")
+	th.Require(len(props.GetStringValue("etag")) > 0, "etag must be provided")
+	th.Require(len(props.GetStringValue("last_modified")) > 0, "last modified must be provided")
+	th.RequireStringEquals(props.GetStringValue("file_uid"), fm.FileUid,
+		"user metadata must be provided")
+	th.RequireStringEquals(props.GetStringValue("md5_hash"), fm.Md5Hash,
+		"user metadata must be provided")
+	th.RequireStringEquals(props.GetStringValue("stored_file_size"), "7",
+		"non-string user metadata must be provided as string")
+}
+
+func TestS3AddFileFromPath(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	ss.CreateContainer(ctx, "album-art")
+	filePath := PathJoin(t.TempDir(), "cover.jpg")
+	FileWriteAllText(filePath, "jpeg-bytes")
+	th.Require(ss.AddFileFromPath(ctx, "album-art", "cover.jpg", filePath) == nil, "AddFileFromPath must succeed")
+
+	props := NewPropertySet()
+	th.Require(ss.GetObjectMetadata(ctx, "album-art", "cover.jpg", props) == nil, "object must exist")
+	th.Require(props.GetLongValue("content_length") == 10, "object length must match file")
+}
+
+func TestS3PutObjectFromReader(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	ss.CreateContainer(ctx, "songs")
+	contents := strings.Repeat("0123456789", 1000)
+	err := ss.PutObjectFromReader(ctx, "songs", "a.flac", strings.NewReader(contents), int64(len(contents)), nil)
+	th.Require(err == nil, "PutObjectFromReader must succeed")
+
+	var buffer bytes.Buffer
+	bytesRetrieved, err := ss.GetObjectToWriter(ctx, "songs", "a.flac", &buffer)
+	th.Require(err == nil && bytesRetrieved == int64(len(contents)),
+		"GetObjectToWriter must return number of bytes written")
+	th.RequireStringEquals(buffer.String(), contents, "retrieved content must match stored content")
+
+	buffer.Reset()
+	_, err = ss.GetObjectToWriter(ctx, "songs", "missing.flac", &buffer)
+	th.Require(errors.Is(err, ErrNotFound), "GetObjectToWriter of missing object must report not found")
+
+	// zero-byte objects are valid, a negative length isn't
+	th.Require(ss.PutObject(ctx, "songs", "empty.flac", []byte{}, nil) == nil, "PutObject of empty content must succeed")
+	buffer.Reset()
+	bytesRetrieved, err = ss.GetObjectToWriter(ctx, "songs", "empty.flac", &buffer)
+	th.Require(err == nil && bytesRetrieved == 0, "empty object must be stored")
+	err = ss.PutObjectFromReader(ctx, "songs", "b.flac", strings.NewReader(contents), -1, nil)
+	th.Require(errors.Is(err, ErrInvalidArgument), "negative content length must be rejected")
+}
+
+func TestS3GetObjectRange(t *testing.T) {
+	th := NewTestHelper(t)
+	ctx := context.Background()
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	ss.CreateContainer(ctx, "songs")
+	ss.PutObject(ctx, "songs", "a.flac", []byte("0123456789"), nil)
+
+	var buffer bytes.Buffer
+	bytesRetrieved, err := ss.GetObjectRange(ctx, "songs", "a.flac", 4, 0, &buffer)
+	th.Require(err == nil && bytesRetrieved == 6, "GetObjectRange must retrieve rest of object")
+	th.RequireStringEquals(buffer.String(), "456789", "GetObjectRange must start at offset")
+
+	buffer.Reset()
+	bytesRetrieved, err = ss.GetObjectRange(ctx, "songs", "a.flac", 2, 3, &buffer)
+	th.Require(err == nil && bytesRetrieved == 3, "GetObjectRange must honor length")
+	th.RequireStringEquals(buffer.String(), "234", "GetObjectRange must retrieve requested range")
+
+	_, err = ss.GetObjectRange(ctx, "songs", "a.flac", 10, 0, &buffer)
+	th.Require(errors.Is(err, ErrInvalidArgument), "offset past end of object must be rejected")
+}
+
+func TestS3ErrorMapping(t *testing.T) {
+	th := NewTestHelper(t)
+	fake := newFakeS3Server()
+	defer fake.Close()
+
+	ss := NewS3StorageSystem("test-access-key", "test-secret-key", fake.server.URL, "us-east-1", false)
+	_, err := ss.GetContainerNames(context.Background())
+	th.Require(err != nil, "operations before Enter must fail")
+
+	fake.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fakeS3WriteError(w, r, http.StatusForbidden, "AccessDenied")
+	})
+	ss.Enter(context.Background())
+	_, err = ss.GetContainerNames(context.Background())
+	th.Require(errors.Is(err, ErrAuth), "access denied must map to ErrAuth")
+}
+
+func TestS3CancelInFlightDownload(t *testing.T) {
+	th := NewTestHelper(t)
+	fake := newFakeS3Server()
+	defer fake.Close()
+	ss := fake.newStorageSystem(t)
+	defer ss.Exit()
+
+	ss.CreateContainer(context.Background(), "w-artist-songs")
+	ss.PutObject(context.Background(), "w-artist-songs", "The-Who--Whos-Next--My-Wife.flac", []byte("flac"), nil)
+
+	// simulate a slow download that only ends when the client goes away
+	requestStarted := make(chan struct{})
+	fake.onGetObject = func(r *http.Request) {
+		close(requestStarted)
+		<-r.Context().Done()
+	}
+
+	jukebox := NewJukebox(NewJukeboxOptions(), ss, "", false)
+	fm := NewFileMetadata()
+	fm.FileUid = "The-Who--Whos-Next--My-Wife.flac"
+	fm.ContainerName = "w-artist-songs"
+	fm.ObjectName = fm.FileUid
+
+	downloadDone := make(chan int64)
+	go func() {
+		downloadDone <- jukebox.retrieveFile(fm, t.TempDir())
+	}()
+
+	<-requestStarted
+	jukebox.PrepareForTermination()
+
+	select {
+	case bytesRetrieved := <-downloadDone:
+		th.Require(bytesRetrieved == 0, "cancelled download must not report bytes")
+	case <-time.After(5 * time.Second):
+		t.Fatal("PrepareForTermination must cancel the in-flight download")
+	}
+}
@@ -0,0 +1,223 @@
+package jukebox
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ShuffleRandom = "random"
+	ShuffleSmart  = "smart"
+	ShuffleAlbum  = "album"
+
+	defaultArtistGap = 3
+)
+
+func ShuffleModes() []string {
+	return []string{ShuffleRandom, ShuffleSmart, ShuffleAlbum}
+}
+
+// Shuffler puts songs in a shuffled order. shuffles made with the same
+// seed come out the same, so a seed of 0 picks one based on the time.
+type Shuffler struct {
+	mode      string
+	seed      int64
+	rng       *rand.Rand
+	artistGap int
+}
+
+func NewShuffler(mode string, seed int64) *Shuffler {
+	var shuffler Shuffler
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	shuffler.mode = mode
+	shuffler.seed = seed
+	shuffler.rng = rand.New(rand.NewSource(seed))
+	shuffler.artistGap = defaultArtistGap
+	return &shuffler
+}
+
+// Seed returns the seed that the shuffles are made with.
+func (shuffler *Shuffler) Seed() int64 {
+	return shuffler.seed
+}
+
+// SetArtistGap sets the number of songs that have to play before an
+// artist can be heard again with smart shuffle.
+func (shuffler *Shuffler) SetArtistGap(artistGap int) {
+	shuffler.artistGap = artistGap
+}
+
+// Shuffle returns the songs in shuffled order. the songs given are left
+// as they are.
+func (shuffler *Shuffler) Shuffle(songs []*SongMetadata) []*SongMetadata {
+	switch shuffler.mode {
+	case ShuffleSmart:
+		return shuffler.smartShuffle(songs)
+	case ShuffleAlbum:
+		return shuffler.albumShuffle(songs)
+	default:
+		return shuffler.randomShuffle(songs)
+	}
+}
+
+func (shuffler *Shuffler) randomShuffle(songs []*SongMetadata) []*SongMetadata {
+	shuffled := append([]*SongMetadata{}, songs...)
+	shuffler.rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+func songArtist(song *SongMetadata) string {
+	if len(song.ArtistName) > 0 {
+		return song.ArtistName
+	}
+	if song.Fm != nil {
+		return artistFromFileName(song.Fm.FileUid)
+	}
+	return ""
+}
+
+// smartShuffle plays every song once, in random order, keeping songs by
+// the same artist at least artistGap songs apart. the artist with the
+// most songs left is played whenever waiting any longer would leave too
+// few other songs to keep it apart. when the songs can't be kept apart,
+// the gap is made as big as it can be.
+func (shuffler *Shuffler) smartShuffle(songs []*SongMetadata) []*SongMetadata {
+	// the songs of each artist, in random order
+	artists := []string{}
+	artistSongs := make(map[string][]*SongMetadata)
+	for _, song := range shuffler.randomShuffle(songs) {
+		artist := songArtist(song)
+		if _, isPresent := artistSongs[artist]; !isPresent {
+			artists = append(artists, artist)
+		}
+		artistSongs[artist] = append(artistSongs[artist], song)
+	}
+
+	shuffled := []*SongMetadata{}
+	lastPlayed := make(map[string]int)
+
+	canPlay := func(artist string) bool {
+		played, wasPlayed := lastPlayed[artist]
+		return !wasPlayed || len(shuffled)-played > shuffler.artistGap
+	}
+
+	for len(artists) > 0 {
+		mostArtist := artists[0]
+		for _, artist := range artists {
+			if len(artistSongs[artist]) > len(artistSongs[mostArtist]) {
+				mostArtist = artist
+			}
+		}
+		songsLeft := len(songs) - len(shuffled)
+		mostArtistDue := (len(artistSongs[mostArtist])-1)*(shuffler.artistGap+1)+1 >= songsLeft
+
+		// each song that can be played is as likely as the others to be
+		// next, so artists with more songs left come up more often
+		candidates := []int{}
+		candidateSongs := 0
+		for i, artist := range artists {
+			if canPlay(artist) && (!mostArtistDue || !canPlay(mostArtist) || artist == mostArtist) {
+				candidates = append(candidates, i)
+				candidateSongs += len(artistSongs[artist])
+			}
+		}
+
+		pick := -1
+		if candidateSongs > 0 {
+			songIndex := shuffler.rng.Intn(candidateSongs)
+			for _, i := range candidates {
+				songIndex -= len(artistSongs[artists[i]])
+				if songIndex < 0 {
+					pick = i
+					break
+				}
+			}
+		} else {
+			// failing that, the artist heard least recently
+			pick = 0
+			for i, artist := range artists {
+				if lastPlayed[artist] < lastPlayed[artists[pick]] {
+					pick = i
+				}
+			}
+		}
+
+		artist := artists[pick]
+		song := artistSongs[artist][0]
+		artistSongs[artist] = artistSongs[artist][1:]
+		if len(artistSongs[artist]) == 0 {
+			artists = append(artists[:pick], artists[pick+1:]...)
+		}
+		lastPlayed[artist] = len(shuffled)
+		shuffled = append(shuffled, song)
+	}
+
+	return shuffled
+}
+
+// albumUidForSong returns the uid of the album that the song is on. songs
+// imported before album uids were stored are grouped by their artist and
+// the album in their uid.
+func albumUidForSong(song *SongMetadata) string {
+	if len(song.AlbumUid) > 0 {
+		return song.AlbumUid
+	}
+	if song.Fm == nil {
+		return ""
+	}
+	return EncodeArtistAlbum(songArtist(song), albumFromFileName(song.Fm.FileUid))
+}
+
+// songBaseName returns the uid of the song without its extension.
+func songBaseName(songUid string) string {
+	posDot := strings.Index(songUid, ".")
+	if posDot > 0 {
+		return songUid[0:posDot]
+	}
+	return songUid
+}
+
+// albumShuffle shuffles the order of the albums, but plays the songs on
+// each album in disc and track order. songs without a track number play
+// after the ones that have one.
+func (shuffler *Shuffler) albumShuffle(songs []*SongMetadata) []*SongMetadata {
+	albumUids := []string{}
+	albumSongs := make(map[string][]*SongMetadata)
+	for _, song := range songs {
+		albumUid := albumUidForSong(song)
+		if _, isPresent := albumSongs[albumUid]; !isPresent {
+			albumUids = append(albumUids, albumUid)
+		}
+		albumSongs[albumUid] = append(albumSongs[albumUid], song)
+	}
+
+	shuffler.rng.Shuffle(len(albumUids), func(i, j int) {
+		albumUids[i], albumUids[j] = albumUids[j], albumUids[i]
+	})
+
+	shuffled := []*SongMetadata{}
+	for _, albumUid := range albumUids {
+		shuffled = append(shuffled, albumInTrackOrder(albumSongs[albumUid])...)
+	}
+	return shuffled
+}
+
+func albumInTrackOrder(songs []*SongMetadata) []*SongMetadata {
+	ordered := append([]*SongMetadata{}, songs...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if (ordered[i].TrackNumber > 0) != (ordered[j].TrackNumber > 0) {
+			return ordered[i].TrackNumber > 0
+		}
+		if ordered[i].DiscNumber != ordered[j].DiscNumber {
+			return ordered[i].DiscNumber < ordered[j].DiscNumber
+		}
+		return ordered[i].TrackNumber < ordered[j].TrackNumber
+	})
+	return ordered
+}
@@ -0,0 +1,104 @@
+package jukebox
+
+import "testing"
+
+func newTestShuffleSongs() []*SongMetadata {
+	return newTestQueueSongs(
+		"The-Who--Whos-Next--Baba-ORiley.mp3",
+		"The-Who--Whos-Next--Bargain.mp3",
+		"The-Who--Whos-Next--My-Wife.mp3",
+		"The-Who--Tommy--Pinball-Wizard.mp3",
+		"The-Who--Tommy--Overture.mp3",
+		"The-Kinks--Face-to-Face--Sunny-Afternoon.mp3",
+		"The-Kinks--Face-to-Face--Rosy-Wont-You-Please-Come-Home.mp3",
+		"The-Beatles--Revolver--Taxman.mp3",
+		"The-Beatles--Revolver--Eleanor-Rigby.mp3",
+		"Cream--Disraeli-Gears--Strange-Brew.mp3",
+	)
+}
+
+func TestShuffleRandom(t *testing.T) {
+	th := NewTestHelper(t)
+	songs := newTestShuffleSongs()
+	order := queueUids(songs)
+
+	shuffled := NewShuffler(ShuffleRandom, 42).Shuffle(songs)
+	th.Require(len(shuffled) == len(songs), "shuffle must keep every song")
+	th.RequireStringEquals(queueUids(songs), order, "shuffle must not change songs given")
+	th.RequireStringEquals(queueUids(NewShuffler(ShuffleRandom, 42).Shuffle(songs)), queueUids(shuffled),
+		"same seed must give same shuffle")
+	th.Require(queueUids(NewShuffler(ShuffleRandom, 43).Shuffle(songs)) != queueUids(shuffled),
+		"different seed must give different shuffle")
+	th.Require(NewShuffler(ShuffleRandom, 0).Seed() != 0, "seed must be picked when not given")
+}
+
+func TestShuffleSmart(t *testing.T) {
+	th := NewTestHelper(t)
+	songs := newTestShuffleSongs()
+
+	for seed := int64(1); seed <= 20; seed++ {
+		shuffler := NewShuffler(ShuffleSmart, seed)
+		shuffler.SetArtistGap(1)
+		shuffled := shuffler.Shuffle(songs)
+		th.Require(len(shuffled) == len(songs), "smart shuffle must keep every song")
+
+		played := make(map[*SongMetadata]bool)
+		for i, song := range shuffled {
+			th.RequireFalse(played[song], "smart shuffle must play every song once")
+			played[song] = true
+			if i > 0 {
+				th.Require(songArtist(song) != songArtist(shuffled[i-1]),
+					"smart shuffle must not play artist twice in a row")
+			}
+		}
+	}
+
+	// with only one artist, the gap can't be kept but every song still plays
+	sameArtist := newTestQueueSongs("A--B--C.mp3", "A--B--D.mp3", "A--B--E.mp3")
+	th.Require(len(NewShuffler(ShuffleSmart, 7).Shuffle(sameArtist)) == 3,
+		"smart shuffle must play songs that can't be kept apart")
+}
+
+func TestShuffleAlbum(t *testing.T) {
+	th := NewTestHelper(t)
+	songs := newTestShuffleSongs()
+	trackNumbers := map[string]int{
+		"The-Who--Whos-Next--Baba-ORiley.mp3": 1,
+		"The-Who--Whos-Next--Bargain.mp3":     2,
+		"The-Who--Whos-Next--My-Wife.mp3":     9,
+	}
+	for _, song := range songs {
+		song.TrackNumber = trackNumbers[song.Fm.FileUid]
+	}
+	// a song whose uid doesn't have the album in it is grouped by the
+	// album uid stored at import
+	bonusTrack := newTestQueueSongs("The-Who--Whos-Next-Deluxe--Pure-and-Easy.mp3")[0]
+	bonusTrack.AlbumUid = "The-Who--Whos-Next"
+	bonusTrack.TrackNumber = 10
+	songs = append([]*SongMetadata{bonusTrack}, songs...)
+
+	shuffled := NewShuffler(ShuffleAlbum, 5).Shuffle(songs)
+	th.Require(len(shuffled) == len(songs), "album shuffle must keep every song")
+
+	// songs from an album must play together, in track order
+	albumUids := []string{}
+	for i, song := range shuffled {
+		albumUid := albumUidForSong(song)
+		if i == 0 || albumUid != albumUids[len(albumUids)-1] {
+			for _, seenUid := range albumUids {
+				th.Require(seenUid != albumUid, "album songs must play together")
+			}
+			albumUids = append(albumUids, albumUid)
+		}
+		if albumUid == "The-Who--Whos-Next" {
+			th.RequireStringEquals(queueUids(shuffled[i:i+4]),
+				"The-Who--Whos-Next--Baba-ORiley.mp3The-Who--Whos-Next--Bargain.mp3"+
+					"The-Who--Whos-Next--My-Wife.mp3The-Who--Whos-Next-Deluxe--Pure-and-Easy.mp3",
+				"album songs must play in track order")
+			break
+		}
+	}
+
+	th.RequireStringEquals(queueUids(NewShuffler(ShuffleAlbum, 5).Shuffle(songs)),
+		queueUids(NewShuffler(ShuffleAlbum, 5).Shuffle(songs)), "same seed must give same album order")
+}
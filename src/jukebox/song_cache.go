@@ -0,0 +1,245 @@
+package jukebox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	songCacheDir             = "song-cache"
+	defaultSongCacheMaxBytes = 1024 * 1024 * 1024
+)
+
+// SongCache keeps copies of downloaded songs on local disk so that
+// replaying a song doesn't require downloading it again. entries are
+// keyed by FileUid and Md5Hash (a re-imported song with new contents is
+// a different entry). the modification time of an entry is its last use,
+// which lets the least recently used entries be evicted once the cache
+// grows beyond its byte budget, and keeps that ordering across restarts.
+type SongCache struct {
+	cacheDir string
+	maxBytes int64
+	mutex    sync.Mutex
+}
+
+type songCacheEntry struct {
+	fileName string
+	size     int64
+	lastUsed time.Time
+}
+
+func NewSongCache(cacheDir string, maxBytes int64) *SongCache {
+	var sc SongCache
+	sc.cacheDir = cacheDir
+	sc.maxBytes = maxBytes
+	return &sc
+}
+
+// OpenSongCache returns the song cache for the current directory
+// configured by the options.
+func OpenSongCache(options *JukeboxOptions) *SongCache {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Println("error: unable to determine current working directory")
+		return nil
+	}
+	return NewSongCache(PathJoin(cwd, songCacheDir), options.SongCacheMaxBytes)
+}
+
+func (sc *SongCache) IsEnabled() bool {
+	return sc.maxBytes > 0
+}
+
+func (sc *SongCache) entryFileName(fm *FileMetadata) string {
+	if len(fm.Md5Hash) > 0 {
+		return fm.Md5Hash + "-" + fm.FileUid
+	}
+	return fm.FileUid
+}
+
+func (sc *SongCache) entryPath(fm *FileMetadata) string {
+	return PathJoin(sc.cacheDir, sc.entryFileName(fm))
+}
+
+// Retrieve copies the cached song to filePath. returns false if the
+// song is not in the cache.
+func (sc *SongCache) Retrieve(fm *FileMetadata, filePath string) bool {
+	if !sc.IsEnabled() || fm == nil {
+		return false
+	}
+
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	entryPath := sc.entryPath(fm)
+	if !FileExists(entryPath) {
+		return false
+	}
+	if fm.StoredFileSize > 0 && GetFileSize(entryPath) != fm.StoredFileSize {
+		// not something we can use
+		DeleteFile(entryPath)
+		return false
+	}
+
+	if !linkOrCopyFile(entryPath, filePath) {
+		return false
+	}
+
+	now := time.Now()
+	os.Chtimes(entryPath, now, now)
+	return true
+}
+
+// Store adds the song at filePath to the cache and evicts the least
+// recently used songs if the cache is over its budget.
+func (sc *SongCache) Store(fm *FileMetadata, filePath string) bool {
+	if !sc.IsEnabled() || fm == nil {
+		return false
+	}
+
+	fileSize := GetFileSize(filePath)
+	if fileSize <= 0 || fileSize > sc.maxBytes {
+		return false
+	}
+
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if !DirectoryExists(sc.cacheDir) && !CreateDirectory(sc.cacheDir) {
+		fmt.Printf("error: unable to create song cache directory '%s'\n", sc.cacheDir)
+		return false
+	}
+
+	entryPath := sc.entryPath(fm)
+	if FileExists(entryPath) {
+		DeleteFile(entryPath)
+	}
+	if !linkOrCopyFile(filePath, entryPath) {
+		fmt.Printf("error: unable to add '%s' to song cache\n", fm.FileUid)
+		return false
+	}
+
+	now := time.Now()
+	os.Chtimes(entryPath, now, now)
+	sc.evict(sc.maxBytes)
+	return true
+}
+
+// entries returns the cache entries, least recently used first.
+func (sc *SongCache) entries() []songCacheEntry {
+	var cacheEntries []songCacheEntry
+
+	dirEntries, err := os.ReadDir(sc.cacheDir)
+	if err != nil {
+		return cacheEntries
+	}
+
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		cacheEntries = append(cacheEntries, songCacheEntry{
+			fileName: dirEntry.Name(),
+			size:     info.Size(),
+			lastUsed: info.ModTime(),
+		})
+	}
+
+	sort.Slice(cacheEntries, func(i, j int) bool {
+		return cacheEntries[i].lastUsed.Before(cacheEntries[j].lastUsed)
+	})
+	return cacheEntries
+}
+
+// evict deletes least recently used entries until the cache holds no
+// more than maxBytes.
+func (sc *SongCache) evict(maxBytes int64) {
+	cacheEntries := sc.entries()
+
+	var totalBytes int64
+	for _, entry := range cacheEntries {
+		totalBytes += entry.size
+	}
+
+	for _, entry := range cacheEntries {
+		if totalBytes <= maxBytes {
+			break
+		}
+		if DeleteFile(PathJoin(sc.cacheDir, entry.fileName)) {
+			totalBytes -= entry.size
+		}
+	}
+}
+
+// Status returns the number of songs in the cache and their total size.
+func (sc *SongCache) Status() (int, int64) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	cacheEntries := sc.entries()
+	var totalBytes int64
+	for _, entry := range cacheEntries {
+		totalBytes += entry.size
+	}
+	return len(cacheEntries), totalBytes
+}
+
+func (sc *SongCache) ShowStatus() {
+	songCount, totalBytes := sc.Status()
+	fmt.Printf("song cache directory: %s\n", sc.cacheDir)
+	if sc.IsEnabled() {
+		fmt.Printf("song cache budget: %d MB\n", sc.maxBytes/(1024*1024))
+	} else {
+		fmt.Println("song cache budget: disabled")
+	}
+	fmt.Printf("songs in cache: %d\n", songCount)
+	fmt.Printf("bytes in cache: %d\n", totalBytes)
+}
+
+// Clear deletes every song in the cache.
+func (sc *SongCache) Clear() bool {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if !DirectoryExists(sc.cacheDir) {
+		return true
+	}
+	return DeleteFilesInDirectory(sc.cacheDir)
+}
+
+// linkOrCopyFile makes destPath a hard link to srcPath, or a copy of it
+// when a link isn't possible (e.g., different file systems).
+func linkOrCopyFile(srcPath string, destPath string) bool {
+	if FileExists(destPath) {
+		DeleteFile(destPath)
+	}
+	if os.Link(srcPath, destPath) == nil {
+		return true
+	}
+
+	srcFile, err := os.Open(srcPath)
+	if err != nil {
+		return false
+	}
+	defer srcFile.Close()
+
+	destFile, err := os.Create(destPath)
+	if err != nil {
+		return false
+	}
+	_, err = io.Copy(destFile, srcFile)
+	errClose := destFile.Close()
+	if err != nil || errClose != nil {
+		DeleteFile(destPath)
+		return false
+	}
+	return true
+}
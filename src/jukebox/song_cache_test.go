@@ -0,0 +1,82 @@
+package jukebox
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func newCacheTestSong(t *testing.T, dir string, fileUid string, contents string) (*FileMetadata, string) {
+	filePath := PathJoin(dir, fileUid)
+	FileWriteAllText(filePath, contents)
+	md5Hash, err := Md5ForFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm := NewFileMetadata()
+	fm.FileUid = fileUid
+	fm.Md5Hash = md5Hash
+	fm.StoredFileSize = int64(len(contents))
+	return fm, filePath
+}
+
+func setLastUsed(sc *SongCache, fm *FileMetadata, lastUsed time.Time) {
+	os.Chtimes(sc.entryPath(fm), lastUsed, lastUsed)
+}
+
+func TestSongCacheStoreRetrieve(t *testing.T) {
+	th := NewTestHelper(t)
+	testDir := t.TempDir()
+	sc := NewSongCache(PathJoin(testDir, songCacheDir), 1000)
+
+	fm, filePath := newCacheTestSong(t, testDir, "The-Who--Whos-Next--My-Wife.mp3", "my wife audio")
+	destPath := PathJoin(testDir, "played.mp3")
+	th.RequireFalse(sc.Retrieve(fm, destPath), "empty cache must not have song")
+	th.Require(sc.Store(fm, filePath), "Store must succeed")
+
+	// the original can go away (e.g., deleted after it's played)
+	DeleteFile(filePath)
+	th.Require(sc.Retrieve(fm, destPath), "cached song must be retrieved")
+	text, _ := FileReadAllText(destPath)
+	th.RequireStringEquals(text, "my wife audio", "retrieved song must match stored song")
+
+	changedFm := *fm
+	changedFm.Md5Hash = "0123456789abcdef"
+	th.RequireFalse(sc.Retrieve(&changedFm, destPath), "song with different hash must not be retrieved")
+
+	// the cache lives on disk, so a new instance sees the same songs
+	sc = NewSongCache(PathJoin(testDir, songCacheDir), 1000)
+	songCount, totalBytes := sc.Status()
+	th.Require(songCount == 1 && totalBytes == 13, "cache must survive restart")
+
+	th.Require(sc.Clear(), "Clear must succeed")
+	songCount, _ = sc.Status()
+	th.Require(songCount == 0, "cache must be empty after Clear")
+}
+
+func TestSongCacheEviction(t *testing.T) {
+	th := NewTestHelper(t)
+	testDir := t.TempDir()
+	sc := NewSongCache(PathJoin(testDir, songCacheDir), 25)
+
+	fmA, pathA := newCacheTestSong(t, testDir, "a.mp3", "aaaaaaaaaa")
+	fmB, pathB := newCacheTestSong(t, testDir, "b.mp3", "bbbbbbbbbb")
+	fmC, pathC := newCacheTestSong(t, testDir, "c.mp3", "cccccccccc")
+	sc.Store(fmA, pathA)
+	sc.Store(fmB, pathB)
+	setLastUsed(sc, fmA, time.Now().Add(-2*time.Hour))
+	setLastUsed(sc, fmB, time.Now().Add(-1*time.Hour))
+
+	// using a makes b the least recently used
+	th.Require(sc.Retrieve(fmA, PathJoin(testDir, "played.mp3")), "a must be cached")
+	th.Require(sc.Store(fmC, pathC), "Store must succeed")
+
+	songCount, totalBytes := sc.Status()
+	th.Require(songCount == 2 && totalBytes == 20, "cache must stay within budget")
+	th.Require(FileExists(sc.entryPath(fmA)), "recently used song must be kept")
+	th.RequireFalse(FileExists(sc.entryPath(fmB)), "least recently used song must be evicted")
+	th.Require(FileExists(sc.entryPath(fmC)), "newly stored song must be kept")
+
+	disabled := NewSongCache(PathJoin(testDir, "disabled"), 0)
+	th.RequireFalse(disabled.Store(fmA, pathA), "disabled cache must not store songs")
+}
@@ -1,28 +1,173 @@
 package jukebox
 
+import "sync"
+
+// SongDownloader is a fixed-size pool of workers that download songs
+// into the song-play directory. songs waiting to be downloaded are kept
+// in priority order (first is most urgent) and a song is never
+// downloaded by more than one worker at a time.
 type SongDownloader struct {
-	jukebox   *Jukebox
-	listSongs []*SongMetadata
+	jukebox       *Jukebox
+	numberWorkers int
+	mutex         sync.Mutex
+	workAvailable *sync.Cond
+	pending       []*SongMetadata
+	inFlight      map[string]bool
+	running       bool
+	stopRequested bool
+	batchActive   bool
+	waitGroup     sync.WaitGroup
 }
 
-func NewSongDownloader(jukebox *Jukebox,
-	listSongs []*SongMetadata) *SongDownloader {
+func NewSongDownloader(jukebox *Jukebox, numberWorkers int) *SongDownloader {
 	var sd SongDownloader
 	sd.jukebox = jukebox
-	sd.listSongs = listSongs
+	if numberWorkers < 1 {
+		numberWorkers = 1
+	}
+	sd.numberWorkers = numberWorkers
+	sd.workAvailable = sync.NewCond(&sd.mutex)
+	sd.pending = []*SongMetadata{}
+	sd.inFlight = make(map[string]bool)
+	sd.running = false
+	sd.stopRequested = false
+	sd.batchActive = false
 	return &sd
 }
 
-func (sd *SongDownloader) run() {
-	if sd.jukebox != nil && sd.listSongs != nil {
+// Start launches the download workers.
+func (sd *SongDownloader) Start() {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	if sd.running || sd.stopRequested {
+		return
+	}
+	sd.running = true
+	for i := 0; i < sd.numberWorkers; i++ {
+		sd.waitGroup.Add(1)
+		go sd.run()
+	}
+}
+
+// Stop discards any pending downloads and waits for the workers to
+// finish the downloads that they're working on.
+func (sd *SongDownloader) Stop() {
+	sd.mutex.Lock()
+	sd.stopRequested = true
+	sd.pending = []*SongMetadata{}
+	sd.workAvailable.Broadcast()
+	sd.mutex.Unlock()
+
+	sd.waitGroup.Wait()
+}
+
+// Schedule replaces the pending downloads with listSongs. songs earlier
+// in the list are downloaded first. songs that are currently being
+// downloaded are not scheduled again.
+func (sd *SongDownloader) Schedule(listSongs []*SongMetadata) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	if sd.stopRequested {
+		return
+	}
+
+	scheduled := make(map[string]bool)
+	pending := []*SongMetadata{}
+	for _, song := range listSongs {
+		if song == nil || song.Fm == nil {
+			continue
+		}
+		fileUid := song.Fm.FileUid
+		if sd.inFlight[fileUid] || scheduled[fileUid] {
+			continue
+		}
+		scheduled[fileUid] = true
+		pending = append(pending, song)
+	}
+	sd.pending = pending
+
+	if len(sd.pending) > 0 && !sd.batchActive {
+		sd.batchActive = true
 		sd.jukebox.batchDownloadStart()
-		for _, song := range sd.listSongs {
-			if sd.jukebox.exitRequested {
-				break
-			} else {
-				sd.jukebox.downloadSong(song)
-			}
+	}
+	// songs may have been dropped from pending, which Wait needs to see
+	sd.workAvailable.Broadcast()
+}
+
+// IsDownloading reports whether the song is pending or being downloaded.
+func (sd *SongDownloader) IsDownloading(fileUid string) bool {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+	return sd.isDownloading(fileUid)
+}
+
+// Wait blocks until the song is no longer pending or being downloaded, or
+// the downloader is stopping.
+func (sd *SongDownloader) Wait(fileUid string) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	for sd.isDownloading(fileUid) && !sd.stopRequested {
+		sd.workAvailable.Wait()
+	}
+}
+
+func (sd *SongDownloader) isDownloading(fileUid string) bool {
+	if sd.inFlight[fileUid] {
+		return true
+	}
+	for _, song := range sd.pending {
+		if song.Fm.FileUid == fileUid {
+			return true
 		}
+	}
+	return false
+}
+
+// nextSong blocks until there's a song to download. returns nil when
+// the downloader is stopping.
+func (sd *SongDownloader) nextSong() *SongMetadata {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	for len(sd.pending) == 0 && !sd.stopRequested {
+		sd.workAvailable.Wait()
+	}
+	if sd.stopRequested {
+		return nil
+	}
+
+	song := sd.pending[0]
+	sd.pending = sd.pending[1:]
+	sd.inFlight[song.Fm.FileUid] = true
+	return song
+}
+
+func (sd *SongDownloader) songDone(song *SongMetadata) {
+	sd.mutex.Lock()
+	defer sd.mutex.Unlock()
+
+	delete(sd.inFlight, song.Fm.FileUid)
+	sd.workAvailable.Broadcast()
+	if sd.batchActive && len(sd.pending) == 0 && len(sd.inFlight) == 0 {
+		sd.batchActive = false
 		sd.jukebox.batchDownloadComplete()
 	}
 }
+
+func (sd *SongDownloader) run() {
+	defer sd.waitGroup.Done()
+
+	for {
+		song := sd.nextSong()
+		if song == nil {
+			break
+		}
+		if !sd.jukebox.isExitRequested() {
+			sd.jukebox.downloadSong(song)
+		}
+		sd.songDone(song)
+	}
+}
@@ -1,9 +1,173 @@
 package jukebox
 
-import "testing"
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingStorageSystem counts the downloads of each object and holds
+// them until release is closed.
+type blockingStorageSystem struct {
+	*FSStorageSystem
+	mutex     sync.Mutex
+	downloads map[string]int
+	started   chan string
+	release   chan struct{}
+}
+
+func (ss *blockingStorageSystem) GetObjectRange(ctx context.Context,
+	containerName string,
+	objectName string,
+	offset int64,
+	length int64,
+	writer io.Writer) (int64, error) {
+	ss.mutex.Lock()
+	ss.downloads[objectName] += 1
+	ss.mutex.Unlock()
+	ss.started <- objectName
+	select {
+	case <-ss.release:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	return ss.FSStorageSystem.GetObjectRange(ctx, containerName, objectName, offset, length, writer)
+}
+
+func (ss *blockingStorageSystem) downloadCount(objectName string) int {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+	return ss.downloads[objectName]
+}
+
+func newDownloaderTestJukebox(t *testing.T) (*Jukebox, *blockingStorageSystem) {
+	jukebox := newTestJukebox(t, NewJukeboxOptions())
+	importTestSongs(t, jukebox, map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3":          "my wife audio",
+		"The-Who--Whos-Next--Baba-ORiley.mp3":      "baba o'riley audio",
+		"The-Who--Whos-Next--Bargain.mp3":          "bargain audio",
+		"The-Who--Whos-Next--Going-Mobile.mp3":     "going mobile audio",
+		"The-Who--Whos-Next--Behind-Blue-Eyes.mp3": "behind blue eyes audio",
+	})
+
+	ss := &blockingStorageSystem{
+		FSStorageSystem: jukebox.storageSystem.(*FSStorageSystem),
+		downloads:       make(map[string]int),
+		started:         make(chan string, 10),
+		release:         make(chan struct{}),
+	}
+	jukebox.storageSystem = ss
+	return jukebox, ss
+}
+
+func waitForFiles(t *testing.T, filePaths []string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for _, filePath := range filePaths {
+		for !FileExists(filePath) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", filePath)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
 
 func TestNewSongDownloader(t *testing.T) {
+	th := NewTestHelper(t)
+	sd := NewSongDownloader(nil, 0)
+	th.Require(sd.numberWorkers == 1, "downloader must have at least one worker")
+	sd = NewSongDownloader(nil, 4)
+	th.Require(sd.numberWorkers == 4, "downloader must use requested number of workers")
+	th.RequireFalse(sd.IsDownloading("The-Who--Whos-Next--My-Wife.mp3"), "new downloader must be idle")
 }
 
 func Test_run(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, ss := newDownloaderTestJukebox(t)
+	songs := jukebox.jukeboxDb.retrieveSongs("", "")
+	th.Require(len(songs) == 5, "all songs must be imported")
+
+	sd := NewSongDownloader(jukebox, 3)
+	sd.Start()
+	defer sd.Stop()
+
+	// the same song scheduled over and over while it's in flight
+	// must only be downloaded once
+	sd.Schedule([]*SongMetadata{songs[0], songs[0]})
+	<-ss.started
+	sd.Schedule([]*SongMetadata{songs[0]})
+	th.Require(sd.IsDownloading(songs[0].Fm.FileUid), "song must be in flight")
+
+	sd.Schedule(songs)
+	close(ss.release)
+
+	var filePaths []string
+	for _, song := range songs {
+		filePaths = append(filePaths, jukebox.songPathInPlaylist(song))
+	}
+	waitForFiles(t, filePaths)
+
+	for _, song := range songs {
+		th.Require(ss.downloadCount(song.Fm.ObjectName) == 1, "each song must be downloaded once")
+	}
+}
+
+func TestSongDownloaderStop(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, ss := newDownloaderTestJukebox(t)
+	songs := jukebox.jukeboxDb.retrieveSongs("", "")
+
+	sd := NewSongDownloader(jukebox, 1)
+	sd.Start()
+	sd.Schedule(songs)
+	<-ss.started
+
+	// cancelling the in-flight download lets Stop return promptly and
+	// the pending songs are never started
+	jukebox.PrepareForTermination()
+	sd.Stop()
+	sd.Schedule(songs)
+
+	downloadCount := 0
+	for _, song := range songs {
+		downloadCount += ss.downloadCount(song.Fm.ObjectName)
+	}
+	th.Require(downloadCount == 1, "only the in-flight song must have been downloaded")
+}
+
+func TestPlaySongWaitsForDownload(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox, ss := newDownloaderTestJukebox(t)
+	songs := jukebox.jukeboxDb.retrieveSongs("", "")
+	jukebox.setAudioPlayer(NewNullAudioPlayer(0))
+	jukebox.songDownloader = NewSongDownloader(jukebox, 1)
+	jukebox.songDownloader.Start()
+	defer jukebox.songDownloader.Stop()
+
+	// the song is still being prefetched when it comes up to be played
+	jukebox.songDownloader.Schedule([]*SongMetadata{songs[0]})
+	<-ss.started
+	played := make(chan bool, 1)
+	go func() {
+		played <- jukebox.playSong(songs[0])
+	}()
+	select {
+	case <-played:
+		close(ss.release)
+		t.Fatal("song must not be played before its download finishes")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(ss.release)
+	select {
+	case songWasPlayed := <-played:
+		th.Require(songWasPlayed, "prefetched song must be played once it's downloaded")
+	case <-time.After(5 * time.Second):
+		t.Fatal("song must be played once it's downloaded")
+	}
+	th.Require(ss.downloadCount(songs[0].Fm.ObjectName) == 1, "song must only be downloaded once")
+
+	// a song that wasn't prefetched is downloaded before it's played
+	th.Require(jukebox.playSong(songs[1]), "song that wasn't prefetched must be played")
 }
@@ -0,0 +1,313 @@
+package jukebox
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// what to do with a song file once it's been imported
+const (
+	AfterImportKeep   = "keep"
+	AfterImportMove   = "move"
+	AfterImportDelete = "delete"
+)
+
+func AfterImportActions() []string {
+	return []string{AfterImportKeep, AfterImportMove, AfterImportDelete}
+}
+
+// SongImporter imports the files in the song import directory. the files
+// go through a pipeline: a pool of workers reads the tags and hash of
+// each file while a second pool uploads the files that are new or have
+// changed since they were last imported, and the goroutine that calls
+// Import stores the metadata of the uploaded songs in batches (one
+// database transaction per batch).
+type SongImporter struct {
+	jukebox        *Jukebox
+	numberWorkers  int
+	batchSize      int
+	afterImport    string
+	onFileDone     func(filesDone int)
+	existingSongs  map[string]*SongMetadata
+	pending        []*importItem
+	NewCount       int
+	ChangedCount   int
+	UnchangedCount int
+	UploadBytes    int64
+	SkippedFiles   []string
+	FailedFiles    []string
+}
+
+// importItem is a file that's on its way through the pipeline. song is
+// nil when the file isn't imported, with skipReason saying why (there's no
+// reason for entries that aren't files).
+type importItem struct {
+	fileName   string
+	song       *SongMetadata
+	skipReason string
+	changed    bool
+	unchanged  bool
+	uploaded   bool
+}
+
+func NewSongImporter(jukebox *Jukebox, numberWorkers int, batchSize int) *SongImporter {
+	var si SongImporter
+	si.jukebox = jukebox
+	if numberWorkers < 1 {
+		numberWorkers = 1
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	si.numberWorkers = numberWorkers
+	si.batchSize = batchSize
+	si.afterImport = AfterImportKeep
+	si.onFileDone = nil
+	si.existingSongs = make(map[string]*SongMetadata)
+	si.pending = []*importItem{}
+	si.NewCount = 0
+	si.ChangedCount = 0
+	si.UnchangedCount = 0
+	si.UploadBytes = 0
+	si.SkippedFiles = []string{}
+	si.FailedFiles = []string{}
+	return &si
+}
+
+// ImportCount is the number of songs that were uploaded and stored.
+func (si *SongImporter) ImportCount() int {
+	return si.NewCount + si.ChangedCount
+}
+
+// Import runs fileNames (entries of the import directory) through the
+// pipeline and returns once all the metadata has been stored. onFileDone
+// is called from the calling goroutine each time a file is finished with.
+func (si *SongImporter) Import(fileNames []string) {
+	// the songs are looked up before the pipeline starts so that the
+	// workers don't read the database while batches are being written
+	jukeboxDb := si.jukebox.metadataDb()
+	if jukeboxDb == nil {
+		si.FailedFiles = append(si.FailedFiles, fileNames...)
+		return
+	}
+	for _, song := range jukeboxDb.retrieveSongs("", "") {
+		si.existingSongs[song.Fm.FileUid] = song
+	}
+
+	fileNameQueue := make(chan string)
+	preparedQueue := make(chan *importItem, si.numberWorkers)
+	uploadQueue := make(chan *importItem, si.numberWorkers)
+	resultQueue := make(chan *importItem, si.numberWorkers)
+
+	go func() {
+		for _, fileName := range fileNames {
+			fileNameQueue <- fileName
+		}
+		close(fileNameQueue)
+	}()
+
+	var prepareGroup sync.WaitGroup
+	for i := 0; i < si.numberWorkers; i++ {
+		prepareGroup.Add(1)
+		go func() {
+			defer prepareGroup.Done()
+			for fileName := range fileNameQueue {
+				preparedQueue <- si.prepare(fileName)
+			}
+		}()
+	}
+	go func() {
+		prepareGroup.Wait()
+		close(preparedQueue)
+	}()
+
+	var uploadGroup sync.WaitGroup
+	uploadGroup.Add(1)
+	go func() {
+		defer uploadGroup.Done()
+		si.dispatch(preparedQueue, uploadQueue, resultQueue)
+	}()
+	for i := 0; i < si.numberWorkers; i++ {
+		uploadGroup.Add(1)
+		go func() {
+			defer uploadGroup.Done()
+			for item := range uploadQueue {
+				si.upload(item)
+				resultQueue <- item
+			}
+		}()
+	}
+	go func() {
+		uploadGroup.Wait()
+		close(resultQueue)
+	}()
+
+	filesDone := 0
+	for item := range resultQueue {
+		if item.uploaded {
+			si.UploadBytes += item.song.Fm.StoredFileSize
+			si.pending = append(si.pending, item)
+			if len(si.pending) >= si.batchSize {
+				si.storeBatch()
+			}
+		} else if item.unchanged {
+			si.UnchangedCount += 1
+			si.finishFile(item.fileName)
+		} else if item.song != nil {
+			si.FailedFiles = append(si.FailedFiles, item.fileName)
+		} else if len(item.skipReason) > 0 {
+			si.SkippedFiles = append(si.SkippedFiles,
+				fmt.Sprintf("%s (%s)", item.fileName, item.skipReason))
+		}
+		filesDone += 1
+		if si.onFileDone != nil {
+			si.onFileDone(filesDone)
+		}
+	}
+	si.storeBatch()
+}
+
+func (si *SongImporter) prepare(fileName string) *importItem {
+	var item importItem
+	item.fileName = fileName
+	fullPath := PathJoin(si.jukebox.songImportDir, fileName)
+	// ignore it if it's not a file
+	if FileExists(fullPath) {
+		item.song, item.skipReason = si.jukebox.songForImport(fullPath, fileName)
+	}
+	return &item
+}
+
+// dispatch passes the prepared songs that are new or changed on to the
+// upload workers. a song is unchanged when the database already has it
+// with the same hash and size. a song with the same uid as an earlier
+// file is skipped so that two workers never upload the same object.
+func (si *SongImporter) dispatch(preparedQueue <-chan *importItem,
+	uploadQueue chan<- *importItem,
+	resultQueue chan<- *importItem) {
+
+	fileForUid := make(map[string]string)
+	for item := range preparedQueue {
+		if item.song != nil {
+			firstFile, found := fileForUid[item.song.Fm.FileUid]
+			if !found {
+				fileForUid[item.song.Fm.FileUid] = item.fileName
+				existingSong := si.existingSongs[item.song.Fm.FileUid]
+				if existingSong != nil && len(item.song.Fm.Md5Hash) > 0 &&
+					existingSong.Fm.Md5Hash == item.song.Fm.Md5Hash &&
+					existingSong.Fm.OriginFileSize == item.song.Fm.OriginFileSize {
+					item.unchanged = true
+					resultQueue <- item
+				} else {
+					item.changed = existingSong != nil
+					uploadQueue <- item
+				}
+				continue
+			}
+			item.song = nil
+			item.skipReason = fmt.Sprintf("same song as %s", firstFile)
+		}
+		resultQueue <- item
+	}
+	close(uploadQueue)
+}
+
+func (si *SongImporter) upload(item *importItem) {
+	jukebox := si.jukebox
+	song := item.song
+	fullPath := PathJoin(jukebox.songImportDir, item.fileName)
+	fileSize := song.Fm.OriginFileSize
+
+	// stream the file contents to the storage system rather
+	// than reading the whole (possibly very large) file into memory
+	songFile, errFile := os.Open(fullPath)
+	if errFile != nil {
+		fmt.Printf("error: unable to read file %s\n", fullPath)
+		return
+	}
+	defer songFile.Close()
+
+	// now that we have the data that will be stored, set the file size for
+	// what's being stored
+	song.Fm.StoredFileSize = fileSize
+
+	containerName := jukebox.containerPrefix + song.Fm.ContainerName
+	errPut := jukebox.storageSystem.PutObjectFromReader(jukebox.ctx,
+		containerName,
+		song.Fm.ObjectName,
+		songFile,
+		fileSize,
+		nil)
+	if errPut != nil {
+		fmt.Printf("error: unable to upload '%s' to '%s'\n",
+			song.Fm.ObjectName,
+			song.Fm.ContainerName)
+		fmt.Printf("error: %v\n", errPut)
+		return
+	}
+	item.uploaded = true
+}
+
+// storeBatch stores the metadata of the songs uploaded since the last
+// batch. a new song that's been uploaded but whose metadata can't be
+// stored is deleted from the storage system, since there'd be no way to
+// access it.
+func (si *SongImporter) storeBatch() {
+	if len(si.pending) == 0 {
+		return
+	}
+	jukebox := si.jukebox
+	songs := []*SongMetadata{}
+	for _, item := range si.pending {
+		songs = append(songs, item.song)
+	}
+	stored := make([]bool, len(songs))
+	if jukeboxDb := jukebox.metadataDb(); jukeboxDb != nil {
+		stored = jukeboxDb.storeSongsMetadata(songs)
+	}
+	for i, item := range si.pending {
+		song := item.song
+		if stored[i] {
+			if item.changed {
+				si.ChangedCount += 1
+			} else {
+				si.NewCount += 1
+			}
+			si.finishFile(item.fileName)
+		} else if item.changed {
+			// the upload replaced the object that the song's existing
+			// metadata refers to, so the object has to stay
+			si.FailedFiles = append(si.FailedFiles, item.fileName)
+			fmt.Printf("unable to store metadata for changed song '%s'\n", song.Fm.ObjectName)
+		} else {
+			si.FailedFiles = append(si.FailedFiles, item.fileName)
+			fmt.Printf("unable to store metadata, deleting obj '%s'\n", song.Fm.ObjectName)
+			jukebox.storageSystem.DeleteObject(jukebox.ctx,
+				jukebox.containerPrefix+song.Fm.ContainerName,
+				song.Fm.ObjectName)
+		}
+	}
+	si.pending = []*importItem{}
+}
+
+// finishFile moves or deletes (if asked to) a file whose song is in the
+// storage system and database.
+func (si *SongImporter) finishFile(fileName string) {
+	fullPath := PathJoin(si.jukebox.songImportDir, fileName)
+	switch si.afterImport {
+	case AfterImportMove:
+		importedDir := si.jukebox.songImportedDir
+		if !DirectoryExists(importedDir) && !CreateDirectory(importedDir) {
+			fmt.Printf("error: unable to create directory %s\n", importedDir)
+			return
+		}
+		if !RenameFile(fullPath, PathJoin(importedDir, fileName)) {
+			fmt.Printf("error: unable to move %s to %s\n", fullPath, importedDir)
+		}
+	case AfterImportDelete:
+		if !DeleteFile(fullPath) {
+			fmt.Printf("error: unable to delete %s\n", fullPath)
+		}
+	}
+}
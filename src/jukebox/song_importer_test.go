@@ -0,0 +1,175 @@
+package jukebox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSongImporter(t *testing.T) {
+	th := NewTestHelper(t)
+	importer := NewSongImporter(nil, 0, -1)
+	th.Require(importer.numberWorkers == 1, "at least one worker")
+	th.Require(importer.batchSize == 1, "batch of at least one song")
+}
+
+func TestSongImporterImport(t *testing.T) {
+	th := NewTestHelper(t)
+	fixturePath, err := filepath.Abs(tagFixturePath("id3v2_and_v1.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagged, err := os.ReadFile(fixturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jukebox := newTestJukebox(t, nil)
+
+	songFiles := map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3":     "my wife audio",
+		"The-Who--Whos-Next--Bargain.mp3":     "bargain audio",
+		"The-Who--Whos-Next--Getting-In.mp3":  "getting in audio",
+		"Aretha-Franklin--Gold--Respect.flac": "respect audio",
+		"Aretha-Franklin--Gold--Think.flac":   "think audio",
+		"not-a-jukebox-song-file-name.mp3":    "skipped",
+	}
+	for fileName, contents := range songFiles {
+		FileWriteAllText(PathJoin(jukebox.songImportDir, fileName), contents)
+	}
+	// both files have the same tags, so only one of them is imported
+	os.WriteFile(PathJoin(jukebox.songImportDir, "a.mp3"), tagged, 0644)
+	os.WriteFile(PathJoin(jukebox.songImportDir, "b.mp3"), tagged, 0644)
+	CreateDirectory(PathJoin(jukebox.songImportDir, "not-a-file"))
+
+	dirListing, err := ListFilesInDirectory(jukebox.songImportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := NewSongImporter(jukebox, 3, 2)
+	progress := []int{}
+	importer.onFileDone = func(filesDone int) {
+		progress = append(progress, filesDone)
+	}
+	importer.Import(dirListing)
+
+	th.Require(len(progress) == len(dirListing), "progress must be reported for each entry")
+	for i, filesDone := range progress {
+		th.Require(filesDone == i+1, "progress must count up by one")
+	}
+	th.Require(importer.ImportCount() == 6, "songs with valid names or tags must be imported")
+	th.Require(len(importer.SkippedFiles) == 2, "badly named file and duplicate must be skipped")
+	th.Require(len(jukebox.jukeboxDb.retrieveSongs("", "")) == 6, "all batches must be stored")
+	th.Require(jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--09-Wont-Get-Fooled-Again.mp3") != nil,
+		"one of the duplicates must be imported")
+
+	uploadBytes := int64(len(tagged))
+	for fileName, contents := range songFiles {
+		if fileName != "not-a-jukebox-song-file-name.mp3" {
+			uploadBytes += int64(len(contents))
+		}
+	}
+	th.Require(importer.UploadBytes == uploadBytes, "skipped files must not count as uploaded")
+}
+
+func TestSongImporterMetadataFailure(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, nil)
+	_, err := jukebox.jukeboxDb.dbConnection.Exec("CREATE TRIGGER reject_song BEFORE INSERT ON song " +
+		"WHEN NEW.song_uid LIKE '%Bargain%' BEGIN SELECT RAISE(ABORT, 'rejected'); END")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for fileName, contents := range map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3": "my wife audio",
+		"The-Who--Whos-Next--Bargain.mp3": "bargain audio",
+	} {
+		FileWriteAllText(PathJoin(jukebox.songImportDir, fileName), contents)
+	}
+	dirListing, err := ListFilesInDirectory(jukebox.songImportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := NewSongImporter(jukebox, 2, 10)
+	importer.Import(dirListing)
+
+	th.Require(importer.ImportCount() == 1, "song with stored metadata must be imported")
+	th.Require(len(importer.FailedFiles) == 1, "song without stored metadata must fail")
+	containerDir := PathJoin(PathJoin(jukebox.currentDir, "storage"), "w-artist-songs")
+	th.Require(FileExists(PathJoin(containerDir, "The-Who--Whos-Next--My-Wife.mp3")),
+		"imported song must stay in storage")
+	th.RequireFalse(FileExists(PathJoin(containerDir, "The-Who--Whos-Next--Bargain.mp3")),
+		"song without metadata must be deleted from storage")
+}
+
+// importSongFiles writes the given songs (file name -> contents) to the
+// song-import directory and imports everything that's there.
+func importSongFiles(t *testing.T, jukebox *Jukebox, afterImport string, songs map[string]string) *SongImporter {
+	for fileName, contents := range songs {
+		FileWriteAllText(PathJoin(jukebox.songImportDir, fileName), contents)
+	}
+	dirListing, err := ListFilesInDirectory(jukebox.songImportDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := NewSongImporter(jukebox, 2, 2)
+	importer.afterImport = afterImport
+	importer.Import(dirListing)
+	return importer
+}
+
+func TestSongImporterIncremental(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, nil)
+	importer := importSongFiles(t, jukebox, AfterImportKeep, map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3": "my wife audio",
+		"The-Who--Whos-Next--Bargain.mp3": "bargain audio",
+	})
+	th.Require(importer.NewCount == 2, "first import must add all songs")
+	original := jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--Bargain.mp3")
+
+	importer = importSongFiles(t, jukebox, AfterImportKeep, map[string]string{
+		"The-Who--Whos-Next--Bargain.mp3":    "bargain audio, remastered",
+		"The-Who--Whos-Next--Getting-In.mp3": "getting in audio",
+	})
+	th.Require(importer.NewCount == 1, "new song must be added")
+	th.Require(importer.ChangedCount == 1, "changed song must be uploaded again")
+	th.Require(importer.UnchangedCount == 1, "unchanged song must be skipped")
+	th.Require(importer.UploadBytes == int64(len("bargain audio, remastered")+len("getting in audio")),
+		"unchanged song must not be uploaded")
+
+	song := jukebox.jukeboxDb.retrieveSong("The-Who--Whos-Next--Bargain.mp3")
+	th.Require(song != nil && original != nil, "changed song must still be in database")
+	if song != nil && original != nil {
+		th.Require(song.Fm.Md5Hash != original.Fm.Md5Hash, "changed song must have new hash")
+		th.Require(song.Fm.OriginFileSize == int64(len("bargain audio, remastered")), "changed song must have new size")
+	}
+}
+
+func TestSongImporterAfterImport(t *testing.T) {
+	th := NewTestHelper(t)
+	jukebox := newTestJukebox(t, nil)
+	importSongFiles(t, jukebox, AfterImportMove, map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3":  "my wife audio",
+		"not-a-jukebox-song-file-name.mp3": "skipped",
+	})
+	th.RequireFalse(FileExists(PathJoin(jukebox.songImportDir, "The-Who--Whos-Next--My-Wife.mp3")),
+		"imported file must be moved")
+	th.Require(FileExists(PathJoin(jukebox.songImportedDir, "The-Who--Whos-Next--My-Wife.mp3")),
+		"imported file must be in song-imported")
+	th.Require(FileExists(PathJoin(jukebox.songImportDir, "not-a-jukebox-song-file-name.mp3")),
+		"skipped file must stay")
+
+	// an unchanged file is already in the jukebox, so it's deleted too
+	importer := importSongFiles(t, jukebox, AfterImportDelete, map[string]string{
+		"The-Who--Whos-Next--My-Wife.mp3": "my wife audio",
+		"The-Who--Whos-Next--Bargain.mp3": "bargain audio",
+	})
+	th.Require(importer.UnchangedCount == 1 && importer.NewCount == 1, "one unchanged and one new song")
+	th.RequireFalse(FileExists(PathJoin(jukebox.songImportDir, "The-Who--Whos-Next--My-Wife.mp3")),
+		"unchanged file must be deleted")
+	th.RequireFalse(FileExists(PathJoin(jukebox.songImportDir, "The-Who--Whos-Next--Bargain.mp3")),
+		"imported file must be deleted")
+	th.Require(FileExists(PathJoin(jukebox.songImportDir, "not-a-jukebox-song-file-name.mp3")),
+		"skipped file must stay")
+}
@@ -1,11 +1,21 @@
 package jukebox
 
+import "strconv"
+
 type SongMetadata struct {
-	Fm         *FileMetadata
-	ArtistUid  string
-	ArtistName string
-	AlbumUid   string
-	SongName   string
+	Fm          *FileMetadata
+	ArtistUid   string
+	ArtistName  string
+	AlbumUid    string
+	SongName    string
+	TrackNumber int
+	DiscNumber  int
+	Year        int
+	Genre       string
+	DurationMs  int64
+	Bitrate     int
+	SampleRate  int
+	Channels    int
 }
 
 func (sm *SongMetadata) Equals(other *SongMetadata) bool {
@@ -21,7 +31,15 @@ func (sm *SongMetadata) Equals(other *SongMetadata) bool {
 	return sm.ArtistUid == other.ArtistUid &&
 		sm.ArtistName == other.ArtistName &&
 		sm.AlbumUid == other.AlbumUid &&
-		sm.SongName == other.SongName
+		sm.SongName == other.SongName &&
+		sm.TrackNumber == other.TrackNumber &&
+		sm.DiscNumber == other.DiscNumber &&
+		sm.Year == other.Year &&
+		sm.Genre == other.Genre &&
+		sm.DurationMs == other.DurationMs &&
+		sm.Bitrate == other.Bitrate &&
+		sm.SampleRate == other.SampleRate &&
+		sm.Channels == other.Channels
 }
 
 func NewSongMetadata() *SongMetadata {
@@ -31,6 +49,14 @@ func NewSongMetadata() *SongMetadata {
 	sm.ArtistName = "" // keep temporarily until ArtistUid is hooked up to artist table
 	sm.AlbumUid = ""
 	sm.SongName = ""
+	sm.TrackNumber = 0
+	sm.DiscNumber = 0
+	sm.Year = 0
+	sm.Genre = ""
+	sm.DurationMs = 0
+	sm.Bitrate = 0
+	sm.SampleRate = 0
+	sm.Channels = 0
 	return &sm
 }
 
@@ -58,6 +84,59 @@ func (sm *SongMetadata) FromDictionaryWithPrefix(dictionary map[string]string,
 	if value, isPresent := dictionary[prefix+"SongName"]; isPresent {
 		sm.SongName = value
 	}
+
+	if value, isPresent := dictionary[prefix+"TrackNumber"]; isPresent {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			sm.TrackNumber = intValue
+		}
+	}
+
+	if value, isPresent := dictionary[prefix+"DiscNumber"]; isPresent {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			sm.DiscNumber = intValue
+		}
+	}
+
+	if value, isPresent := dictionary[prefix+"Year"]; isPresent {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			sm.Year = intValue
+		}
+	}
+
+	if value, isPresent := dictionary[prefix+"Genre"]; isPresent {
+		sm.Genre = value
+	}
+
+	if value, isPresent := dictionary[prefix+"DurationMs"]; isPresent {
+		intValue, err := strconv.ParseInt(value, 10, 64)
+		if err == nil {
+			sm.DurationMs = intValue
+		}
+	}
+
+	if value, isPresent := dictionary[prefix+"Bitrate"]; isPresent {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			sm.Bitrate = intValue
+		}
+	}
+
+	if value, isPresent := dictionary[prefix+"SampleRate"]; isPresent {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			sm.SampleRate = intValue
+		}
+	}
+
+	if value, isPresent := dictionary[prefix+"Channels"]; isPresent {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
+			sm.Channels = intValue
+		}
+	}
 }
 
 func (sm *SongMetadata) ToDictionary() map[string]string {
@@ -69,10 +148,18 @@ func (sm *SongMetadata) ToDictionaryWithPrefix(prefix string) map[string]string
 	sm.Fm.ToDictionaryWithPrefix(prefix)
 
 	smDict := map[string]string{
-		prefix + "ArtistUid":  sm.ArtistUid,
-		prefix + "ArtistName": sm.ArtistName,
-		prefix + "AlbumUid":   sm.AlbumUid,
-		prefix + "SongName":   sm.SongName}
+		prefix + "ArtistUid":   sm.ArtistUid,
+		prefix + "ArtistName":  sm.ArtistName,
+		prefix + "AlbumUid":    sm.AlbumUid,
+		prefix + "SongName":    sm.SongName,
+		prefix + "TrackNumber": strconv.Itoa(sm.TrackNumber),
+		prefix + "DiscNumber":  strconv.Itoa(sm.DiscNumber),
+		prefix + "Year":        strconv.Itoa(sm.Year),
+		prefix + "Genre":       sm.Genre,
+		prefix + "DurationMs":  strconv.FormatInt(sm.DurationMs, 10),
+		prefix + "Bitrate":     strconv.Itoa(sm.Bitrate),
+		prefix + "SampleRate":  strconv.Itoa(sm.SampleRate),
+		prefix + "Channels":    strconv.Itoa(sm.Channels)}
 
 	for key, value := range fmDict {
 		smDict[prefix+key] = value
@@ -1,5 +1,7 @@
 package jukebox
 
+import "io"
+
 type StorageSystem interface {
 	Enter() bool
 	Exit()
@@ -25,10 +27,26 @@ type StorageSystem interface {
 		fileContents []byte,
 		headers *PropertySet) bool
 
+	PutObjectFromReader(containerName string,
+		objectName string,
+		reader io.Reader,
+		contentLength int64,
+		headers *PropertySet) bool
+
 	DeleteObject(containerName string,
 		objectName string) bool
 
 	GetObject(containerName string,
 		objectName string,
 		localFilePath string) int64
+
+	GetObjectToWriter(containerName string,
+		objectName string,
+		writer io.Writer) int64
+
+	GetObjectRange(containerName string,
+		objectName string,
+		offset int64,
+		length int64,
+		writer io.Writer) int64
 }
@@ -0,0 +1,159 @@
+package jukebox
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// StorageSystemAdapter exposes a StorageSystemV2 through the original
+// boolean StorageSystem interface. errors are printed and reduced to
+// false (or a zero byte count) the way the original implementations did.
+type StorageSystemAdapter struct {
+	storageSystem StorageSystemV2
+	ctx           context.Context
+}
+
+func NewStorageSystemAdapter(storageSystem StorageSystemV2) *StorageSystemAdapter {
+	return NewStorageSystemAdapterWithContext(context.Background(), storageSystem)
+}
+
+func NewStorageSystemAdapterWithContext(ctx context.Context,
+	storageSystem StorageSystemV2) *StorageSystemAdapter {
+	var adapter StorageSystemAdapter
+	adapter.storageSystem = storageSystem
+	adapter.ctx = ctx
+	return &adapter
+}
+
+func (adapter *StorageSystemAdapter) succeeded(err error) bool {
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	return true
+}
+
+func (adapter *StorageSystemAdapter) byteCount(byteCount int64, err error) int64 {
+	if !adapter.succeeded(err) {
+		return 0
+	}
+	return byteCount
+}
+
+func (adapter *StorageSystemAdapter) Enter() bool {
+	return adapter.succeeded(adapter.storageSystem.Enter(adapter.ctx))
+}
+
+func (adapter *StorageSystemAdapter) Exit() {
+	adapter.storageSystem.Exit()
+}
+
+func (adapter *StorageSystemAdapter) HasContainer(containerName string) bool {
+	haveContainer, err := adapter.storageSystem.HasContainer(adapter.ctx, containerName)
+	return adapter.succeeded(err) && haveContainer
+}
+
+func (adapter *StorageSystemAdapter) CreateContainer(containerName string) bool {
+	return adapter.succeeded(adapter.storageSystem.CreateContainer(adapter.ctx, containerName))
+}
+
+func (adapter *StorageSystemAdapter) DeleteContainer(containerName string) bool {
+	return adapter.succeeded(adapter.storageSystem.DeleteContainer(adapter.ctx, containerName))
+}
+
+func (adapter *StorageSystemAdapter) ListContainerContents(containerName string) ([]string, error) {
+	return adapter.storageSystem.ListContainerContents(adapter.ctx, containerName)
+}
+
+func (adapter *StorageSystemAdapter) GetContainerNames() ([]string, error) {
+	return adapter.storageSystem.GetContainerNames(adapter.ctx)
+}
+
+func (adapter *StorageSystemAdapter) RetrieveFile(fm *FileMetadata, localDirectory string) int64 {
+	return adapter.byteCount(adapter.storageSystem.RetrieveFile(adapter.ctx, fm, localDirectory))
+}
+
+func (adapter *StorageSystemAdapter) StoreFile(fm *FileMetadata, fileContents []byte) bool {
+	return adapter.succeeded(adapter.storageSystem.StoreFile(adapter.ctx, fm, fileContents))
+}
+
+func (adapter *StorageSystemAdapter) AddFileFromPath(containerName string,
+	objectName string,
+	filePath string) bool {
+	return adapter.succeeded(adapter.storageSystem.AddFileFromPath(adapter.ctx,
+		containerName,
+		objectName,
+		filePath))
+}
+
+func (adapter *StorageSystemAdapter) GetObjectMetadata(containerName string,
+	objectName string,
+	dictProps *PropertySet) bool {
+	return adapter.succeeded(adapter.storageSystem.GetObjectMetadata(adapter.ctx,
+		containerName,
+		objectName,
+		dictProps))
+}
+
+func (adapter *StorageSystemAdapter) PutObject(containerName string,
+	objectName string,
+	fileContents []byte,
+	headers *PropertySet) bool {
+	return adapter.succeeded(adapter.storageSystem.PutObject(adapter.ctx,
+		containerName,
+		objectName,
+		fileContents,
+		headers))
+}
+
+func (adapter *StorageSystemAdapter) PutObjectFromReader(containerName string,
+	objectName string,
+	reader io.Reader,
+	contentLength int64,
+	headers *PropertySet) bool {
+	return adapter.succeeded(adapter.storageSystem.PutObjectFromReader(adapter.ctx,
+		containerName,
+		objectName,
+		reader,
+		contentLength,
+		headers))
+}
+
+func (adapter *StorageSystemAdapter) DeleteObject(containerName string,
+	objectName string) bool {
+	return adapter.succeeded(adapter.storageSystem.DeleteObject(adapter.ctx,
+		containerName,
+		objectName))
+}
+
+func (adapter *StorageSystemAdapter) GetObject(containerName string,
+	objectName string,
+	localFilePath string) int64 {
+	return adapter.byteCount(adapter.storageSystem.GetObject(adapter.ctx,
+		containerName,
+		objectName,
+		localFilePath))
+}
+
+func (adapter *StorageSystemAdapter) GetObjectToWriter(containerName string,
+	objectName string,
+	writer io.Writer) int64 {
+	return adapter.byteCount(adapter.storageSystem.GetObjectToWriter(adapter.ctx,
+		containerName,
+		objectName,
+		writer))
+}
+
+func (adapter *StorageSystemAdapter) GetObjectRange(containerName string,
+	objectName string,
+	offset int64,
+	length int64,
+	writer io.Writer) int64 {
+	return adapter.byteCount(adapter.storageSystem.GetObjectRange(adapter.ctx,
+		containerName,
+		objectName,
+		offset,
+		length,
+		writer))
+}
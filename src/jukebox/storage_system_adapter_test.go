@@ -0,0 +1,28 @@
+package jukebox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStorageSystemAdapter(t *testing.T) {
+	th := NewTestHelper(t)
+	adapter := NewStorageSystemAdapter(NewFSStorageSystem(t.TempDir(), false))
+
+	th.Require(adapter.Enter(), "Enter must succeed")
+	th.RequireFalse(adapter.HasContainer("songs"), "container must not exist before creation")
+	th.RequireFalse(adapter.PutObject("songs", "a.mp3", []byte("abc"), nil),
+		"PutObject into missing container must fail")
+	th.Require(adapter.CreateContainer("songs"), "CreateContainer must succeed")
+	th.Require(adapter.HasContainer("songs"), "container must exist after creation")
+	th.Require(adapter.PutObject("songs", "a.mp3", []byte("abc"), nil), "PutObject must succeed")
+
+	var buffer bytes.Buffer
+	th.Require(adapter.GetObjectToWriter("songs", "a.mp3", &buffer) == 3,
+		"GetObjectToWriter must return number of bytes written")
+	th.Require(adapter.GetObjectToWriter("songs", "missing.mp3", &buffer) == 0,
+		"GetObjectToWriter of missing object must return 0")
+
+	th.Require(adapter.DeleteObject("songs", "a.mp3"), "DeleteObject must succeed")
+	th.RequireFalse(adapter.DeleteObject("songs", "a.mp3"), "DeleteObject of missing object must fail")
+}
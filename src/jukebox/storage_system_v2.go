@@ -0,0 +1,98 @@
+package jukebox
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// errors returned (possibly wrapped) by StorageSystemV2 implementations.
+// use errors.Is to test for them.
+var (
+	ErrNotFound         = errors.New("object not found")
+	ErrContainerMissing = errors.New("container does not exist")
+	ErrAuth             = errors.New("not authorized")
+	ErrTransient        = errors.New("transient storage failure")
+	ErrInvalidArgument  = errors.New("invalid argument")
+)
+
+// StorageSystemV2 is the context-aware version of StorageSystem. every
+// method can be cancelled through its context and reports failures as
+// errors instead of a bare bool or byte count. NewStorageSystemAdapter
+// turns any StorageSystemV2 into the original StorageSystem.
+type StorageSystemV2 interface {
+	Enter(ctx context.Context) error
+	Exit()
+
+	HasContainer(ctx context.Context, containerName string) (bool, error)
+	CreateContainer(ctx context.Context, containerName string) error
+	DeleteContainer(ctx context.Context, containerName string) error
+	ListContainerContents(ctx context.Context, containerName string) ([]string, error)
+	GetContainerNames(ctx context.Context) ([]string, error)
+
+	RetrieveFile(ctx context.Context, fm *FileMetadata, localDirectory string) (int64, error)
+	StoreFile(ctx context.Context, fm *FileMetadata, fileContents []byte) error
+	AddFileFromPath(ctx context.Context,
+		containerName string,
+		objectName string,
+		filePath string) error
+
+	GetObjectMetadata(ctx context.Context,
+		containerName string,
+		objectName string,
+		dictProps *PropertySet) error
+
+	PutObject(ctx context.Context,
+		containerName string,
+		objectName string,
+		fileContents []byte,
+		headers *PropertySet) error
+
+	PutObjectFromReader(ctx context.Context,
+		containerName string,
+		objectName string,
+		reader io.Reader,
+		contentLength int64,
+		headers *PropertySet) error
+
+	DeleteObject(ctx context.Context,
+		containerName string,
+		objectName string) error
+
+	GetObject(ctx context.Context,
+		containerName string,
+		objectName string,
+		localFilePath string) (int64, error)
+
+	GetObjectToWriter(ctx context.Context,
+		containerName string,
+		objectName string,
+		writer io.Writer) (int64, error)
+
+	// GetObjectRange writes length bytes of the object starting at offset.
+	// a length of zero (or less) means everything up to the end of the object.
+	GetObjectRange(ctx context.Context,
+		containerName string,
+		objectName string,
+		offset int64,
+		length int64,
+		writer io.Writer) (int64, error)
+}
+
+// contextReader makes long running copies stop promptly once the
+// context is cancelled.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func newContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.reader.Read(p)
+}
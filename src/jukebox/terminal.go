@@ -0,0 +1,86 @@
+//go:build linux || darwin || freebsd
+
+package jukebox
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// terminalState is the terminal settings to put back once keys are no
+// longer being read one at a time.
+type terminalState struct {
+	termios syscall.Termios
+}
+
+func getTermios(fd uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios,
+		uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func setTermios(fd uintptr, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios,
+		uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// isTerminal reports whether fd is a terminal.
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	return getTermios(fd, &termios) == nil
+}
+
+// isForegroundTerminal reports whether fd is a terminal that this process
+// is in the foreground of. a jukebox run in the background gets stopped
+// if it touches the terminal.
+func isForegroundTerminal(fd uintptr) bool {
+	var foregroundGroup int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGPGRP,
+		uintptr(unsafe.Pointer(&foregroundGroup)))
+	return errno == 0 && int(foregroundGroup) == syscall.Getpgrp()
+}
+
+// terminalSize returns the number of columns and rows of the terminal.
+func terminalSize(fd uintptr) (int, int, error) {
+	// struct winsize
+	var size struct {
+		rows, columns, xPixels, yPixels uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCGWINSZ,
+		uintptr(unsafe.Pointer(&size)))
+	if errno != 0 {
+		return 0, 0, errno
+	}
+	return int(size.columns), int(size.rows), nil
+}
+
+// makeKeyReader puts the terminal in a mode where each key can be read as
+// soon as it's pressed, without being echoed. reads return after waiting
+// readTimeoutTenths tenths of a second for a key. output and Ctrl-C work
+// as before. returns the settings for restoreTerminal.
+func makeKeyReader(fd uintptr, readTimeoutTenths uint8) (*terminalState, error) {
+	var state terminalState
+	if err := getTermios(fd, &state.termios); err != nil {
+		return nil, err
+	}
+	termios := state.termios
+	termios.Lflag &^= syscall.ICANON | syscall.ECHO
+	termios.Cc[syscall.VMIN] = 0
+	termios.Cc[syscall.VTIME] = readTimeoutTenths
+	if err := setTermios(fd, &termios); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// restoreTerminal puts back the settings from before makeKeyReader.
+func restoreTerminal(fd uintptr, state *terminalState) error {
+	return setTermios(fd, &state.termios)
+}
@@ -0,0 +1,10 @@
+//go:build darwin || freebsd
+
+package jukebox
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,33 @@
+//go:build !linux && !darwin && !freebsd
+
+package jukebox
+
+import "errors"
+
+// keys can't be read one at a time on this OS, so keyboard control and
+// the tui are turned off.
+var errNoKeyReader = errors.New("reading single keys isn't supported on this OS")
+
+type terminalState struct{}
+
+// isTerminal reports whether fd is a terminal. it's always false here,
+// which keeps keyboard control turned off.
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+func isForegroundTerminal(fd uintptr) bool {
+	return false
+}
+
+func terminalSize(fd uintptr) (int, int, error) {
+	return 0, 0, errNoKeyReader
+}
+
+func makeKeyReader(fd uintptr, readTimeoutTenths uint8) (*terminalState, error) {
+	return nil, errNoKeyReader
+}
+
+func restoreTerminal(fd uintptr, state *terminalState) error {
+	return nil
+}
@@ -0,0 +1,705 @@
+package jukebox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"unicode/utf8"
+)
+
+const (
+	tuiPaneArtists = iota
+	tuiPaneAlbums
+	tuiPaneSongs
+	tuiPanePlaylists
+	tuiPaneQueue
+	tuiPaneCount
+)
+
+const (
+	tuiKeyUp        = "up"
+	tuiKeyDown      = "down"
+	tuiKeyLeft      = "left"
+	tuiKeyRight     = "right"
+	tuiKeyPageUp    = "page-up"
+	tuiKeyPageDown  = "page-down"
+	tuiKeyTab       = "tab"
+	tuiKeyBackTab   = "back-tab"
+	tuiKeyEnter     = "enter"
+	tuiKeyEscape    = "escape"
+	tuiKeyBackspace = "backspace"
+
+	// the screen is redrawn at least this often (in tenths of a second)
+	tuiRedrawTenths = 5
+
+	tuiMinWidth       = 40
+	tuiMinHeight      = 10
+	tuiProgressWidth  = 20
+	tuiAllItemsValue  = ""
+	tuiAllItemsLabel  = "(all)"
+	tuiPageSize       = 10
+	tuiEscapeSequence = "\033["
+
+	ansiHome       = "\033[H"
+	ansiClearToEnd = "\033[K"
+	ansiClearBelow = "\033[J"
+	ansiReset      = "\033[0m"
+	ansiBold       = "\033[1m"
+	ansiUnderline  = "\033[4m"
+	ansiReverse    = "\033[7m"
+	ansiAltScreen  = "\033[?1049h"
+	ansiMainScreen = "\033[?1049l"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+)
+
+// Tui is a full-screen terminal UI for browsing the library (artists,
+// their albums and songs, and the playlists) and the queue. songs are
+// played by the jukebox's own play loop, which is started when there's
+// something to play.
+type Tui struct {
+	jukebox       *Jukebox
+	input         *os.File
+	output        io.Writer
+	width         int
+	height        int
+	panes         []*TuiPane
+	focus         int
+	searching     bool
+	searchText    string
+	confirmDelete string
+	quitRequested bool
+	startPlay     func()
+	mutex         sync.Mutex
+	message       string
+	playing       bool
+	playWaitGroup sync.WaitGroup
+}
+
+func NewTui(jukebox *Jukebox, input *os.File, output io.Writer) *Tui {
+	var tui Tui
+	tui.jukebox = jukebox
+	tui.input = input
+	tui.output = output
+	tui.width = 80
+	tui.height = 24
+	tui.panes = make([]*TuiPane, tuiPaneCount)
+	tui.panes[tuiPaneArtists] = NewTuiPane("Artists")
+	tui.panes[tuiPaneAlbums] = NewTuiPane("Albums")
+	tui.panes[tuiPaneSongs] = NewTuiPane("Songs")
+	tui.panes[tuiPanePlaylists] = NewTuiPane("Playlists")
+	tui.panes[tuiPaneQueue] = NewTuiPane("Queue")
+	tui.focus = tuiPaneArtists
+	tui.startPlay = tui.play
+	return &tui
+}
+
+// Run shows the UI until it's quit. returns false if the input isn't a
+// terminal.
+func (tui *Tui) Run() bool {
+	fd := tui.input.Fd()
+	if !isTerminal(fd) || !isForegroundTerminal(fd) {
+		fmt.Println("error: tui must be run in a terminal")
+		return false
+	}
+	state, err := makeKeyReader(fd, tuiRedrawTenths)
+	if err != nil {
+		fmt.Println("error: unable to read keys from terminal")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	defer restoreTerminal(fd, state)
+
+	// everything the jukebox prints goes to the message line
+	messageReader, messageWriter, err := os.Pipe()
+	if err != nil {
+		fmt.Println("error: unable to capture output")
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	stdout := os.Stdout
+	os.Stdout = messageWriter
+	var messageWaitGroup sync.WaitGroup
+	messageWaitGroup.Add(1)
+	go tui.captureMessages(messageReader, &messageWaitGroup)
+	defer func() {
+		os.Stdout = stdout
+		messageWriter.Close()
+		messageWaitGroup.Wait()
+		messageReader.Close()
+	}()
+
+	fmt.Fprint(tui.output, ansiAltScreen+ansiHideCursor)
+	defer fmt.Fprint(tui.output, ansiReset+ansiShowCursor+ansiMainScreen)
+
+	// play is started and stopped here rather than from the terminal
+	tui.jukebox.keysFromTerminal = false
+	tui.jukebox.queue.SetRepeatMode(tui.jukebox.jukeboxOptions.RepeatMode)
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signalChannel)
+
+	tui.refreshLibrary()
+	for !tui.quitRequested {
+		if width, height, err := terminalSize(fd); err == nil {
+			tui.width = width
+			tui.height = height
+		}
+		tui.refreshQueue()
+		fmt.Fprint(tui.output, tui.render())
+
+		for _, key := range tui.readKeys() {
+			tui.handleKey(key)
+		}
+
+		select {
+		case <-signalChannel:
+			tui.quitRequested = true
+		default:
+		}
+		if tui.jukebox.isExitRequested() {
+			// a limit on how long to play was reached
+			tui.quitRequested = true
+		}
+	}
+
+	if tui.isPlaying() {
+		tui.jukebox.StopPlay()
+	}
+	tui.playWaitGroup.Wait()
+	return true
+}
+
+func (tui *Tui) captureMessages(reader io.Reader, waitGroup *sync.WaitGroup) {
+	defer waitGroup.Done()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		// progress output rewrites the line with carriage returns
+		lines := strings.Split(scanner.Text(), "\r")
+		message := strings.TrimSpace(lines[len(lines)-1])
+		if len(message) > 0 {
+			tui.mutex.Lock()
+			tui.message = message
+			tui.mutex.Unlock()
+		}
+	}
+}
+
+func (tui *Tui) currentMessage() string {
+	tui.mutex.Lock()
+	defer tui.mutex.Unlock()
+	return tui.message
+}
+
+func (tui *Tui) isPlaying() bool {
+	tui.mutex.Lock()
+	defer tui.mutex.Unlock()
+	return tui.playing
+}
+
+// play starts the play loop, unless it's already running.
+func (tui *Tui) play() {
+	tui.mutex.Lock()
+	defer tui.mutex.Unlock()
+	if tui.playing {
+		return
+	}
+	tui.playing = true
+	tui.playWaitGroup.Add(1)
+	go func() {
+		defer tui.playWaitGroup.Done()
+		played := tui.jukebox.playQueue(NewPlaySource(SourceSongs))
+		tui.mutex.Lock()
+		tui.playing = false
+		if !played {
+			tui.message = "error: unable to start play"
+		}
+		tui.mutex.Unlock()
+	}()
+}
+
+// readKeys waits a short while for keys to be pressed and returns them.
+func (tui *Tui) readKeys() []string {
+	input := make([]byte, 64)
+	bytesRead, err := tui.input.Read(input)
+	if err != nil || bytesRead == 0 {
+		return []string{}
+	}
+	return parseKeys(input[:bytesRead])
+}
+
+// parseKeys turns what the terminal sends for key presses into key names.
+// keys that print a character are named by the character.
+func parseKeys(input []byte) []string {
+	keys := []string{}
+	text := string(input)
+	for len(text) > 0 {
+		key := ""
+		length := 1
+		if strings.HasPrefix(text, tuiEscapeSequence) && len(text) >= 3 {
+			length = 3
+			switch text[2] {
+			case 'A':
+				key = tuiKeyUp
+			case 'B':
+				key = tuiKeyDown
+			case 'C':
+				key = tuiKeyRight
+			case 'D':
+				key = tuiKeyLeft
+			case 'Z':
+				key = tuiKeyBackTab
+			case '5', '6':
+				if len(text) >= 4 && text[3] == '~' {
+					length = 4
+					if text[2] == '5' {
+						key = tuiKeyPageUp
+					} else {
+						key = tuiKeyPageDown
+					}
+				}
+			}
+		} else {
+			switch text[0] {
+			case '\033':
+				key = tuiKeyEscape
+			case '\t':
+				key = tuiKeyTab
+			case '\r', '\n':
+				key = tuiKeyEnter
+			case 127, '\b':
+				key = tuiKeyBackspace
+			default:
+				r, size := utf8.DecodeRuneInString(text)
+				length = size
+				if r != utf8.RuneError && r >= ' ' {
+					key = string(r)
+				}
+			}
+		}
+		if len(key) > 0 {
+			keys = append(keys, key)
+		}
+		text = text[length:]
+	}
+	return keys
+}
+
+func (tui *Tui) handleKey(key string) {
+	if len(tui.confirmDelete) > 0 {
+		songUid := tui.confirmDelete
+		tui.confirmDelete = ""
+		if key == "y" {
+			if tui.jukebox.deleteLibrarySong(songUid) {
+				fmt.Printf("deleted %s\n", songUid)
+			} else {
+				fmt.Printf("error: unable to delete %s\n", songUid)
+			}
+			tui.refreshLibrary()
+		} else {
+			fmt.Printf("kept %s\n", songUid)
+		}
+		return
+	}
+
+	pane := tui.panes[tui.focus]
+	if tui.searching {
+		switch key {
+		case tuiKeyEnter:
+			tui.searching = false
+			pane.SetFilter(tui.searchText)
+			tui.selectionChanged()
+		case tuiKeyEscape:
+			tui.searching = false
+		case tuiKeyBackspace:
+			if len(tui.searchText) > 0 {
+				_, size := utf8.DecodeLastRuneInString(tui.searchText)
+				tui.searchText = tui.searchText[:len(tui.searchText)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				tui.searchText += key
+			}
+		}
+		return
+	}
+
+	switch key {
+	case tuiKeyTab, tuiKeyRight:
+		tui.focus = (tui.focus + 1) % tuiPaneCount
+	case tuiKeyBackTab, tuiKeyLeft:
+		tui.focus = (tui.focus + tuiPaneCount - 1) % tuiPaneCount
+	case tuiKeyUp, "k":
+		pane.MoveSelection(-1)
+		tui.selectionChanged()
+	case tuiKeyDown, "j":
+		pane.MoveSelection(1)
+		tui.selectionChanged()
+	case tuiKeyPageUp:
+		pane.MoveSelection(-tuiPageSize)
+		tui.selectionChanged()
+	case tuiKeyPageDown:
+		pane.MoveSelection(tuiPageSize)
+		tui.selectionChanged()
+	case tuiKeyEnter:
+		tui.openSelection()
+	case tuiKeyEscape:
+		pane.SetFilter("")
+		tui.selectionChanged()
+	case "/":
+		tui.searching = true
+		tui.searchText = pane.Filter()
+	case "a":
+		tui.enqueueSelection()
+	case "d":
+		tui.deleteSelection()
+	case " ":
+		if tui.isPlaying() {
+			tui.jukebox.TogglePausePlay()
+		}
+	case "n":
+		if tui.isPlaying() {
+			tui.jukebox.AdvanceToNextSong()
+		}
+	case "p":
+		if tui.isPlaying() && !tui.jukebox.PreviousSong() {
+			fmt.Println("no previous song")
+		}
+	case "+", "=":
+		tui.jukebox.ChangeVolume(keyVolumeStep)
+	case "-":
+		tui.jukebox.ChangeVolume(-keyVolumeStep)
+	case "q":
+		tui.quitRequested = true
+	}
+}
+
+// selectionChanged shows the albums of the selected artist and the songs
+// of the selected album.
+func (tui *Tui) selectionChanged() {
+	switch tui.focus {
+	case tuiPaneArtists:
+		tui.refreshAlbums()
+		tui.refreshSongs()
+	case tuiPaneAlbums:
+		tui.refreshSongs()
+	}
+}
+
+func (tui *Tui) selectedValue(paneIndex int) string {
+	if item := tui.panes[paneIndex].Selected(); item != nil {
+		return item.Value
+	}
+	return tuiAllItemsValue
+}
+
+// selectedArtistAlbum returns the artist and album that the songs pane
+// shows the songs of (either can be empty for all of them).
+func (tui *Tui) selectedArtistAlbum() (string, string) {
+	artist := tui.selectedValue(tuiPaneArtists)
+	albumValue := tui.selectedValue(tuiPaneAlbums)
+	if albumValue == tuiAllItemsValue {
+		return artist, ""
+	}
+	components := strings.SplitN(albumValue, DoubleDashes, 2)
+	return components[0], components[1]
+}
+
+func (tui *Tui) refreshLibrary() {
+	artistItems := []TuiItem{{Label: tuiAllItemsLabel, Value: tuiAllItemsValue}}
+	for _, artist := range tui.jukebox.LibraryArtists() {
+		artistItems = append(artistItems, TuiItem{
+			Label: fmt.Sprintf("%s (%d)", artist.Artist, artist.Songs),
+			Value: artist.Artist})
+	}
+	tui.panes[tuiPaneArtists].SetItems(artistItems)
+
+	playlistItems := []TuiItem{}
+	for _, playlistName := range tui.jukebox.PlaylistNames() {
+		playlistItems = append(playlistItems, TuiItem{Label: playlistName, Value: playlistName})
+	}
+	tui.panes[tuiPanePlaylists].SetItems(playlistItems)
+
+	tui.refreshAlbums()
+	tui.refreshSongs()
+}
+
+func (tui *Tui) refreshAlbums() {
+	artist := tui.selectedValue(tuiPaneArtists)
+	albumItems := []TuiItem{{Label: tuiAllItemsLabel, Value: tuiAllItemsValue}}
+	for _, album := range tui.jukebox.LibraryAlbums(artist) {
+		label := album.Album
+		if len(artist) == 0 {
+			label = fmt.Sprintf("%s - %s", album.Album, album.Artist)
+		}
+		albumItems = append(albumItems, TuiItem{
+			Label: label,
+			Value: album.Artist + DoubleDashes + album.Album})
+	}
+	tui.panes[tuiPaneAlbums].SetItems(albumItems)
+}
+
+func (tui *Tui) refreshSongs() {
+	artist, album := tui.selectedArtistAlbum()
+	songItems := []TuiItem{}
+	for _, song := range tui.jukebox.LibrarySongs(artist, album) {
+		songItems = append(songItems, TuiItem{Label: songLabel(song), Value: song.Uid})
+	}
+	tui.panes[tuiPaneSongs].SetItems(songItems)
+}
+
+func songLabel(song *SongInfo) string {
+	if len(song.Song) == 0 {
+		return song.Uid
+	}
+	if len(song.Artist) == 0 {
+		return song.Song
+	}
+	return fmt.Sprintf("%s - %s", song.Artist, song.Song)
+}
+
+// refreshQueue shows the songs in the queue, with the current song marked.
+func (tui *Tui) refreshQueue() {
+	current, _ := tui.jukebox.queue.Position()
+	queueItems := []TuiItem{}
+	for i, song := range tui.jukebox.queue.Songs() {
+		marker := "  "
+		if i == current {
+			marker = "> "
+		}
+		queueItems = append(queueItems, TuiItem{
+			Label: marker + songLabel(NewSongInfo(song)),
+			Value: song.Fm.FileUid})
+	}
+	queuePane := tui.panes[tuiPaneQueue]
+	selected := queuePane.SelectedIndex()
+	queuePane.SetItems(queueItems)
+	if len(queuePane.Filter()) == 0 {
+		// songs can be in the queue more than once
+		queuePane.Select(selected)
+	}
+}
+
+// selectedSongs returns the songs that the selection in the focused pane
+// stands for, and which of them was selected (for the songs and queue
+// panes).
+func (tui *Tui) selectedSongs() ([]*SongMetadata, int) {
+	pane := tui.panes[tui.focus]
+	selected := pane.Selected()
+	if selected == nil {
+		return nil, 0
+	}
+
+	switch tui.focus {
+	case tuiPaneArtists, tuiPaneAlbums:
+		if tui.focus == tuiPaneArtists {
+			return tui.librarySongs(selected.Value, ""), 0
+		}
+		artist, album := tui.selectedArtistAlbum()
+		return tui.librarySongs(artist, album), 0
+	case tuiPanePlaylists:
+		return tui.jukebox.playlistSongs(selected.Value), 0
+	case tuiPaneQueue:
+		return tui.jukebox.queue.Songs(), pane.SelectedIndex()
+	default:
+		songs := []*SongMetadata{}
+		for _, item := range pane.Items() {
+			if song := tui.jukebox.songForQueue(item.Value); song != nil {
+				songs = append(songs, song)
+			}
+		}
+		return songs, pane.SelectedIndex()
+	}
+}
+
+func (tui *Tui) librarySongs(artist string, album string) []*SongMetadata {
+	jukeboxDb := tui.jukebox.metadataDb()
+	if jukeboxDb == nil {
+		return []*SongMetadata{}
+	}
+	songs := jukeboxDb.retrieveSongs(artist, album)
+	sort.Slice(songs, func(i, j int) bool {
+		return songs[i].Fm.FileUid < songs[j].Fm.FileUid
+	})
+	return songs
+}
+
+// openSelection moves on from an artist to its albums and from an album
+// to its songs. songs and playlists are played, replacing the queue, and
+// a song in the queue is skipped to.
+func (tui *Tui) openSelection() {
+	switch tui.focus {
+	case tuiPaneArtists, tuiPaneAlbums:
+		tui.focus += 1
+		return
+	}
+
+	songs, index := tui.selectedSongs()
+	if len(songs) == 0 {
+		return
+	}
+	tui.jukebox.queue.Restore(songs, index)
+	if tui.isPlaying() {
+		tui.jukebox.changeSong(true)
+	} else {
+		tui.startPlay()
+	}
+}
+
+// enqueueSelection adds the selected song, or the songs of the selected
+// artist, album or playlist, to the queue.
+func (tui *Tui) enqueueSelection() {
+	if tui.focus == tuiPaneQueue {
+		return
+	}
+	var songs []*SongMetadata
+	if tui.focus == tuiPaneSongs {
+		if selected := tui.panes[tuiPaneSongs].Selected(); selected != nil {
+			if song := tui.jukebox.songForQueue(selected.Value); song != nil {
+				songs = append(songs, song)
+			}
+		}
+	} else {
+		songs, _ = tui.selectedSongs()
+	}
+	if len(songs) == 0 {
+		return
+	}
+	tui.jukebox.enqueueSongs(songs)
+	if len(songs) == 1 {
+		fmt.Printf("enqueued %s\n", songs[0].Fm.FileUid)
+	} else {
+		fmt.Printf("enqueued %d songs\n", len(songs))
+	}
+	tui.startPlay()
+}
+
+// deleteSelection removes the selected song from the queue, or asks to
+// delete the selected song from the library.
+func (tui *Tui) deleteSelection() {
+	selected := tui.panes[tui.focus].Selected()
+	if selected == nil {
+		return
+	}
+	switch tui.focus {
+	case tuiPaneQueue:
+		current, _ := tui.jukebox.queue.Position()
+		position := tui.panes[tuiPaneQueue].SelectedIndex() - (current + 1)
+		if current < 0 || position < 0 || !tui.jukebox.RemoveFromQueue(position) {
+			fmt.Println("only upcoming songs can be removed from the queue")
+		}
+	case tuiPaneSongs:
+		tui.confirmDelete = selected.Value
+	default:
+		fmt.Println("only songs can be deleted")
+	}
+}
+
+// render returns what to write to the terminal to draw the whole screen.
+func (tui *Tui) render() string {
+	var screen strings.Builder
+	screen.WriteString(ansiHome)
+	for i, line := range tui.screenLines() {
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		screen.WriteString(line)
+		screen.WriteString(ansiClearToEnd)
+	}
+	screen.WriteString(ansiClearBelow)
+	return screen.String()
+}
+
+func (tui *Tui) screenLines() []string {
+	if tui.width < tuiMinWidth || tui.height < tuiMinHeight {
+		return []string{"terminal is too small"}
+	}
+
+	// the library is on top, the playlists and queue below it and the
+	// now playing, message and help lines at the bottom
+	paneRows := tui.height - 3
+	topRows := paneRows / 2
+	bottomRows := paneRows - topRows
+	columnWidth := (tui.width - 2) / 3
+	lastColumnWidth := tui.width - 2 - 2*columnWidth
+	separator := "|"
+
+	lines := []string{}
+	artistLines := tui.paneLines(tuiPaneArtists, columnWidth, topRows)
+	albumLines := tui.paneLines(tuiPaneAlbums, columnWidth, topRows)
+	songLines := tui.paneLines(tuiPaneSongs, lastColumnWidth, topRows)
+	for row := 0; row < topRows; row++ {
+		lines = append(lines, artistLines[row]+separator+albumLines[row]+separator+songLines[row])
+	}
+	playlistLines := tui.paneLines(tuiPanePlaylists, columnWidth, bottomRows)
+	queueLines := tui.paneLines(tuiPaneQueue, tui.width-1-columnWidth, bottomRows)
+	for row := 0; row < bottomRows; row++ {
+		lines = append(lines, playlistLines[row]+separator+queueLines[row])
+	}
+
+	lines = append(lines, ansiReverse+fitWidth(tui.nowPlaying(), tui.width)+ansiReset)
+	lines = append(lines, fitWidth(" "+tui.currentMessage(), tui.width))
+	lines = append(lines, fitWidth(tui.helpLine(), tui.width))
+	return lines
+}
+
+func (tui *Tui) paneLines(paneIndex int, width int, height int) []string {
+	return tui.panes[paneIndex].Lines(width, height, paneIndex == tui.focus)
+}
+
+// nowPlaying returns the now playing bar: the song, how far into it play
+// is and the volume.
+func (tui *Tui) nowPlaying() string {
+	status := tui.jukebox.Status()
+	if status.Song == nil || !tui.isPlaying() {
+		return " stopped"
+	}
+	state := "playing"
+	if status.Paused {
+		state = "paused"
+	}
+	line := fmt.Sprintf(" %s %d/%d: %s  %s", state, status.Index+1, status.NumberSongs,
+		songLabel(status.Song), formatSongPosition(status.PositionSeconds))
+	if status.DurationSeconds > 0 {
+		line += fmt.Sprintf(" %s %s", progressBar(status.PositionSeconds, status.DurationSeconds, tuiProgressWidth),
+			formatSongPosition(status.DurationSeconds))
+	}
+	line += fmt.Sprintf("  vol %d%%", tui.jukebox.Volume())
+	if status.SleepSeconds > 0 {
+		line += fmt.Sprintf("  sleep %s", formatSongPosition(status.SleepSeconds))
+	}
+	return line
+}
+
+// progressBar shows how much of total has been done as a bar that's width
+// characters wide (plus the brackets).
+func progressBar(done int, total int, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	if filled > width {
+		filled = width
+	} else if filled < 0 {
+		filled = 0
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+func (tui *Tui) helpLine() string {
+	if len(tui.confirmDelete) > 0 {
+		return fmt.Sprintf(" delete %s from the library? y to delete, any other key to keep it",
+			tui.confirmDelete)
+	}
+	if tui.searching {
+		return fmt.Sprintf(" search: %s_", tui.searchText)
+	}
+	return " tab pane  enter open/play  a enqueue  d delete  / search  esc clear" +
+		"  space pause  n next  p previous  +/- volume  q quit"
+}
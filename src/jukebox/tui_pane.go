@@ -0,0 +1,173 @@
+package jukebox
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TuiItem is one line in a pane of the terminal UI. the value is what the
+// line stands for (e.g., the uid of a song).
+type TuiItem struct {
+	Label string
+	Value string
+}
+
+// TuiPane is a scrolling list in the terminal UI. only the items that
+// contain the filter (if there is one) are shown.
+type TuiPane struct {
+	title    string
+	items    []TuiItem
+	filter   string
+	shown    []TuiItem
+	selected int
+	top      int
+}
+
+func NewTuiPane(title string) *TuiPane {
+	var pane TuiPane
+	pane.title = title
+	pane.items = []TuiItem{}
+	pane.filter = ""
+	pane.shown = []TuiItem{}
+	pane.selected = 0
+	pane.top = 0
+	return &pane
+}
+
+// SetItems replaces the items. the selection stays on the same value if
+// it's still there.
+func (pane *TuiPane) SetItems(items []TuiItem) {
+	pane.items = items
+	pane.applyFilter()
+}
+
+// SetFilter shows only the items whose label contains filter (ignoring
+// case). an empty filter shows everything.
+func (pane *TuiPane) SetFilter(filter string) {
+	pane.filter = filter
+	pane.applyFilter()
+}
+
+func (pane *TuiPane) Filter() string {
+	return pane.filter
+}
+
+func (pane *TuiPane) applyFilter() {
+	selectedValue := ""
+	if item := pane.Selected(); item != nil {
+		selectedValue = item.Value
+	}
+
+	lowerFilter := strings.ToLower(pane.filter)
+	pane.shown = []TuiItem{}
+	pane.selected = 0
+	foundSelected := false
+	for _, item := range pane.items {
+		if strings.Contains(strings.ToLower(item.Label), lowerFilter) {
+			if !foundSelected && item.Value == selectedValue {
+				pane.selected = len(pane.shown)
+				foundSelected = true
+			}
+			pane.shown = append(pane.shown, item)
+		}
+	}
+}
+
+// Items returns the items being shown.
+func (pane *TuiPane) Items() []TuiItem {
+	return pane.shown
+}
+
+// Selected returns the selected item, or nil if no items are shown.
+func (pane *TuiPane) Selected() *TuiItem {
+	if pane.selected < 0 || pane.selected >= len(pane.shown) {
+		return nil
+	}
+	return &pane.shown[pane.selected]
+}
+
+func (pane *TuiPane) SelectedIndex() int {
+	return pane.selected
+}
+
+// MoveSelection moves the selection by change items, stopping at the
+// first and last items.
+func (pane *TuiPane) MoveSelection(change int) {
+	pane.Select(pane.selected + change)
+}
+
+func (pane *TuiPane) Select(index int) {
+	if index >= len(pane.shown) {
+		index = len(pane.shown) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	pane.selected = index
+}
+
+// Lines returns the pane drawn as height lines that are each width
+// columns wide. the first line is the title. the selection is highlighted
+// in the pane that has the focus.
+func (pane *TuiPane) Lines(width int, height int, focused bool) []string {
+	lines := []string{}
+	if height <= 0 || width <= 0 {
+		return lines
+	}
+
+	title := fmt.Sprintf(" %s (%d)", pane.title, len(pane.shown))
+	if len(pane.filter) > 0 {
+		title += fmt.Sprintf(" /%s", pane.filter)
+	}
+	if focused {
+		lines = append(lines, ansiReverse+fitWidth(title, width)+ansiReset)
+	} else {
+		lines = append(lines, ansiBold+fitWidth(title, width)+ansiReset)
+	}
+
+	// keep the selection in view
+	rows := height - 1
+	if pane.selected < pane.top {
+		pane.top = pane.selected
+	} else if rows > 0 && pane.selected >= pane.top+rows {
+		pane.top = pane.selected - rows + 1
+	}
+	if pane.top > len(pane.shown)-rows {
+		pane.top = len(pane.shown) - rows
+	}
+	if pane.top < 0 {
+		pane.top = 0
+	}
+
+	for row := 0; row < rows; row++ {
+		index := pane.top + row
+		if index >= len(pane.shown) {
+			lines = append(lines, fitWidth("", width))
+			continue
+		}
+		line := fitWidth(" "+pane.shown[index].Label, width)
+		if index == pane.selected {
+			if focused {
+				line = ansiReverse + line + ansiReset
+			} else {
+				line = ansiUnderline + line + ansiReset
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fitWidth pads or cuts text to be width columns wide.
+func fitWidth(text string, width int) string {
+	length := utf8.RuneCountInString(text)
+	if length > width {
+		runes := []rune(text)
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "~"
+	}
+	return text + strings.Repeat(" ", width-length)
+}
@@ -0,0 +1,67 @@
+package jukebox
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestTuiPane() *TuiPane {
+	pane := NewTuiPane("Songs")
+	pane.SetItems([]TuiItem{
+		{Label: "The Who - My Wife", Value: "my-wife"},
+		{Label: "The Who - Baba O'Riley", Value: "baba"},
+		{Label: "The Kinks - Sunny Afternoon", Value: "sunny"},
+	})
+	return pane
+}
+
+func TestTuiPaneSelection(t *testing.T) {
+	th := NewTestHelper(t)
+	pane := newTestTuiPane()
+	th.RequireStringEquals(pane.Selected().Value, "my-wife", "first item must be selected")
+	pane.MoveSelection(-1)
+	th.Require(pane.SelectedIndex() == 0, "selection must stop at first item")
+	pane.MoveSelection(5)
+	th.RequireStringEquals(pane.Selected().Value, "sunny", "selection must stop at last item")
+
+	pane.SetItems([]TuiItem{{Label: "Sunny Afternoon", Value: "sunny"}, {Label: "My Wife", Value: "my-wife"}})
+	th.Require(pane.SelectedIndex() == 0, "selection must stay on same value when items change")
+
+	pane.SetItems([]TuiItem{})
+	th.Require(pane.Selected() == nil, "empty pane must not have selection")
+}
+
+func TestTuiPaneFilter(t *testing.T) {
+	th := NewTestHelper(t)
+	pane := newTestTuiPane()
+	pane.MoveSelection(1)
+	pane.SetFilter("WHO")
+	th.Require(len(pane.Items()) == 2, "filter must ignore case")
+	th.RequireStringEquals(pane.Selected().Value, "baba", "selection must be kept when still shown")
+	pane.SetFilter("kinks")
+	th.RequireStringEquals(pane.Selected().Value, "sunny", "selection must move when filtered out")
+	pane.SetFilter("")
+	th.Require(len(pane.Items()) == 3, "empty filter must show all items")
+}
+
+func TestTuiPaneLines(t *testing.T) {
+	th := NewTestHelper(t)
+	pane := newTestTuiPane()
+	pane.MoveSelection(2)
+
+	lines := pane.Lines(12, 3, true)
+	th.Require(len(lines) == 3, "pane must fill its height")
+	th.Require(strings.Contains(lines[0], " Songs (3)"), "first line must be title")
+	th.Require(strings.Contains(lines[2], ansiReverse+" The Kinks ~"), "selection must be scrolled into view and highlighted")
+
+	lines = pane.Lines(12, 5, false)
+	th.RequireStringEquals(lines[4], strings.Repeat(" ", 12), "rows without items must be blank")
+	th.RequireFalse(strings.Contains(strings.Join(lines, ""), ansiReverse), "unfocused pane must not be highlighted")
+}
+
+func TestFitWidth(t *testing.T) {
+	th := NewTestHelper(t)
+	th.RequireStringEquals(fitWidth("abc", 5), "abc  ", "short text must be padded")
+	th.RequireStringEquals(fitWidth("abcdef", 4), "abc~", "long text must be cut")
+	th.RequireStringEquals(fitWidth("héllo", 5), "héllo", "width must count characters")
+}
@@ -0,0 +1,165 @@
+package jukebox
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func newTestTui(t *testing.T) (*Tui, *int) {
+	options := NewJukeboxOptions()
+	options.AudioPlayer = audioPlayerNull
+	jukebox := newTestJukebox(t, options)
+	importTestSongs(t, jukebox, testApiSongs)
+
+	tui := NewTui(jukebox, os.Stdin, os.Stdout)
+	playsStarted := 0
+	tui.startPlay = func() {
+		playsStarted += 1
+	}
+	tui.refreshLibrary()
+	return tui, &playsStarted
+}
+
+func TestParseKeys(t *testing.T) {
+	th := NewTestHelper(t)
+	keys := parseKeys([]byte("a\033[A\033[B\t\033[Z\r\177\033[6~\033é"))
+	expected := []string{"a", tuiKeyUp, tuiKeyDown, tuiKeyTab, tuiKeyBackTab, tuiKeyEnter,
+		tuiKeyBackspace, tuiKeyPageDown, tuiKeyEscape, "é"}
+	th.RequireStringEquals(strings.Join(keys, ","), strings.Join(expected, ","), "keys must be parsed")
+}
+
+func TestTuiBrowseLibrary(t *testing.T) {
+	th := NewTestHelper(t)
+	tui, _ := newTestTui(t)
+
+	th.Require(len(tui.panes[tuiPaneArtists].Items()) == 3, "artists must include all artists item")
+	th.Require(len(tui.panes[tuiPaneSongs].Items()) == 3, "all songs must be shown to start")
+
+	tui.handleKey(tuiKeyDown)
+	th.RequireStringEquals(tui.selectedValue(tuiPaneArtists), "The Kinks", "artists must be in order")
+	th.Require(len(tui.panes[tuiPaneAlbums].Items()) == 2, "albums of artist must be shown")
+	th.Require(len(tui.panes[tuiPaneSongs].Items()) == 1, "songs of artist must be shown")
+
+	tui.handleKey(tuiKeyDown)
+	tui.handleKey(tuiKeyEnter)
+	th.Require(tui.focus == tuiPaneAlbums, "enter on artist must move to albums")
+	tui.handleKey(tuiKeyDown)
+	artist, album := tui.selectedArtistAlbum()
+	th.RequireStringEquals(artist+"/"+album, "The Who/Whos Next", "album must be selected")
+	th.Require(len(tui.panes[tuiPaneSongs].Items()) == 2, "songs of album must be shown")
+
+	tui.handleKey(tuiKeyBackTab)
+	tui.handleKey(tuiKeyUp)
+	tui.handleKey("/")
+	for _, key := range []string{"k", "i", "n", "x", tuiKeyBackspace, "k", tuiKeyEnter} {
+		tui.handleKey(key)
+	}
+	th.RequireStringEquals(tui.panes[tuiPaneArtists].Filter(), "kink", "search must filter pane")
+	th.Require(len(tui.panes[tuiPaneArtists].Items()) == 1, "search must find artist")
+	tui.handleKey(tuiKeyEscape)
+	th.Require(len(tui.panes[tuiPaneArtists].Items()) == 3, "escape must clear filter")
+}
+
+func TestTuiQueue(t *testing.T) {
+	th := NewTestHelper(t)
+	tui, playsStarted := newTestTui(t)
+	jukebox := tui.jukebox
+
+	// enqueue the album, then one song
+	tui.handleKey(tuiKeyTab)
+	tui.handleKey(tuiKeyDown)
+	tui.handleKey(tuiKeyDown)
+	tui.handleKey("a")
+	th.Require(jukebox.queue.Len() == 2, "album must be enqueued")
+	th.Require(jukebox.currentSong() != nil, "first enqueued song must be current")
+	th.Require(*playsStarted == 1, "enqueueing must start play")
+	tui.handleKey(tuiKeyTab)
+	tui.handleKey("a")
+	th.Require(jukebox.queue.Len() == 3, "song must be enqueued")
+
+	tui.focus = tuiPaneQueue
+	tui.refreshQueue()
+	th.Require(strings.HasPrefix(tui.panes[tuiPaneQueue].Items()[0].Label, "> "), "current song must be marked")
+	tui.handleKey(tuiKeyDown)
+	tui.handleKey("d")
+	th.Require(jukebox.queue.Len() == 2, "upcoming song must be removed from queue")
+	tui.handleKey(tuiKeyUp)
+	tui.handleKey("d")
+	th.Require(jukebox.queue.Len() == 2, "current song must not be removed from queue")
+
+	tui.refreshQueue()
+	tui.handleKey(tuiKeyDown)
+	tui.handleKey(tuiKeyEnter)
+	position, _ := jukebox.queue.Position()
+	th.Require(position == 1, "enter in queue must skip to song")
+
+	// play the album's songs from its last song
+	tui.focus = tuiPaneSongs
+	tui.panes[tuiPaneSongs].Select(1)
+	tui.handleKey(tuiKeyEnter)
+	position, numberSongs := jukebox.queue.Position()
+	th.Require(position == 1 && numberSongs == 2, "enter on song must play songs from it")
+	th.Require(*playsStarted == 4, "play must be started for each change")
+}
+
+func TestTuiDeleteSong(t *testing.T) {
+	th := NewTestHelper(t)
+	tui, _ := newTestTui(t)
+	tui.focus = tuiPaneSongs
+	songUid := tui.panes[tuiPaneSongs].Selected().Value
+
+	tui.handleKey("d")
+	th.Require(strings.Contains(tui.helpLine(), songUid), "delete must be confirmed")
+	tui.handleKey("n")
+	th.Require(len(tui.panes[tuiPaneSongs].Items()) == 3, "song must be kept without y")
+
+	tui.handleKey("d")
+	tui.handleKey("y")
+	th.Require(len(tui.panes[tuiPaneSongs].Items()) == 2, "song must be deleted with y")
+	th.Require(tui.jukebox.jukeboxDb != nil, "library must still be open after delete")
+	th.Require(tui.jukebox.jukeboxDb.retrieveSong(songUid) == nil, "song must be gone from library")
+}
+
+func TestTuiPlayFailure(t *testing.T) {
+	th := NewTestHelper(t)
+	tui, _ := newTestTui(t)
+	jukebox := tui.jukebox
+	songs := jukebox.jukeboxDb.retrieveSongs("", "")
+	for _, song := range songs {
+		jukebox.storageSystem.DeleteObject(jukebox.ctx, song.Fm.ContainerName, song.Fm.ObjectName)
+	}
+	jukebox.queue.Reset(songs)
+
+	// the first song can't be downloaded, which mustn't end the process
+	tui.play()
+	tui.playWaitGroup.Wait()
+	th.RequireFalse(tui.isPlaying(), "play must stop when it can't start")
+	th.RequireStringEquals(tui.currentMessage(), "error: unable to start play", "failure must be shown")
+}
+
+func TestTuiScreen(t *testing.T) {
+	th := NewTestHelper(t)
+	tui, _ := newTestTui(t)
+	tui.width = 90
+	tui.height = 20
+
+	lines := tui.screenLines()
+	th.Require(len(lines) == 20, "screen must fill terminal")
+	th.Require(strings.Contains(lines[0], "Artists (3)") && strings.Contains(lines[0], "Songs (3)"),
+		"library panes must be on top")
+	th.Require(strings.Contains(lines[8], "Playlists (0)") && strings.Contains(lines[8], "Queue (0)"),
+		"playlists and queue must be below")
+	th.Require(strings.Contains(lines[17], "stopped"), "now playing bar must show nothing playing")
+	th.Require(strings.HasPrefix(lines[19], " tab pane"), "help must be last")
+
+	tui.width = 20
+	th.RequireStringEquals(strings.Join(tui.screenLines(), ""), "terminal is too small", "small terminal")
+}
+
+func TestProgressBar(t *testing.T) {
+	th := NewTestHelper(t)
+	th.RequireStringEquals(progressBar(30, 120, 8), "[==------]", "quarter done")
+	th.RequireStringEquals(progressBar(200, 120, 4), "[====]", "past the end")
+	th.RequireStringEquals(progressBar(10, 0, 4), "[----]", "unknown length")
+}
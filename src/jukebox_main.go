@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"jukebox"
 	"os"
@@ -12,6 +13,30 @@ const (
 	argPrefix          = "--"
 	argDebug           = "debug"
 	argFileCacheCount  = "file-cache-count"
+	argDownloadWorkers = "download-workers"
+	argImportWorkers   = "import-workers"
+	argImportBatchSize = "import-batch-size"
+	argAfterImport     = "after-import"
+	argWatchInterval   = "watch-interval"
+	argCacheSizeMb     = "cache-size-mb"
+	argStream          = "stream"
+	argPlayer          = "player"
+	argPlayerCommand   = "player-command"
+	argHttpAddress     = "http-address"
+	argControlSocket   = "control-socket"
+	argLevel           = "level"
+	argPosition        = "position"
+	argTo              = "to"
+	argRepeat          = "repeat"
+	argShuffle         = "shuffle"
+	argSeed            = "seed"
+	argArtistGap       = "artist-gap"
+	argNumberSongs     = "number-songs"
+	argStopAfter       = "stop-after-minutes"
+	argStopAt          = "stop-at"
+	argMinutes         = "minutes"
+	argPreviewSeconds  = "preview-seconds"
+	argPreviewOffset   = "preview-offset"
 	argIntegrityChecks = "integrity-checks"
 	argStorage         = "storage"
 	argArtist          = "artist"
@@ -20,7 +45,11 @@ const (
 	argAlbum           = "album"
 	argCommand         = "command"
 	argFormat          = "format"
+	argAction          = "action"
 
+	cmdCacheClear       = "cache-clear"
+	cmdCacheStatus      = "cache-status"
+	cmdCtl              = "ctl"
 	cmdDeleteAlbum      = "delete-album"
 	cmdDeleteArtist     = "delete-artist"
 	cmdDeletePlaylist   = "delete-playlist"
@@ -42,12 +71,15 @@ const (
 	cmdPlay             = "play"
 	cmdPlayAlbum        = "play-album"
 	cmdPlayPlaylist     = "play-playlist"
+	cmdResume           = "resume"
 	cmdRetrieveCatalog  = "retrieve-catalog"
 	cmdShowAlbum        = "show-album"
 	cmdShowPlaylist     = "show-playlist"
 	cmdShufflePlay      = "shuffle-play"
+	cmdTui              = "tui"
 	cmdUploadMetadataDb = "upload-metadata-db"
 	cmdUsage            = "usage"
+	cmdWatch            = "watch"
 
 	ssFs = "fs"
 	ssS3 = "s3"
@@ -71,7 +103,7 @@ const (
 
 func connectS3StorageSystem(credentials map[string]string,
 	inDebugMode bool,
-	isUpdate bool) jukebox.StorageSystem {
+	isUpdate bool) jukebox.StorageSystemV2 {
 
 	theAwsAccessKey := ""
 	theAwsSecretKey := ""
@@ -144,7 +176,7 @@ func connectStorageSystem(systemName string,
 	credentials map[string]string,
 	containerPrefix string,
 	inDebugMode bool,
-	isUpdate bool) jukebox.StorageSystem {
+	isUpdate bool) jukebox.StorageSystemV2 {
 
 	if systemName == ssS3 {
 		if len(containerPrefix) > 0 {
@@ -164,6 +196,9 @@ func connectStorageSystem(systemName string,
 
 func showUsage() {
 	fmt.Println("Supported Commands:")
+	fmt.Printf("\t%s        - delete all songs in local song cache\n", cmdCacheClear)
+	fmt.Printf("\t%s       - show local song cache usage\n", cmdCacheStatus)
+	fmt.Printf("\t%s                - control a playing jukebox (pause, resume, next, previous, stop, status, volume,\n\t                     queue, enqueue, play-next, remove, move, clear, sleep, cancel-sleep)\n", cmdCtl)
 	fmt.Printf("\t%s      - delete specified artist\n", cmdDeleteArtist)
 	fmt.Printf("\t%s       - delete specified album\n", cmdDeleteAlbum)
 	fmt.Printf("\t%s    - delete specified playlist\n", cmdDeletePlaylist)
@@ -172,6 +207,7 @@ func showUsage() {
 	fmt.Printf("\t%s       - import all new songs from song-import subdirectory\n", cmdImportSongs)
 	fmt.Printf("\t%s   - import all new playlists from playlist-import subdirectory\n", cmdImportPlaylists)
 	fmt.Printf("\t%s   - import all album art from album-art-import subdirectory\n", cmdImportAlbumArt)
+	fmt.Printf("\t%s              - import new songs, playlists and album art as they're added to the import subdirectories\n", cmdWatch)
 	fmt.Printf("\t%s         - show listing of all available songs\n", cmdListSongs)
 	fmt.Printf("\t%s       - show listing of all available artists\n", cmdListArtists)
 	fmt.Printf("\t%s    - show listing of all available storage containers\n", cmdListContainers)
@@ -184,6 +220,8 @@ func showUsage() {
 	fmt.Printf("\t%s       - play songs randomly\n", cmdShufflePlay)
 	fmt.Printf("\t%s      - play specified playlist\n", cmdPlayPlaylist)
 	fmt.Printf("\t%s         - play specified album\n", cmdPlayAlbum)
+	fmt.Printf("\t%s             - continue playing where play last stopped\n", cmdResume)
+	fmt.Printf("\t%s                - browse the library and queue in a full-screen terminal UI\n", cmdTui)
 	fmt.Printf("\t%s   - retrieve copy of music catalog\n", cmdRetrieveCatalog)
 	fmt.Printf("\t%s - upload SQLite metadata\n", cmdUploadMetadataDb)
 	fmt.Printf("\t%s       - initialize storage system\n", cmdInitStorage)
@@ -191,10 +229,94 @@ func showUsage() {
 	fmt.Println("")
 }
 
-func initStorageSystem(storageSys jukebox.StorageSystem, containerPrefix string) bool {
+// runControlCommand sends the action to the jukebox that's playing and
+// shows its status afterward. queue positions are given starting at 1
+// (0 when not given).
+func runControlCommand(options *jukebox.JukeboxOptions,
+	action string,
+	song string,
+	level int,
+	haveLevel bool,
+	position int,
+	to int,
+	minutes int) bool {
+
+	request := &jukebox.ControlRequest{Command: action}
+	switch action {
+	case jukebox.ControlPause, jukebox.ControlResume, jukebox.ControlNext,
+		jukebox.ControlPrevious, jukebox.ControlStop, jukebox.ControlStatus,
+		jukebox.ControlQueue, jukebox.ControlClear, jukebox.ControlWake:
+	case jukebox.ControlEnqueue, jukebox.ControlPlayNext:
+		if len(song) == 0 {
+			fmt.Printf("error: song must be specified using --%s option\n", argSong)
+			return false
+		}
+		request.Song = song
+	case jukebox.ControlVolume:
+		if !haveLevel {
+			fmt.Printf("error: volume level must be specified using --%s option\n", argLevel)
+			return false
+		}
+		request.Level = level
+	case jukebox.ControlSleep:
+		if minutes < 1 {
+			fmt.Printf("error: sleep minutes must be specified using --%s option\n", argMinutes)
+			return false
+		}
+		request.Minutes = minutes
+	case jukebox.ControlRemove:
+		if position < 1 {
+			fmt.Printf("error: queue position must be specified using --%s option\n", argPosition)
+			return false
+		}
+		request.Position = position - 1
+	case jukebox.ControlMove:
+		if position < 1 || to < 1 {
+			fmt.Printf("error: queue positions must be specified using --%s and --%s options\n",
+				argPosition, argTo)
+			return false
+		}
+		request.Position = position - 1
+		request.To = to - 1
+	default:
+		if len(action) == 0 {
+			fmt.Printf("error: %s requires an action\n", cmdCtl)
+		} else {
+			fmt.Printf("error: unknown %s action '%s'\n", cmdCtl, action)
+		}
+		fmt.Println("supported actions: pause, resume, next, previous, stop, status, volume,")
+		fmt.Println("                   queue, enqueue, play-next, remove, move, clear, sleep, cancel-sleep")
+		return false
+	}
+
+	client, err := jukebox.NewControlClient(options.ControlSocket)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	defer client.Close()
+
+	response, err := client.Send(request)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		return false
+	}
+	if response.Status != nil {
+		response.Status.Show()
+	}
+	if response.Queue != nil || action == jukebox.ControlQueue {
+		fmt.Println("----- queue -----")
+		for i, song := range response.Queue {
+			fmt.Printf("%d. %s\n", i+1, song.Uid)
+		}
+	}
+	return true
+}
+
+func initStorageSystem(storageSys jukebox.StorageSystemV2, containerPrefix string) bool {
 	var success bool
 	fmt.Println("starting storage system initialization...")
-	if jukebox.InitializeStorageSystem(storageSys, containerPrefix) {
+	if jukebox.InitializeStorageSystem(context.Background(), storageSys, containerPrefix) {
 		fmt.Println("storage system successfully initialized")
 		success = true
 	} else {
@@ -217,13 +339,38 @@ func main() {
 	optParser := jukebox.NewArgumentParser(debugMode)
 	optParser.AddOptionalBoolFlag(argPrefix+argDebug, "run in debug mode")
 	optParser.AddOptionalIntArgument(argPrefix+argFileCacheCount, "number of songs to buffer in cache")
+	optParser.AddOptionalIntArgument(argPrefix+argDownloadWorkers, "number of songs to download at the same time")
+	optParser.AddOptionalIntArgument(argPrefix+argImportWorkers, "number of songs to upload at the same time when importing")
+	optParser.AddOptionalIntArgument(argPrefix+argImportBatchSize, "number of imported songs to store in each database transaction")
+	optParser.AddOptionalStringArgument(argPrefix+argAfterImport, "what to do with song files once imported (keep, move, delete)")
+	optParser.AddOptionalIntArgument(argPrefix+argWatchInterval, "seconds between looks at the import directories for watch")
+	optParser.AddOptionalIntArgument(argPrefix+argCacheSizeMb, "size of local song cache in MB (0 disables)")
+	optParser.AddOptionalStringArgument(argPrefix+argPlayer, "audio player (mpv, ffplay, mpg123, mplayer, afplay, mpc-hc, command, null)")
+	optParser.AddOptionalStringArgument(argPrefix+argPlayerCommand, "audio player command line for command player (e.g., 'player --start={offset} {file}')")
+	optParser.AddOptionalStringArgument(argPrefix+argHttpAddress, "address for http control API while playing (e.g., 'localhost:5309', '' disables)")
+	optParser.AddOptionalBoolFlag(argPrefix+argStream, "stream songs to audio player while downloading")
 	optParser.AddOptionalBoolFlag(argPrefix+argIntegrityChecks, "check file integrity after download")
 	optParser.AddOptionalStringArgument(argPrefix+argStorage, "storage system type (s3, fs)")
 	optParser.AddOptionalStringArgument(argPrefix+argArtist, "limit operations to specified artist")
 	optParser.AddOptionalStringArgument(argPrefix+argPlaylist, "limit operations to specified playlist")
 	optParser.AddOptionalStringArgument(argPrefix+argSong, "limit operations to specified song")
 	optParser.AddOptionalStringArgument(argPrefix+argAlbum, "limit operations to specified album")
+	optParser.AddOptionalStringArgument(argPrefix+argControlSocket, "unix socket for controlling a playing jukebox ('' disables)")
+	optParser.AddOptionalIntArgument(argPrefix+argLevel, "volume level (0-100) for ctl volume")
+	optParser.AddOptionalIntArgument(argPrefix+argPosition, "queue position (starting at 1) for ctl remove and move")
+	optParser.AddOptionalIntArgument(argPrefix+argTo, "queue position (starting at 1) to move to for ctl move")
+	optParser.AddOptionalStringArgument(argPrefix+argRepeat, "repeat mode (all, one, none)")
+	optParser.AddOptionalStringArgument(argPrefix+argShuffle, "shuffle mode (random, smart, album)")
+	optParser.AddOptionalIntArgument(argPrefix+argSeed, "seed for shuffling, to repeat a shuffle")
+	optParser.AddOptionalIntArgument(argPrefix+argArtistGap, "number of songs between songs by the same artist for smart shuffle")
+	optParser.AddOptionalIntArgument(argPrefix+argNumberSongs, "number of songs to play before stopping")
+	optParser.AddOptionalIntArgument(argPrefix+argStopAfter, "number of minutes to play before stopping")
+	optParser.AddOptionalStringArgument(argPrefix+argStopAt, "time of day (HH:MM) to stop playing at")
+	optParser.AddOptionalIntArgument(argPrefix+argMinutes, "minutes for ctl sleep")
+	optParser.AddOptionalIntArgument(argPrefix+argPreviewSeconds, "play only this many seconds of each song (intro scan)")
+	optParser.AddOptionalIntArgument(argPrefix+argPreviewOffset, "seconds into each song to start previews at")
 	optParser.AddRequiredArgument(argCommand, "command for jukebox")
+	optParser.AddOptionalPositionalArgument(argAction, "action for ctl command")
 
 	consoleArgs := os.Args[1:]
 
@@ -249,6 +396,149 @@ func main() {
 		options.FileCacheCount = value
 	}
 
+	if ps.Contains(argDownloadWorkers) {
+		value := ps.Get(argDownloadWorkers).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting download workers=%d\n", value)
+		}
+		options.DownloadWorkers = value
+	}
+
+	if ps.Contains(argImportWorkers) {
+		value := ps.Get(argImportWorkers).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting import workers=%d\n", value)
+		}
+		options.ImportWorkers = value
+	}
+
+	if ps.Contains(argImportBatchSize) {
+		value := ps.Get(argImportBatchSize).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting import batch size=%d\n", value)
+		}
+		options.ImportBatchSize = value
+	}
+
+	if ps.Contains(argAfterImport) {
+		options.AfterImport = ps.Get(argAfterImport).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting after import action to '%s'\n", options.AfterImport)
+		}
+	}
+
+	if ps.Contains(argWatchInterval) {
+		value := ps.Get(argWatchInterval).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting watch interval=%d\n", value)
+		}
+		options.WatchIntervalSeconds = value
+	}
+
+	if ps.Contains(argCacheSizeMb) {
+		value := ps.Get(argCacheSizeMb).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting song cache size=%d MB\n", value)
+		}
+		options.SongCacheMaxBytes = int64(value) * 1024 * 1024
+	}
+
+	if ps.Contains(argPlayerCommand) {
+		options.AudioPlayerCommand = ps.Get(argPlayerCommand).GetStringValue()
+		options.AudioPlayer = "command"
+	}
+
+	if ps.Contains(argPlayer) {
+		options.AudioPlayer = ps.Get(argPlayer).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting audio player to '%s'\n", options.AudioPlayer)
+		}
+	}
+
+	if ps.Contains(argHttpAddress) {
+		options.HttpAddress = ps.Get(argHttpAddress).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting http address to '%s'\n", options.HttpAddress)
+		}
+	}
+
+	if ps.Contains(argControlSocket) {
+		options.ControlSocket = ps.Get(argControlSocket).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting control socket to '%s'\n", options.ControlSocket)
+		}
+	}
+
+	if ps.Contains(argRepeat) {
+		options.RepeatMode = ps.Get(argRepeat).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting repeat mode to '%s'\n", options.RepeatMode)
+		}
+	}
+
+	if ps.Contains(argShuffle) {
+		options.ShuffleMode = ps.Get(argShuffle).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting shuffle mode to '%s'\n", options.ShuffleMode)
+		}
+	}
+
+	if ps.Contains(argSeed) {
+		options.ShuffleSeed = int64(ps.Get(argSeed).GetIntValue())
+		if debugMode {
+			fmt.Printf("setting shuffle seed=%d\n", options.ShuffleSeed)
+		}
+	}
+
+	if ps.Contains(argArtistGap) {
+		options.ArtistGap = ps.Get(argArtistGap).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting artist gap=%d\n", options.ArtistGap)
+		}
+	}
+
+	if ps.Contains(argNumberSongs) {
+		options.NumberSongs = ps.Get(argNumberSongs).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting number of songs=%d\n", options.NumberSongs)
+		}
+	}
+
+	if ps.Contains(argStopAfter) {
+		options.StopAfterMinutes = ps.Get(argStopAfter).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting stop after minutes=%d\n", options.StopAfterMinutes)
+		}
+	}
+
+	if ps.Contains(argStopAt) {
+		options.StopAt = ps.Get(argStopAt).GetStringValue()
+		if debugMode {
+			fmt.Printf("setting stop time to '%s'\n", options.StopAt)
+		}
+	}
+
+	if ps.Contains(argPreviewSeconds) {
+		options.PreviewSeconds = ps.Get(argPreviewSeconds).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting preview seconds=%d\n", options.PreviewSeconds)
+		}
+	}
+
+	if ps.Contains(argPreviewOffset) {
+		options.PreviewOffset = ps.Get(argPreviewOffset).GetIntValue()
+		if debugMode {
+			fmt.Printf("setting preview offset=%d\n", options.PreviewOffset)
+		}
+	}
+
+	if ps.Contains(argStream) {
+		if debugMode {
+			fmt.Println("setting streaming on")
+		}
+		options.StreamSongs = true
+	}
+
 	if ps.Contains(argIntegrityChecks) {
 		if debugMode {
 			fmt.Println("setting integrity checks on")
@@ -350,21 +640,26 @@ func main() {
 		}
 
 		helpCmds := []string{cmdHelp, cmdUsage}
+		localCmds := []string{cmdCacheStatus, cmdCacheClear, cmdCtl}
 		nonHelpCmds := []string{cmdImportSongs, cmdPlay, cmdShufflePlay, cmdListSongs,
 			cmdListArtists, cmdListContainers, cmdListGenres,
 			cmdListAlbums, cmdRetrieveCatalog, cmdImportPlaylists,
 			cmdListPlaylists, cmdShowPlaylist, cmdPlayPlaylist,
 			cmdDeleteSong, cmdDeleteAlbum, cmdDeletePlaylist,
 			cmdDeleteArtist, cmdUploadMetadataDb,
-			cmdImportAlbumArt, cmdPlayAlbum, cmdShowAlbum}
+			cmdImportAlbumArt, cmdPlayAlbum, cmdShowAlbum, cmdResume, cmdTui, cmdWatch}
 		updateCmds := []string{cmdImportSongs, cmdImportPlaylists, cmdDeleteSong,
 			cmdDeleteAlbum, cmdDeletePlaylist, cmdDeleteArtist,
-			cmdUploadMetadataDb, cmdImportAlbumArt, cmdInitStorage}
+			cmdUploadMetadataDb, cmdImportAlbumArt, cmdInitStorage, cmdTui, cmdWatch}
 		var allCmds []string
 		for _, cmd := range helpCmds {
 			allCmds = append(allCmds, cmd)
 		}
 
+		for _, cmd := range localCmds {
+			allCmds = append(allCmds, cmd)
+		}
+
 		for _, cmd := range nonHelpCmds {
 			allCmds = append(allCmds, cmd)
 		}
@@ -375,6 +670,7 @@ func main() {
 
 		commandInAllCmds := false
 		commandInHelpCmds := false
+		commandInLocalCmds := false
 		commandInUpdateCmds := false
 
 		for _, cmd := range allCmds {
@@ -391,6 +687,13 @@ func main() {
 			}
 		}
 
+		for _, cmd := range localCmds {
+			if cmd == command {
+				commandInLocalCmds = true
+				break
+			}
+		}
+
 		for _, cmd := range updateCmds {
 			if cmd == command {
 				commandInUpdateCmds = true
@@ -410,6 +713,53 @@ func main() {
 					os.Exit(1)
 				}
 
+				// commands that only deal with local state don't need storage
+				if commandInLocalCmds {
+					if command == cmdCtl {
+						action := ""
+						if ps.Contains(argAction) {
+							action = ps.Get(argAction).GetStringValue()
+						}
+						level := 0
+						haveLevel := ps.Contains(argLevel)
+						if haveLevel {
+							level = ps.Get(argLevel).GetIntValue()
+						}
+						position := 0
+						if ps.Contains(argPosition) {
+							position = ps.Get(argPosition).GetIntValue()
+						}
+						to := 0
+						if ps.Contains(argTo) {
+							to = ps.Get(argTo).GetIntValue()
+						}
+						minutes := 0
+						if ps.Contains(argMinutes) {
+							minutes = ps.Get(argMinutes).GetIntValue()
+						}
+						if !runControlCommand(options, action, song, level, haveLevel, position, to, minutes) {
+							exitCode = 1
+						}
+						os.Exit(exitCode)
+					}
+
+					songCache := jukebox.OpenSongCache(options)
+					if songCache == nil {
+						os.Exit(1)
+					}
+					if command == cmdCacheStatus {
+						songCache.ShowStatus()
+					} else if command == cmdCacheClear {
+						if songCache.Clear() {
+							fmt.Println("song cache cleared")
+						} else {
+							fmt.Println("error: unable to clear song cache")
+							exitCode = 1
+						}
+					}
+					os.Exit(exitCode)
+				}
+
 				if command == cmdUploadMetadataDb {
 					options.SuppressMetadataDownload = true
 				} else {
@@ -428,7 +778,7 @@ func main() {
 					debugMode,
 					isUpdate)
 				if storageSystem != nil {
-					if storageSystem.Enter() {
+					if errEnter := storageSystem.Enter(context.Background()); errEnter == nil {
 						defer storageSystem.Exit()
 						fmt.Println("storage system entered")
 
@@ -449,16 +799,32 @@ func main() {
 								jb.ImportSongs()
 							} else if command == cmdImportPlaylists {
 								jb.ImportPlaylists()
+							} else if command == cmdWatch {
+								jb.WatchImportDirs()
 							} else if command == cmdPlay {
-								shuffle = false
+								// giving a shuffle mode shuffles
+								shuffle = ps.Contains(argShuffle)
 								if len(artist) == 0 && len(album) == 0 && len(playlist) > 0 {
-									jb.PlayPlaylist(playlist)
-								} else {
-									jb.PlaySongs(shuffle, artist, album)
+									if !jb.PlayPlaylist(playlist) {
+										exitCode = 1
+									}
+								} else if !jb.PlaySongs(shuffle, artist, album) {
+									exitCode = 1
 								}
 							} else if command == cmdShufflePlay {
 								shuffle = true
-								jb.PlaySongs(shuffle, artist, album)
+								if !jb.PlaySongs(shuffle, artist, album) {
+									exitCode = 1
+								}
+							} else if command == cmdResume {
+								if !jb.ResumeSession() {
+									exitCode = 1
+								}
+							} else if command == cmdTui {
+								// deleting songs needs the update credentials
+								if !jukebox.NewTui(jb, os.Stdin, os.Stdout).Run() {
+									exitCode = 1
+								}
 							} else if command == cmdListSongs {
 								jb.ShowListings()
 							} else if command == cmdListArtists {
@@ -487,14 +853,18 @@ func main() {
 								}
 							} else if command == cmdPlayPlaylist {
 								if len(playlist) > 0 {
-									jb.PlayPlaylist(playlist)
+									if !jb.PlayPlaylist(playlist) {
+										exitCode = 1
+									}
 								} else {
 									fmt.Printf("error: playlist must be specified using --%s option\n", argPlaylist)
 									exitCode = 1
 								}
 							} else if command == cmdPlayAlbum {
 								if len(album) > 0 && len(artist) > 0 {
-									jb.PlayAlbum(artist, album)
+									if !jb.PlayAlbum(artist, album) {
+										exitCode = 1
+									}
 								} else {
 									fmt.Printf("error: artist and album must be specified using --%s and --%s options\n", argArtist, argAlbum)
 								}
@@ -573,6 +943,7 @@ func main() {
 						}
 					} else {
 						fmt.Println("unable to enter storage system")
+						fmt.Printf("error: %v\n", errEnter)
 					}
 				}
 			}
@@ -1,2 +0,0 @@
-#!/bin/sh
-python3 toggle_pause_play.py